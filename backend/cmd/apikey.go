@@ -0,0 +1,96 @@
+package main
+
+import (
+	"backend/internal/services"
+	"backend/pkg/auth"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var errAPIKeyUsage = errors.New("usage: backend apikey issue -name NAME [-scopes " + strings.Join(auth.Scopes, ",") + "] [-ttl DURATION] | list | revoke ID | rotate [-grace DURATION] ID")
+
+// runAPIKey implements the "apikey" subcommand. Secrets are printed once and
+// cannot be shown again.
+func runAPIKey(ctx context.Context, apiKeyService services.APIKeyServiceI, args []string) error {
+	if len(args) == 0 {
+		return errAPIKeyUsage
+	}
+	switch args[0] {
+	case "issue":
+		flags := flag.NewFlagSet("apikey issue", flag.ContinueOnError)
+		name := flags.String("name", "", "who the key is issued to")
+		scopes := flags.String("scopes", auth.ScopeWalletRead, "comma-separated scopes")
+		ttl := flags.Duration("ttl", 0, "lifetime of the key, 0 never expires")
+		if err := flags.Parse(args[1:]); err != nil || flags.NArg() != 0 {
+			return errAPIKeyUsage
+		}
+		secret, key, err := apiKeyService.Issue(ctx, *name, strings.Split(*scopes, ","), *ttl)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("id\t%s\nkey\t%s\n", key.ID, secret)
+	case "list":
+		keys, err := apiKeyService.List(ctx)
+		if err != nil {
+			return err
+		}
+		now := time.Now()
+		for _, key := range keys {
+			state := "active"
+			switch {
+			case key.RevokedAt != nil:
+				state = "revoked"
+			case !key.Active(now):
+				state = "expired"
+			case key.ExpiresAt != nil:
+				state = "expires " + key.ExpiresAt.Format(time.RFC3339)
+			}
+			fmt.Printf("%s\t%s\t%s\t%s\n", key.ID, key.Name, strings.Join(key.Scopes, ","), state)
+		}
+	case "revoke":
+		id, err := parseAPIKeyID(args[1:])
+		if err != nil {
+			return err
+		}
+		err = apiKeyService.Revoke(ctx, id)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("revoked %s\n", id)
+	case "rotate":
+		flags := flag.NewFlagSet("apikey rotate", flag.ContinueOnError)
+		grace := flags.Duration("grace", 24*time.Hour, "how long the old key keeps working")
+		if err := flags.Parse(args[1:]); err != nil {
+			return errAPIKeyUsage
+		}
+		id, err := parseAPIKeyID(flags.Args())
+		if err != nil {
+			return err
+		}
+		secret, key, err := apiKeyService.Rotate(ctx, id, *grace)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("id\t%s\nkey\t%s\n", key.ID, secret)
+	default:
+		return errAPIKeyUsage
+	}
+	return nil
+}
+
+func parseAPIKeyID(args []string) (uuid.UUID, error) {
+	if len(args) != 1 {
+		return uuid.Nil, errAPIKeyUsage
+	}
+	id, err := uuid.Parse(args[0])
+	if err != nil {
+		return uuid.Nil, errAPIKeyUsage
+	}
+	return id, nil
+}
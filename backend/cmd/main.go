@@ -1,45 +1,211 @@
 package main
 
 import (
+	"backend/internal/expiry"
 	"backend/internal/handlers"
+	"backend/internal/logging"
+	"backend/internal/metrics"
+	"backend/internal/migrations"
+	"backend/internal/ratelimit"
 	"backend/internal/repos"
+	"backend/internal/requestid"
 	"backend/internal/services"
+	"backend/internal/tracing"
+	"backend/pkg/auth"
 	"backend/pkg/config"
+	"backend/pkg/customerror"
 	"context"
+	"errors"
 	"fmt"
-	"log"
+	"log/slog"
+	"net/http"
 	"os"
+	"os/signal"
+	"sync"
+	"sync/atomic"
+	"syscall"
 
 	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
 )
 
 func main() {
 	config, err := config.NewConfig("config.env")
 	if err != nil {
-		log.Fatalf("%s", err.Error())
+		slog.Error("cannot load config", slog.Any("error", err))
+		os.Exit(1)
 	}
-	walletRepository, err := repos.NewWalletRepository(config)
+	logger := logging.New(os.Stdout, config.LogLevel, config.LogFormat)
+	// Libraries that still use the standard log package end up in the same
+	// structured stream.
+	slog.SetDefault(logger)
+	if err := run(config, logger); err != nil {
+		logger.Error("backend stopped", slog.Any("error", err))
+		os.Exit(1)
+	}
+}
+
+func run(config *config.Config, logger *slog.Logger) error {
+	tracerProvider, shutdownTracing, err := tracing.NewTracerProvider(context.Background(), config)
 	if err != nil {
-		log.Fatalf("%s", err.Error())
+		return err
 	}
-	defer walletRepository.ClosePull()
-	err = walletRepository.CreateTables(context.Background())
+	defer func() {
+		if err := shutdownTracing(context.Background()); err != nil {
+			logger.Error("cannot flush spans", slog.Any("error", err))
+		}
+	}()
+	pool, err := repos.NewPool(config, tracerProvider)
 	if err != nil {
-		log.Fatalf("%s", err.Error())
+		return err
 	}
-	file, err := os.OpenFile("critical.log", os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	migrator, err := migrations.NewMigrator(pool)
 	if err != nil {
-		log.Fatal(err)
+		pool.Close()
+		return err
+	}
+	apiKeyService := services.NewAPIKeyService(repos.NewAPIKeyRepository(pool, config, logger, tracerProvider), config.OperationTimeout, logger, tracerProvider)
+	rateService := services.NewRateService(repos.NewRateRepository(pool, config, logger, tracerProvider), config.OperationTimeout, logger, tracerProvider)
+	if len(os.Args) > 1 {
+		defer pool.Close()
+		switch os.Args[1] {
+		case "migrate":
+			return runMigrate(context.Background(), migrator, os.Args[2:])
+		case "apikey":
+			return runAPIKey(context.Background(), apiKeyService, os.Args[2:])
+		case "rates":
+			return runRates(context.Background(), rateService, os.Args[2:])
+		default:
+			return customerror.NewError("main.run", os.Args[1], "unknown command")
+		}
+	}
+
+	// ready is cleared until migrations finish and again once shutdown starts;
+	// migrated only until they finish, so requests already being drained at
+	// shutdown are still served.
+	var ready, migrated atomic.Bool
+	walletRepository := repos.NewWalletRepository(pool, config, logger, tracerProvider)
+	defer walletRepository.ClosePull()
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		metrics.NewPoolCollector(pool),
+	)
+	recorder, err := metrics.NewPrometheus(registry)
+	if err != nil {
+		return err
+	}
+	walletService := services.NewWalletService(walletRepository, config.OperationTimeout, config.DefaultCurrency, recorder, logger, tracerProvider)
+	var rateLimitStore ratelimit.StoreI = ratelimit.NewMemoryStore()
+	var postgresStore *ratelimit.PostgresStore
+	if config.RateLimitStore == "postgres" {
+		postgresStore = ratelimit.NewPostgresStore(pool, logger)
+		rateLimitStore = postgresStore
+	}
+	limiter := ratelimit.NewLimiter(rateLimitStore,
+		ratelimit.Limit{Rate: config.ClientRate, Burst: config.ClientBurst},
+		ratelimit.Limit{Rate: config.WalletRate, Burst: config.WalletBurst},
+	)
+	walletHandlers := handlers.NewWalletHandler(walletService, limiter, config.LegacyStatusEnvelope, logger, tracerProvider)
+	rateLimitHandlers := handlers.NewRateLimitHandler(limiter, config.LegacyStatusEnvelope, logger)
+	rateHandlers := handlers.NewRateHandler(rateService, config.LegacyStatusEnvelope, logger, tracerProvider)
+	var tokenVerifier auth.TokenVerifierI
+	if config.JWTSecret != "" || config.JWTJWKSFile != "" {
+		tokenVerifier, err = auth.NewJWTVerifier(config.JWTSecret, config.JWTJWKSFile, config.JWTIssuer, config.JWTAudience, config.JWTAdminRole)
+		if err != nil {
+			return customerror.Wrap(err, "main.NewJWTVerifier", config.JWTJWKSFile)
+		}
 	}
-	defer file.Close()
-	log.SetOutput(file)
-	walletService := services.NewWalletService(walletRepository)
-	walletHandlers := handlers.NewWalletHandler(walletService)
+	authHandlers := handlers.NewAuthHandler(apiKeyService, tokenVerifier, config.LegacyStatusEnvelope, logger)
+	healthHandlers := handlers.NewHealthHandler(pool, &ready, config.ReadinessTimeout)
 
-	router := gin.Default()
-	api := router.Group("/api")
-	v1 := api.Group("/v1")
+	router := gin.New()
+	router.Use(gin.Recovery())
+	healthHandlers.RegisterRoutes(&router.RouterGroup)
+	router.GET("/metrics", metrics.Handler(registry))
+	api := router.Group("/api", handlers.LegacyBody("/api/v1/wallet"), tracing.Middleware(tracerProvider), requestid.Middleware(), logging.Middleware(logger),
+		metrics.Middleware(recorder), handlers.RequireMigrated(&migrated, config.LegacyStatusEnvelope))
+	v1 := api.Group("/v1", authHandlers.Authenticate, rateLimitHandlers.LimitClient)
 	walletHandlers.RegisterRoutes(v1)
+	admin := api.Group("/v1/admin", authHandlers.RequireAdmin(auth.ScopeRatesWrite), rateLimitHandlers.LimitClient)
+	rateHandlers.RegisterRoutes(admin)
 
-	router.Run(fmt.Sprintf("%s:%s", config.WebHost, config.WebPort))
+	server := &http.Server{
+		Addr:     fmt.Sprintf("%s:%s", config.WebHost, config.WebPort),
+		Handler:  router,
+		ErrorLog: slog.NewLogLogger(logger.Handler(), slog.LevelError),
+	}
+	// Signals are caught before the server starts, so a SIGTERM during a long
+	// startup migration still ends in a graceful shutdown.
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+	// The workers are stopped, and their last batch committed, before the
+	// deferred ClosePull closes the pool under them.
+	var workers sync.WaitGroup
+	defer func() {
+		stop()
+		workers.Wait()
+	}()
+	serverErr := make(chan error, 1)
+	go func() {
+		serverErr <- server.ListenAndServe()
+	}()
+	logger.Info("listening", slog.String("addr", server.Addr))
+
+	// Replicas started together are serialized by the migration lock, so
+	// applying pending migrations on startup is safe. The server is already
+	// up and answers /readyz and /api with 503 meanwhile. A signal cancels the
+	// migration, whose transaction is rolled back, and shuts the server down.
+	applied, err := migrator.Up(ctx)
+	if err != nil && ctx.Err() == nil {
+		return err
+	}
+	for _, migration := range applied {
+		logger.Info("applied migration", slog.Int64("version", migration.Version), slog.String("name", migration.Name))
+	}
+	if err != nil {
+		logger.Info("migration interrupted", slog.Any("error", err))
+	} else {
+		migrated.Store(true)
+		ready.Store(true)
+	}
+	// The workers need the migrated schema.
+	if err == nil && config.HoldExpiryInterval > 0 {
+		worker := expiry.NewWorker(walletRepository, config.HoldExpiryInterval, config.HoldExpiryBatchSize, config.OperationTimeout, logger)
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			worker.Run(ctx)
+		}()
+	}
+	if err == nil && postgresStore != nil {
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			postgresStore.Run(ctx)
+		}()
+	}
+	select {
+	case err := <-serverErr:
+		if errors.Is(err, http.ErrServerClosed) {
+			return nil
+		}
+		return customerror.Wrap(err, "main.ListenAndServe", server.Addr)
+	case <-ctx.Done():
+	}
+	ready.Store(false)
+	logger.Info("shutting down", slog.Duration("timeout", config.ShutdownTimeout))
+
+	// Shutdown stops accepting connections and waits for in-flight requests,
+	// so balance updates that already started are committed before the pool
+	// is closed by the deferred ClosePull.
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), config.ShutdownTimeout)
+	defer cancel()
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		server.Close()
+		return customerror.Wrap(err, "main.Shutdown", server.Addr)
+	}
+	return nil
 }
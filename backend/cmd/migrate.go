@@ -0,0 +1,56 @@
+package main
+
+import (
+	"backend/internal/migrations"
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+var errMigrateUsage = errors.New("usage: backend migrate up|down|status")
+
+// runMigrate implements the "migrate" subcommand.
+func runMigrate(ctx context.Context, migrator migrations.MigratorI, args []string) error {
+	if len(args) != 1 {
+		return errMigrateUsage
+	}
+	switch args[0] {
+	case "up":
+		applied, err := migrator.Up(ctx)
+		if err != nil {
+			return err
+		}
+		if len(applied) == 0 {
+			fmt.Println("schema is up to date")
+		}
+		for _, migration := range applied {
+			fmt.Printf("applied %04d_%s\n", migration.Version, migration.Name)
+		}
+	case "down":
+		reverted, err := migrator.Down(ctx)
+		if err != nil {
+			return err
+		}
+		if reverted == nil {
+			fmt.Println("no migrations to revert")
+			return nil
+		}
+		fmt.Printf("reverted %04d_%s\n", reverted.Version, reverted.Name)
+	case "status":
+		statuses, err := migrator.Status(ctx)
+		if err != nil {
+			return err
+		}
+		for _, status := range statuses {
+			appliedAt := "pending"
+			if status.AppliedAt != nil {
+				appliedAt = status.AppliedAt.Format(time.RFC3339)
+			}
+			fmt.Printf("%04d_%s\t%s\n", status.Version, status.Name, appliedAt)
+		}
+	default:
+		return errMigrateUsage
+	}
+	return nil
+}
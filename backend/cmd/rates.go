@@ -0,0 +1,64 @@
+package main
+
+import (
+	"backend/internal/services"
+	"backend/pkg/wallet"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+)
+
+var errRatesUsage = errors.New("usage: backend rates import [-source NAME] FILE | list")
+
+// runRates implements the "rates" subcommand, which loads exchange rates from
+// CSV without going through the API. See wallet.ReadExchangeRatesCSV for the
+// format; "-" reads standard input.
+func runRates(ctx context.Context, rateService services.RateServiceI, args []string) error {
+	if len(args) == 0 {
+		return errRatesUsage
+	}
+	switch args[0] {
+	case "import":
+		flags := flag.NewFlagSet("rates import", flag.ContinueOnError)
+		source := flags.String("source", "csv", "source of rows that name none")
+		if err := flags.Parse(args[1:]); err != nil || flags.NArg() != 1 {
+			return errRatesUsage
+		}
+		file := os.Stdin
+		if path := flags.Arg(0); path != "-" {
+			opened, err := os.Open(path)
+			if err != nil {
+				return err
+			}
+			defer opened.Close()
+			file = opened
+		}
+		rates, err := wallet.ReadExchangeRatesCSV(file, *source)
+		if err != nil {
+			return err
+		}
+		rates, err = rateService.Import(ctx, rates)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("imported %d rates\n", len(rates))
+	case "list":
+		rates, err := rateService.ListCurrent(ctx)
+		if err != nil {
+			return err
+		}
+		for _, rate := range rates {
+			validTo := "-"
+			if rate.ValidTo != nil {
+				validTo = rate.ValidTo.Format(time.RFC3339)
+			}
+			fmt.Printf("%s/%s\t%s\t%s\t%s\t%s\n", rate.Base, rate.Quote, rate.Rate, rate.ValidFrom.Format(time.RFC3339), validTo, rate.Source)
+		}
+	default:
+		return errRatesUsage
+	}
+	return nil
+}
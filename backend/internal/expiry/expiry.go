@@ -0,0 +1,81 @@
+package expiry
+
+import (
+	"backend/pkg/wallet"
+	"context"
+	"log/slog"
+	"time"
+)
+
+// RepositoryI is the part of the wallet repository the worker needs.
+type RepositoryI interface {
+	ReleaseExpiredHolds(ctx context.Context, limit int) ([]wallet.Hold, error)
+}
+
+type WorkerI interface {
+	Run(ctx context.Context)
+}
+
+// Worker releases holds that expired without being captured or released, so
+// their funds become available again. Every replica runs one; batches lock
+// their holds with SKIP LOCKED, so replicas share the work instead of
+// waiting on each other.
+type Worker struct {
+	Repo RepositoryI
+	// Interval is the pause between two sweeps. A sweep keeps taking batches
+	// of BatchSize holds until one comes back short.
+	Interval  time.Duration
+	BatchSize int
+	// Timeout bounds each batch.
+	Timeout time.Duration
+	Logger  *slog.Logger
+}
+
+func NewWorker(repo RepositoryI, interval time.Duration, batchSize int, timeout time.Duration, logger *slog.Logger) WorkerI {
+	return &Worker{
+		Repo:      repo,
+		Interval:  interval,
+		BatchSize: batchSize,
+		Timeout:   timeout,
+		Logger:    logger,
+	}
+}
+
+// Run sweeps right away and then every Interval until ctx is cancelled. A
+// batch already running when ctx is cancelled is allowed to commit, so Run
+// returns without leaving work half done.
+func (Worker *Worker) Run(ctx context.Context) {
+	ticker := time.NewTicker(Worker.Interval)
+	defer ticker.Stop()
+	for {
+		Worker.sweep(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (Worker *Worker) sweep(ctx context.Context) {
+	for ctx.Err() == nil {
+		batchCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), Worker.Timeout)
+		holds, err := Worker.Repo.ReleaseExpiredHolds(batchCtx, Worker.BatchSize)
+		cancel()
+		if err != nil {
+			// The holds stay active and are retried on the next sweep.
+			Worker.Logger.ErrorContext(ctx, "cannot release expired holds", slog.Any("error", err))
+			return
+		}
+		for _, hold := range holds {
+			Worker.Logger.InfoContext(ctx, "expired hold released",
+				slog.String("hold_id", hold.ID.String()),
+				slog.String("wallet_id", hold.WalletID.String()),
+				slog.Int64("amount", hold.Amount),
+			)
+		}
+		if len(holds) < Worker.BatchSize {
+			return
+		}
+	}
+}
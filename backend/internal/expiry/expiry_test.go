@@ -0,0 +1,107 @@
+package expiry_test
+
+import (
+	"backend/internal/expiry"
+	"backend/pkg/wallet"
+	"context"
+	"errors"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockRepository struct {
+	mock.Mock
+}
+
+func (m *MockRepository) ReleaseExpiredHolds(ctx context.Context, limit int) ([]wallet.Hold, error) {
+	args := m.Called(ctx, limit)
+	return args.Get(0).([]wallet.Hold), args.Error(1)
+}
+
+func holds(count int) []wallet.Hold {
+	released := make([]wallet.Hold, count)
+	for i := range released {
+		released[i] = wallet.Hold{ID: uuid.New(), WalletID: uuid.New(), Amount: 100, Status: wallet.HoldStatusReleased}
+	}
+	return released
+}
+
+type RunTest struct {
+	Name string
+	// Mock sets up the repository; stop, called from the last expected
+	// batch, cancels the worker.
+	Mock func(r *MockRepository, stop func(mock.Arguments))
+}
+
+func TestWorker_Run(t *testing.T) {
+	tests := []RunTest{
+		{
+			Name: "Full Batches Test",
+			Mock: func(r *MockRepository, stop func(mock.Arguments)) {
+				r.On("ReleaseExpiredHolds", mock.Anything, 2).Return(holds(2), nil).Twice()
+				r.On("ReleaseExpiredHolds", mock.Anything, 2).Run(stop).Return(holds(1), nil).Once()
+			},
+		},
+		{
+			Name: "Nothing Expired Test",
+			Mock: func(r *MockRepository, stop func(mock.Arguments)) {
+				r.On("ReleaseExpiredHolds", mock.Anything, 2).Run(stop).Return([]wallet.Hold{}, nil).Once()
+			},
+		},
+		{
+			Name: "Error Test",
+			Mock: func(r *MockRepository, stop func(mock.Arguments)) {
+				r.On("ReleaseExpiredHolds", mock.Anything, 2).Run(stop).Return([]wallet.Hold(nil), errors.New("error")).Once()
+			},
+		},
+		{
+			Name: "Shutdown Between Batches Test",
+			Mock: func(r *MockRepository, stop func(mock.Arguments)) {
+				r.On("ReleaseExpiredHolds", mock.Anything, 2).Run(stop).Return(holds(2), nil).Once()
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			mockRepo := new(MockRepository)
+			test.Mock(mockRepo, func(args mock.Arguments) {
+				cancel()
+				// The batch that is running when the worker is stopped
+				// still gets to commit.
+				assert.NoError(t, args.Get(0).(context.Context).Err())
+			})
+			worker := expiry.NewWorker(mockRepo, time.Hour, 2, time.Second, slog.New(slog.DiscardHandler))
+
+			done := make(chan struct{})
+			go func() {
+				worker.Run(ctx)
+				close(done)
+			}()
+			select {
+			case <-done:
+			case <-time.After(time.Second):
+				t.Fatal("worker did not stop")
+			}
+			mockRepo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestWorker_Interval(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	mockRepo := new(MockRepository)
+	mockRepo.On("ReleaseExpiredHolds", mock.Anything, 10).Return([]wallet.Hold{}, nil).Once()
+	mockRepo.On("ReleaseExpiredHolds", mock.Anything, 10).Run(func(mock.Arguments) { cancel() }).Return(holds(1), nil).Once()
+	worker := expiry.NewWorker(mockRepo, 10*time.Millisecond, 10, time.Second, slog.New(slog.DiscardHandler))
+
+	worker.Run(ctx)
+	mockRepo.AssertExpectations(t)
+}
@@ -0,0 +1,89 @@
+package handlers
+
+import (
+	"backend/pkg/customerror"
+	"backend/pkg/responses"
+	"backend/pkg/wallet"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// AmountFormatHeader selects how a request exchanges amounts. With
+// AmountFormatDecimal amounts in the request are decimal strings in major
+// units of the wallet's currency, such as "12.34", and every amount and
+// balance in the response is written the same way. Without the header, or
+// with AmountFormatMinor, amounts are integers in minor units.
+const (
+	AmountFormatHeader  = "Amount-Format"
+	AmountFormatMinor   = "minor"
+	AmountFormatDecimal = "decimal"
+)
+
+// decimalAmounts reports whether the request asked for decimal amounts. An
+// unknown format is answered with 400 and reported as ok false.
+func (WalletHandler *WalletHandler) decimalAmounts(ctx *gin.Context) (decimal bool, ok bool) {
+	switch strings.ToLower(ctx.GetHeader(AmountFormatHeader)) {
+	case "", AmountFormatMinor:
+		return false, true
+	case AmountFormatDecimal:
+		return true, true
+	}
+	WalletHandler.abortWithProblem(ctx, responses.ProblemInvalidAmountFormat)
+	return false, false
+}
+
+// amountsIn returns the currency responses write amounts in: currency for
+// decimal amounts and nil, meaning minor units, otherwise.
+func amountsIn(decimal bool, currency wallet.Currency) *wallet.Currency {
+	if !decimal {
+		return nil
+	}
+	return &currency
+}
+
+// walletCurrency loads the currency of wallet id for reading a decimal amount
+// sent for it. Failures are answered and reported as nil.
+func (WalletHandler *WalletHandler) walletCurrency(ctx *gin.Context, id uuid.UUID, module string) *wallet.Currency {
+	foundWallet, err := WalletHandler.WalletService.GetBalance(ctx.Request.Context(), id)
+	if errors.Is(err, wallet.ErrNotFound) {
+		WalletHandler.abortWithProblem(ctx, responses.ProblemWalletNotFound)
+		return nil
+	}
+	if err != nil {
+		WalletHandler.abortWithError(ctx, err, module)
+		return nil
+	}
+	return &foundWallet.Currency
+}
+
+// parseAmount reads a request amount in minor units, or in major units of in
+// when it is set. An omitted amount is zero. Failures are answered and
+// reported as ok false.
+func (WalletHandler *WalletHandler) parseAmount(ctx *gin.Context, value json.Number, in *wallet.Currency) (amount int64, ok bool) {
+	var err error
+	switch {
+	case value == "":
+		return 0, true
+	case in != nil:
+		amount, err = in.ParseAmount(value.String())
+	default:
+		amount, err = strconv.ParseInt(value.String(), 10, 64)
+		if errors.Is(err, strconv.ErrRange) {
+			err = customerror.ErrAmountOutOfRange
+		}
+	}
+	if errors.Is(err, customerror.ErrAmountOutOfRange) {
+		WalletHandler.abortWithProblem(ctx, responses.ProblemAmountOutOfRange)
+		return 0, false
+	}
+	if err != nil {
+		WalletHandler.abortWithProblem(ctx, responses.ProblemInvalidAmount)
+		return 0, false
+	}
+	return amount, true
+}
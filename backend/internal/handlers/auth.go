@@ -0,0 +1,113 @@
+package handlers
+
+import (
+	"backend/internal/logging"
+	"backend/internal/services"
+	"backend/pkg/auth"
+	"backend/pkg/customerror"
+	"backend/pkg/responses"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// APIKeyHeader carries the secret of an API key.
+const APIKeyHeader = "X-API-Key"
+
+type AuthHandlerI interface {
+	Authenticate(ctx *gin.Context)
+	RequireAdmin(scope string) gin.HandlerFunc
+}
+
+type AuthHandler struct {
+	APIKeyService services.APIKeyServiceI
+	// TokenVerifier validates bearer tokens of end users. When nil, only API
+	// keys are accepted.
+	TokenVerifier auth.TokenVerifierI
+	LegacyStatus  bool
+	Logger        *slog.Logger
+}
+
+func NewAuthHandler(apiKeyService services.APIKeyServiceI, tokenVerifier auth.TokenVerifierI, legacyStatus bool, logger *slog.Logger) AuthHandlerI {
+	return &AuthHandler{
+		APIKeyService: apiKeyService,
+		TokenVerifier: tokenVerifier,
+		LegacyStatus:  legacyStatus,
+		Logger:        logger,
+	}
+}
+
+// requiredScope maps a request to the scope it needs: reads need wallet:read,
+// everything that can move money needs wallet:write.
+func requiredScope(method string) string {
+	switch method {
+	case http.MethodGet, http.MethodHead:
+		return auth.ScopeWalletRead
+	default:
+		return auth.ScopeWalletWrite
+	}
+}
+
+// principal resolves the caller from a bearer token when the request carries
+// one and from the API key header otherwise.
+func (AuthHandler *AuthHandler) principal(ctx *gin.Context) (*auth.Principal, error) {
+	token, found := strings.CutPrefix(ctx.GetHeader("Authorization"), "Bearer ")
+	if !found {
+		return AuthHandler.APIKeyService.Authenticate(ctx.Request.Context(), ctx.GetHeader(APIKeyHeader))
+	}
+	if AuthHandler.TokenVerifier == nil {
+		return nil, auth.ErrUnauthenticated
+	}
+	return AuthHandler.TokenVerifier.Verify(strings.TrimSpace(token))
+}
+
+// Authenticate rejects requests without valid credentials or whose
+// credentials lack the scope of the request, and stores the caller in the
+// request context.
+func (AuthHandler *AuthHandler) Authenticate(ctx *gin.Context) {
+	AuthHandler.authenticate(ctx, requiredScope(ctx.Request.Method), false)
+}
+
+// RequireAdmin authenticates like Authenticate but requires scope whatever
+// the method, and turns away end users without the admin role.
+func (AuthHandler *AuthHandler) RequireAdmin(scope string) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		AuthHandler.authenticate(ctx, scope, true)
+	}
+}
+
+func (AuthHandler *AuthHandler) authenticate(ctx *gin.Context, scope string, admin bool) {
+	principal, err := AuthHandler.principal(ctx)
+	if errors.Is(err, auth.ErrUnauthenticated) {
+		ctx.Writer.Header().Add("WWW-Authenticate", `ApiKey header="`+APIKeyHeader+`"`)
+		if AuthHandler.TokenVerifier != nil {
+			ctx.Writer.Header().Add("WWW-Authenticate", "Bearer")
+		}
+		abortWithProblem(ctx, responses.ProblemUnauthorized, AuthHandler.LegacyStatus)
+		return
+	}
+	if err != nil {
+		err = customerror.AppendModule(err, "Authenticate")
+		AuthHandler.Logger.ErrorContext(ctx.Request.Context(), "cannot authenticate request", slog.Any("error", err))
+		abortWithProblem(ctx, responses.ProblemInternal, AuthHandler.LegacyStatus)
+		return
+	}
+	callerAttr := slog.String("api_key_id", principal.ID)
+	if principal.Subject != "" {
+		callerAttr = slog.String("subject", principal.Subject)
+	}
+	reqCtx := logging.WithAttrs(ctx.Request.Context(), callerAttr)
+	ctx.Request = ctx.Request.WithContext(auth.NewContext(reqCtx, principal))
+	if !principal.HasScope(scope) {
+		abortWithProblem(ctx, responses.ProblemInsufficientScope, AuthHandler.LegacyStatus)
+		return
+	}
+	if admin && principal.Restricted() {
+		abortWithProblem(ctx, responses.ProblemForbidden, AuthHandler.LegacyStatus)
+		return
+	}
+	ctx.Next()
+}
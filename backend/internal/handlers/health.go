@@ -0,0 +1,91 @@
+package handlers
+
+import (
+	"backend/internal/repos"
+	"backend/pkg/responses"
+	"context"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type HealthHandlerI interface {
+	RegisterRoutes(router *gin.RouterGroup)
+	Liveness(ctx *gin.Context)
+	Readiness(ctx *gin.Context)
+}
+
+type HealthHandler struct {
+	Pool repos.PoolInterface
+	// Ready is cleared while migrations run and once shutdown starts, so load
+	// balancers stop routing to the instance before it goes away.
+	Ready       *atomic.Bool
+	PingTimeout time.Duration
+}
+
+func NewHealthHandler(pool repos.PoolInterface, ready *atomic.Bool, pingTimeout time.Duration) HealthHandlerI {
+	return &HealthHandler{
+		Pool:        pool,
+		Ready:       ready,
+		PingTimeout: pingTimeout,
+	}
+}
+
+// RequireMigrated answers 503 until migrated is set, so no request reaches a
+// schema that migrations are still changing. The probes stay outside of it.
+func RequireMigrated(migrated *atomic.Bool, legacyStatus bool) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		if !migrated.Load() {
+			abortWithProblem(ctx, responses.ProblemUnavailable, legacyStatus)
+			return
+		}
+		ctx.Next()
+	}
+}
+
+func (HealthHandler *HealthHandler) RegisterRoutes(router *gin.RouterGroup) {
+	router.GET("/healthz", HealthHandler.Liveness)
+	router.GET("/readyz", HealthHandler.Readiness)
+}
+
+// Liveness only reports that the process serves HTTP. It never touches the
+// database, so an outage there does not get healthy replicas restarted.
+func (HealthHandler *HealthHandler) Liveness(ctx *gin.Context) {
+	ctx.JSON(http.StatusOK, responses.HealthData{Status: "ok"})
+}
+
+func (HealthHandler *HealthHandler) Readiness(ctx *gin.Context) {
+	stat := HealthHandler.Pool.Stat()
+	pool := &responses.PoolData{
+		AcquiredConns:     stat.AcquiredConns(),
+		IdleConns:         stat.IdleConns(),
+		TotalConns:        stat.TotalConns(),
+		MaxConns:          stat.MaxConns(),
+		AcquireCount:      stat.AcquireCount(),
+		EmptyAcquireCount: stat.EmptyAcquireCount(),
+	}
+	if !HealthHandler.Ready.Load() {
+		ctx.JSON(http.StatusServiceUnavailable, responses.HealthData{
+			Status: "unavailable",
+			Reason: "starting or shutting down",
+			Pool:   pool,
+		})
+		return
+	}
+	pingCtx, cancel := context.WithTimeout(ctx.Request.Context(), HealthHandler.PingTimeout)
+	defer cancel()
+	if err := HealthHandler.Pool.Ping(pingCtx); err != nil {
+		ctx.JSON(http.StatusServiceUnavailable, responses.HealthData{
+			Status: "unavailable",
+			Reason: "database unreachable",
+			Pool:   pool,
+		})
+		return
+	}
+	ctx.JSON(http.StatusOK, responses.HealthData{
+		Status: "ok",
+		Pool:   pool,
+	})
+}
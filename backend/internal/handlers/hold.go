@@ -0,0 +1,226 @@
+package handlers
+
+import (
+	"backend/internal/logging"
+	"backend/internal/tracing"
+	"backend/pkg/customerror"
+	"backend/pkg/requests"
+	"backend/pkg/responses"
+	"backend/pkg/wallet"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func tagHold(ctx *gin.Context, id uuid.UUID) {
+	trace.SpanFromContext(ctx.Request.Context()).SetAttributes(tracing.HoldIDKey.String(id.String()))
+	ctx.Request = ctx.Request.WithContext(logging.WithAttrs(ctx.Request.Context(), slog.String("hold_id", id.String())))
+}
+
+// holdID reads and tags the hold id of the path. Failures are answered and
+// reported as ok false.
+func (WalletHandler *WalletHandler) holdID(ctx *gin.Context) (id uuid.UUID, ok bool) {
+	id, err := uuid.Parse(ctx.Param("id"))
+	if err != nil {
+		WalletHandler.abortWithProblem(ctx, responses.ProblemInvalidRequest)
+		return uuid.Nil, false
+	}
+	tagHold(ctx, id)
+	return id, true
+}
+
+// allowHold looks up hold id and charges the bucket of its wallet, as every
+// endpoint that moves funds does. Failures are answered and reported as nil.
+func (WalletHandler *WalletHandler) allowHold(ctx *gin.Context, id uuid.UUID, module string) *wallet.Hold {
+	hold, err := WalletHandler.WalletService.GetHold(ctx.Request.Context(), id)
+	if err != nil {
+		WalletHandler.abortWithHoldError(ctx, err, module)
+		return nil
+	}
+	if !WalletHandler.allowWallet(ctx, hold.WalletID) {
+		return nil
+	}
+	return hold
+}
+
+// abortWithHoldError answers the errors every hold endpoint shares.
+func (WalletHandler *WalletHandler) abortWithHoldError(ctx *gin.Context, err error, module string) {
+	switch {
+	case errors.Is(err, wallet.ErrHoldNotFound):
+		WalletHandler.abortWithProblem(ctx, responses.ProblemHoldNotFound)
+	case errors.Is(err, wallet.ErrHoldNotActive):
+		WalletHandler.abortWithProblem(ctx, responses.ProblemHoldNotActive)
+	case errors.Is(err, wallet.ErrHoldExpired):
+		WalletHandler.abortWithProblem(ctx, responses.ProblemHoldExpired)
+	default:
+		WalletHandler.abortWithError(ctx, err, module)
+	}
+}
+
+func (WalletHandler *WalletHandler) CreateHold(ctx *gin.Context) {
+	span := WalletHandler.startSpan(ctx, "WalletHandler.CreateHold")
+	defer span.End()
+
+	walletID, err := uuid.Parse(ctx.Param("id"))
+	if err != nil {
+		WalletHandler.abortWithProblem(ctx, responses.ProblemInvalidWalletID)
+		return
+	}
+	tagWallet(ctx, walletID)
+	var userRequest requests.CreateHoldRequest
+	err = ctx.ShouldBindJSON(&userRequest)
+	if err != nil {
+		WalletHandler.abortWithProblem(ctx, responses.ProblemInvalidRequest)
+		return
+	}
+	decimal, ok := WalletHandler.decimalAmounts(ctx)
+	if !ok {
+		return
+	}
+	if !WalletHandler.allowWallet(ctx, walletID) {
+		return
+	}
+	var in *wallet.Currency
+	if decimal {
+		if in = WalletHandler.walletCurrency(ctx, walletID, "CreateHold"); in == nil {
+			return
+		}
+	}
+	amount, ok := WalletHandler.parseAmount(ctx, userRequest.Amount, in)
+	if !ok {
+		return
+	}
+	hold, err := WalletHandler.WalletService.CreateHold(ctx.Request.Context(), walletID, amount, userRequest.Currency, userRequest.ExpiresAt)
+	if errors.Is(err, customerror.ErrInvalidAmount) {
+		WalletHandler.abortWithProblem(ctx, responses.ProblemInvalidAmount)
+		return
+	}
+	if errors.Is(err, customerror.ErrAmountOutOfRange) {
+		WalletHandler.abortWithProblem(ctx, responses.ProblemAmountOutOfRange)
+		return
+	}
+	if errors.Is(err, customerror.ErrWrongAmount) {
+		WalletHandler.abortWithProblem(ctx, responses.ProblemInsufficientFunds)
+		return
+	}
+	if errors.Is(err, wallet.ErrInvalidHoldExpiry) {
+		WalletHandler.abortWithProblem(ctx, responses.ProblemInvalidHoldExpiry)
+		return
+	}
+	if errors.Is(err, wallet.ErrCurrencyMismatch) {
+		WalletHandler.abortWithProblem(ctx, responses.ProblemCurrencyMismatch)
+		return
+	}
+	if errors.Is(err, wallet.ErrNotFound) {
+		WalletHandler.abortWithProblem(ctx, responses.ProblemWalletNotFound)
+		return
+	}
+	if err != nil {
+		WalletHandler.abortWithError(ctx, err, "CreateHold")
+		return
+	}
+	tagHold(ctx, hold.ID)
+
+	WalletHandler.respond(ctx, http.StatusCreated, responses.NewHoldData(hold, amountsIn(decimal, hold.Currency)))
+}
+
+func (WalletHandler *WalletHandler) GetHold(ctx *gin.Context) {
+	span := WalletHandler.startSpan(ctx, "WalletHandler.GetHold")
+	defer span.End()
+
+	id, ok := WalletHandler.holdID(ctx)
+	if !ok {
+		return
+	}
+	decimal, ok := WalletHandler.decimalAmounts(ctx)
+	if !ok {
+		return
+	}
+	hold, err := WalletHandler.WalletService.GetHold(ctx.Request.Context(), id)
+	if err != nil {
+		WalletHandler.abortWithHoldError(ctx, err, "GetHold")
+		return
+	}
+
+	WalletHandler.respond(ctx, http.StatusOK, responses.NewHoldData(hold, amountsIn(decimal, hold.Currency)))
+}
+
+func (WalletHandler *WalletHandler) CaptureHold(ctx *gin.Context) {
+	span := WalletHandler.startSpan(ctx, "WalletHandler.CaptureHold")
+	defer span.End()
+
+	id, ok := WalletHandler.holdID(ctx)
+	if !ok {
+		return
+	}
+	// An empty body captures the whole hold.
+	var userRequest requests.CaptureHoldRequest
+	err := ctx.ShouldBindJSON(&userRequest)
+	if err != nil && !errors.Is(err, io.EOF) {
+		WalletHandler.abortWithProblem(ctx, responses.ProblemInvalidRequest)
+		return
+	}
+	decimal, ok := WalletHandler.decimalAmounts(ctx)
+	if !ok {
+		return
+	}
+	hold := WalletHandler.allowHold(ctx, id, "CaptureHold")
+	if hold == nil {
+		return
+	}
+	// An omitted amount captures the whole hold; an explicit zero is as
+	// invalid as it is for every other operation.
+	var amount *int64
+	if userRequest.Amount != "" {
+		// A decimal amount is in the currency of the wallet the hold is on.
+		var in *wallet.Currency
+		if decimal {
+			in = &hold.Currency
+		}
+		value, ok := WalletHandler.parseAmount(ctx, userRequest.Amount, in)
+		if !ok {
+			return
+		}
+		amount = &value
+	}
+	hold, err = WalletHandler.WalletService.CaptureHold(ctx.Request.Context(), id, amount)
+	if errors.Is(err, customerror.ErrInvalidAmount) {
+		WalletHandler.abortWithProblem(ctx, responses.ProblemInvalidAmount)
+		return
+	}
+	if err != nil {
+		WalletHandler.abortWithHoldError(ctx, err, "CaptureHold")
+		return
+	}
+
+	WalletHandler.respond(ctx, http.StatusOK, responses.NewHoldData(hold, amountsIn(decimal, hold.Currency)))
+}
+
+func (WalletHandler *WalletHandler) ReleaseHold(ctx *gin.Context) {
+	span := WalletHandler.startSpan(ctx, "WalletHandler.ReleaseHold")
+	defer span.End()
+
+	id, ok := WalletHandler.holdID(ctx)
+	if !ok {
+		return
+	}
+	decimal, ok := WalletHandler.decimalAmounts(ctx)
+	if !ok {
+		return
+	}
+	if WalletHandler.allowHold(ctx, id, "ReleaseHold") == nil {
+		return
+	}
+	hold, err := WalletHandler.WalletService.ReleaseHold(ctx.Request.Context(), id)
+	if err != nil {
+		WalletHandler.abortWithHoldError(ctx, err, "ReleaseHold")
+		return
+	}
+
+	WalletHandler.respond(ctx, http.StatusOK, responses.NewHoldData(hold, amountsIn(decimal, hold.Currency)))
+}
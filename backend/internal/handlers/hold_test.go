@@ -0,0 +1,255 @@
+package handlers_test
+
+import (
+	"backend/internal/handlers"
+	"backend/internal/ratelimit"
+	"backend/pkg/customerror"
+	"backend/pkg/responses"
+	"backend/pkg/wallet"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+type HoldTest struct {
+	Name           string
+	Path           string
+	Body           string
+	AmountFormat   string
+	Mock           func(*MockService)
+	ExpectedStatus int
+	ExpectedData   map[string]interface{}
+	ExpectedCode   string
+}
+
+func TestWalletHandler_Holds(t *testing.T) {
+	walletID := uuid.New()
+	holdID := uuid.New()
+	usd := wallet.Currency{Code: "USD", MinorUnits: 2}
+	expiresAt := time.Date(2026, 1, 8, 0, 0, 0, 0, time.UTC)
+	createdAt := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	hold := func(status string, captured int64) *wallet.Hold {
+		return &wallet.Hold{
+			ID: holdID, WalletID: walletID, Amount: 300, Captured: captured, Status: status,
+			ExpiresAt: expiresAt, CreatedAt: createdAt, Currency: usd,
+		}
+	}
+	amount := func(value int64) *int64 { return &value }
+	holdData := func(status string, amount interface{}, captured interface{}) map[string]interface{} {
+		return map[string]interface{}{
+			"id":         holdID.String(),
+			"walletId":   walletID.String(),
+			"amount":     amount,
+			"captured":   captured,
+			"status":     status,
+			"expiresAt":  "2026-01-08T00:00:00Z",
+			"createdAt":  "2026-01-01T00:00:00Z",
+			"currency":   "USD",
+			"minorUnits": float64(2),
+		}
+	}
+
+	tests := []HoldTest{
+		{
+			Name: "Create Test",
+			Path: "/wallets/" + walletID.String() + "/holds",
+			Body: `{"amount":300,"expiresAt":"2026-01-08T00:00:00Z"}`,
+			Mock: func(s *MockService) {
+				s.On("CreateHold", mock.Anything, walletID, int64(300), "", &expiresAt).Return(hold(wallet.HoldStatusActive, 0), nil)
+			},
+			ExpectedStatus: http.StatusCreated,
+			ExpectedData:   holdData(wallet.HoldStatusActive, float64(300), float64(0)),
+		},
+		{
+			Name:         "Create Decimal Test",
+			Path:         "/wallets/" + walletID.String() + "/holds",
+			Body:         `{"amount":"3"}`,
+			AmountFormat: "decimal",
+			Mock: func(s *MockService) {
+				s.On("GetBalance", mock.Anything, walletID).Return(&wallet.Wallet{ID: walletID, Currency: usd}, nil)
+				s.On("CreateHold", mock.Anything, walletID, int64(300), "", (*time.Time)(nil)).Return(hold(wallet.HoldStatusActive, 0), nil)
+			},
+			ExpectedStatus: http.StatusCreated,
+			ExpectedData:   holdData(wallet.HoldStatusActive, "3.00", "0.00"),
+		},
+		{
+			Name: "Create Insufficient Funds Test",
+			Path: "/wallets/" + walletID.String() + "/holds",
+			Body: `{"amount":300}`,
+			Mock: func(s *MockService) {
+				s.On("CreateHold", mock.Anything, walletID, int64(300), "", (*time.Time)(nil)).Return((*wallet.Hold)(nil), customerror.ErrWrongAmount)
+			},
+			ExpectedStatus: http.StatusBadRequest,
+			ExpectedCode:   responses.CodeInsufficientFunds,
+		},
+		{
+			Name: "Create Invalid Expiry Test",
+			Path: "/wallets/" + walletID.String() + "/holds",
+			Body: `{"amount":300,"expiresAt":"2026-01-08T00:00:00Z"}`,
+			Mock: func(s *MockService) {
+				s.On("CreateHold", mock.Anything, walletID, int64(300), "", &expiresAt).Return((*wallet.Hold)(nil), wallet.ErrInvalidHoldExpiry)
+			},
+			ExpectedStatus: http.StatusBadRequest,
+			ExpectedCode:   responses.CodeInvalidHoldExpiry,
+		},
+		{
+			Name:           "Create Invalid UUID Test",
+			Path:           "/wallets/invalid/holds",
+			Body:           `{"amount":300}`,
+			Mock:           func(s *MockService) {},
+			ExpectedStatus: http.StatusBadRequest,
+			ExpectedCode:   responses.CodeInvalidWalletID,
+		},
+		{
+			Name: "Full Capture Test",
+			Path: "/holds/" + holdID.String() + "/capture",
+			Mock: func(s *MockService) {
+				s.On("GetHold", mock.Anything, holdID).Return(hold(wallet.HoldStatusActive, 0), nil)
+				s.On("CaptureHold", mock.Anything, holdID, (*int64)(nil)).Return(hold(wallet.HoldStatusCaptured, 300), nil)
+			},
+			ExpectedStatus: http.StatusOK,
+			ExpectedData:   holdData(wallet.HoldStatusCaptured, float64(300), float64(300)),
+		},
+		{
+			Name: "Null Amount Capture Test",
+			Path: "/holds/" + holdID.String() + "/capture",
+			Body: `{"amount":null}`,
+			Mock: func(s *MockService) {
+				s.On("GetHold", mock.Anything, holdID).Return(hold(wallet.HoldStatusActive, 0), nil)
+				s.On("CaptureHold", mock.Anything, holdID, (*int64)(nil)).Return(hold(wallet.HoldStatusCaptured, 300), nil)
+			},
+			ExpectedStatus: http.StatusOK,
+			ExpectedData:   holdData(wallet.HoldStatusCaptured, float64(300), float64(300)),
+		},
+		{
+			Name: "Zero Capture Test",
+			Path: "/holds/" + holdID.String() + "/capture",
+			Body: `{"amount":0}`,
+			Mock: func(s *MockService) {
+				s.On("GetHold", mock.Anything, holdID).Return(hold(wallet.HoldStatusActive, 0), nil)
+				s.On("CaptureHold", mock.Anything, holdID, amount(0)).Return((*wallet.Hold)(nil), customerror.ErrInvalidAmount)
+			},
+			ExpectedStatus: http.StatusBadRequest,
+			ExpectedCode:   responses.CodeInvalidAmount,
+		},
+		{
+			Name:         "Zero Decimal Capture Test",
+			Path:         "/holds/" + holdID.String() + "/capture",
+			Body:         `{"amount":"0.00"}`,
+			AmountFormat: "decimal",
+			Mock: func(s *MockService) {
+				s.On("GetHold", mock.Anything, holdID).Return(hold(wallet.HoldStatusActive, 0), nil)
+				s.On("CaptureHold", mock.Anything, holdID, amount(0)).Return((*wallet.Hold)(nil), customerror.ErrInvalidAmount)
+			},
+			ExpectedStatus: http.StatusBadRequest,
+			ExpectedCode:   responses.CodeInvalidAmount,
+		},
+		{
+			Name:         "Partial Decimal Capture Test",
+			Path:         "/holds/" + holdID.String() + "/capture",
+			Body:         `{"amount":"1.5"}`,
+			AmountFormat: "decimal",
+			Mock: func(s *MockService) {
+				s.On("GetHold", mock.Anything, holdID).Return(hold(wallet.HoldStatusActive, 0), nil)
+				s.On("CaptureHold", mock.Anything, holdID, amount(150)).Return(hold(wallet.HoldStatusCaptured, 150), nil)
+			},
+			ExpectedStatus: http.StatusOK,
+			ExpectedData:   holdData(wallet.HoldStatusCaptured, "3.00", "1.50"),
+		},
+		{
+			Name: "Capture Exceeds Hold Test",
+			Path: "/holds/" + holdID.String() + "/capture",
+			Body: `{"amount":301}`,
+			Mock: func(s *MockService) {
+				s.On("GetHold", mock.Anything, holdID).Return(hold(wallet.HoldStatusActive, 0), nil)
+				s.On("CaptureHold", mock.Anything, holdID, amount(301)).Return((*wallet.Hold)(nil), fmt.Errorf("%w: capture exceeds the hold", customerror.ErrInvalidAmount))
+			},
+			ExpectedStatus: http.StatusBadRequest,
+			ExpectedCode:   responses.CodeInvalidAmount,
+		},
+		{
+			Name: "Capture Expired Test",
+			Path: "/holds/" + holdID.String() + "/capture",
+			Mock: func(s *MockService) {
+				s.On("GetHold", mock.Anything, holdID).Return(hold(wallet.HoldStatusActive, 0), nil)
+				s.On("CaptureHold", mock.Anything, holdID, (*int64)(nil)).Return((*wallet.Hold)(nil), wallet.ErrHoldExpired)
+			},
+			ExpectedStatus: http.StatusConflict,
+			ExpectedCode:   responses.CodeHoldExpired,
+		},
+		{
+			Name: "Release Test",
+			Path: "/holds/" + holdID.String() + "/release",
+			Mock: func(s *MockService) {
+				s.On("GetHold", mock.Anything, holdID).Return(hold(wallet.HoldStatusActive, 0), nil)
+				s.On("ReleaseHold", mock.Anything, holdID).Return(hold(wallet.HoldStatusReleased, 0), nil)
+			},
+			ExpectedStatus: http.StatusOK,
+			ExpectedData:   holdData(wallet.HoldStatusReleased, float64(300), float64(0)),
+		},
+		{
+			Name: "Release Not Active Test",
+			Path: "/holds/" + holdID.String() + "/release",
+			Mock: func(s *MockService) {
+				s.On("GetHold", mock.Anything, holdID).Return(hold(wallet.HoldStatusCaptured, 300), nil)
+				s.On("ReleaseHold", mock.Anything, holdID).Return((*wallet.Hold)(nil), wallet.ErrHoldNotActive)
+			},
+			ExpectedStatus: http.StatusConflict,
+			ExpectedCode:   responses.CodeHoldNotActive,
+		},
+		{
+			Name: "Release Not Found Test",
+			Path: "/holds/" + holdID.String() + "/release",
+			Mock: func(s *MockService) {
+				s.On("GetHold", mock.Anything, holdID).Return((*wallet.Hold)(nil), wallet.ErrHoldNotFound)
+			},
+			ExpectedStatus: http.StatusNotFound,
+			ExpectedCode:   responses.CodeHoldNotFound,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			mockService := new(MockService)
+			test.Mock(mockService)
+
+			handler := handlers.NewWalletHandler(mockService, ratelimit.NewNop(), false, slog.New(slog.DiscardHandler), noop.NewTracerProvider())
+
+			router := gin.Default()
+			handler.RegisterRoutes(&router.RouterGroup)
+
+			req, _ := http.NewRequest(http.MethodPost, test.Path, bytes.NewBufferString(test.Body))
+			req.Header.Set("Content-Type", "application/json")
+			if test.AmountFormat != "" {
+				req.Header.Set(handlers.AmountFormatHeader, test.AmountFormat)
+			}
+			resp := httptest.NewRecorder()
+
+			router.ServeHTTP(resp, req)
+
+			assert.Equal(t, test.ExpectedStatus, resp.Code)
+
+			var body gin.H
+			err := json.Unmarshal(resp.Body.Bytes(), &body)
+			assert.NoError(t, err)
+			if test.ExpectedCode != "" {
+				assert.Equal(t, test.ExpectedCode, body["code"])
+			} else {
+				assert.Equal(t, test.ExpectedData, body["data"])
+			}
+
+			mockService.AssertExpectations(t)
+		})
+	}
+}
@@ -0,0 +1,138 @@
+package handlers
+
+import (
+	"backend/internal/requestid"
+	"backend/internal/services"
+	"backend/pkg/customerror"
+	"backend/pkg/requests"
+	"backend/pkg/responses"
+	"backend/pkg/wallet"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// CSVContentType selects the CSV body of ImportRates; see
+// wallet.ReadExchangeRatesCSV for the columns.
+const CSVContentType = "text/csv"
+
+// maxRatesBodySize keeps an import body well above MaxRatesPerImport rows.
+const maxRatesBodySize = 1 << 20
+
+type RateHandlerI interface {
+	RegisterRoutes(router *gin.RouterGroup)
+	ImportRates(ctx *gin.Context)
+	ListRates(ctx *gin.Context)
+}
+
+type RateHandler struct {
+	RateService  services.RateServiceI
+	LegacyStatus bool
+	Logger       *slog.Logger
+	Tracer       trace.Tracer
+}
+
+func NewRateHandler(rateService services.RateServiceI, legacyStatus bool, logger *slog.Logger, tracerProvider trace.TracerProvider) RateHandlerI {
+	return &RateHandler{
+		RateService:  rateService,
+		LegacyStatus: legacyStatus,
+		Logger:       logger,
+		Tracer:       tracerProvider.Tracer("backend/internal/handlers"),
+	}
+}
+
+// startSpan starts the handler span and makes it the parent of everything the
+// request context reaches afterwards.
+func (RateHandler *RateHandler) startSpan(ctx *gin.Context, name string) trace.Span {
+	spanCtx, span := RateHandler.Tracer.Start(ctx.Request.Context(), name)
+	ctx.Request = ctx.Request.WithContext(spanCtx)
+	return span
+}
+
+// RegisterRoutes expects router to admit administrators only.
+func (RateHandler *RateHandler) RegisterRoutes(router *gin.RouterGroup) {
+	router.POST("/rates", RateHandler.ImportRates)
+	router.GET("/rates", RateHandler.ListRates)
+}
+
+// ImportRates loads rates from a JSON body, or from CSV when the request is
+// sent as text/csv. Rows without a source in a CSV body take the "source"
+// query parameter. Either every rate is stored or none.
+func (RateHandler *RateHandler) ImportRates(ctx *gin.Context) {
+	span := RateHandler.startSpan(ctx, "RateHandler.ImportRates")
+	defer span.End()
+	ctx.Request.Body = http.MaxBytesReader(ctx.Writer, ctx.Request.Body, maxRatesBodySize)
+	var rates []wallet.ExchangeRate
+	var err error
+	if ctx.ContentType() == CSVContentType {
+		rates, err = wallet.ReadExchangeRatesCSV(ctx.Request.Body, ctx.DefaultQuery("source", "csv"))
+	} else {
+		var userRequest requests.ImportRatesRequest
+		if err := ctx.ShouldBindJSON(&userRequest); err != nil {
+			abortWithProblem(ctx, responses.ProblemInvalidRequest, RateHandler.LegacyStatus)
+			return
+		}
+		rates = newRates(userRequest)
+	}
+	if err == nil {
+		rates, err = RateHandler.RateService.Import(ctx.Request.Context(), rates)
+	}
+	if errors.Is(err, wallet.ErrInvalidExchangeRate) {
+		RateHandler.abortWithDetail(ctx, responses.ProblemInvalidExchangeRate, err)
+		return
+	}
+	if errors.Is(err, wallet.ErrUnknownCurrency) {
+		RateHandler.abortWithDetail(ctx, responses.ProblemUnknownCurrency, err)
+		return
+	}
+	if err != nil {
+		RateHandler.abortWithError(ctx, err, "ImportRates")
+		return
+	}
+	respond(ctx, http.StatusCreated, responses.RatesData{Rates: rates}, RateHandler.LegacyStatus)
+}
+
+func newRates(userRequest requests.ImportRatesRequest) []wallet.ExchangeRate {
+	rates := make([]wallet.ExchangeRate, 0, len(userRequest.Rates))
+	for _, rateRequest := range userRequest.Rates {
+		rate := wallet.ExchangeRate{
+			Base:    strings.ToUpper(rateRequest.Base),
+			Quote:   strings.ToUpper(rateRequest.Quote),
+			Rate:    rateRequest.Rate,
+			ValidTo: rateRequest.ValidTo,
+			Source:  rateRequest.Source,
+		}
+		if rateRequest.ValidFrom != nil {
+			rate.ValidFrom = *rateRequest.ValidFrom
+		}
+		rates = append(rates, rate)
+	}
+	return rates
+}
+
+func (RateHandler *RateHandler) ListRates(ctx *gin.Context) {
+	span := RateHandler.startSpan(ctx, "RateHandler.ListRates")
+	defer span.End()
+	rates, err := RateHandler.RateService.ListCurrent(ctx.Request.Context())
+	if err != nil {
+		RateHandler.abortWithError(ctx, err, "ListRates")
+		return
+	}
+	respond(ctx, http.StatusOK, responses.RatesData{Rates: rates}, RateHandler.LegacyStatus)
+}
+
+// abortWithDetail tells the administrator which rate was rejected and why.
+func (RateHandler *RateHandler) abortWithDetail(ctx *gin.Context, problem responses.Problem, err error) {
+	problem.Detail = err.Error()
+	abortWithProblem(ctx, problem, RateHandler.LegacyStatus)
+}
+
+func (RateHandler *RateHandler) abortWithError(ctx *gin.Context, err error, module string) {
+	err = customerror.WithRequestID(customerror.AppendModule(err, module), requestid.FromContext(ctx.Request.Context()))
+	RateHandler.Logger.ErrorContext(ctx.Request.Context(), "request failed", slog.Any("error", err))
+	abortWithProblem(ctx, responses.ProblemInternal, RateHandler.LegacyStatus)
+}
@@ -0,0 +1,197 @@
+package handlers_test
+
+import (
+	"backend/internal/handlers"
+	"backend/pkg/customerror"
+	"backend/pkg/responses"
+	"backend/pkg/wallet"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+type MockRateService struct {
+	mock.Mock
+}
+
+func (m *MockRateService) Import(ctx context.Context, rates []wallet.ExchangeRate) ([]wallet.ExchangeRate, error) {
+	args := m.Called(ctx, rates)
+	return args.Get(0).([]wallet.ExchangeRate), args.Error(1)
+}
+
+func (m *MockRateService) ListCurrent(ctx context.Context) ([]wallet.ExchangeRate, error) {
+	args := m.Called(ctx)
+	return args.Get(0).([]wallet.ExchangeRate), args.Error(1)
+}
+
+type ImportRatesTest struct {
+	Name           string
+	ContentType    string
+	Body           string
+	Mock           func(*MockRateService)
+	ExpectedStatus int
+	ExpectedCode   string
+	ExpectedDetail string
+}
+
+func TestRateHandler_ImportRates(t *testing.T) {
+	validFrom := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	imported := []wallet.ExchangeRate{{ID: uuid.New(), Base: "USD", Quote: "EUR", Rate: "0.92", ValidFrom: validFrom, Source: "ecb"}}
+
+	tests := []ImportRatesTest{
+		{
+			Name:        "JSON Test",
+			ContentType: "application/json",
+			Body:        `{"rates":[{"base":"usd","quote":"EUR","rate":"0.92","validFrom":"2026-01-01T00:00:00Z","source":"ecb"}]}`,
+			Mock: func(s *MockRateService) {
+				s.On("Import", mock.Anything, []wallet.ExchangeRate{{Base: "USD", Quote: "EUR", Rate: "0.92", ValidFrom: validFrom, Source: "ecb"}}).Return(imported, nil)
+			},
+			ExpectedStatus: http.StatusCreated,
+		},
+		{
+			Name:        "CSV Test",
+			ContentType: handlers.CSVContentType,
+			Body:        "base,quote,rate,valid_from\nUSD,EUR,0.92,2026-01-01T00:00:00Z\n",
+			Mock: func(s *MockRateService) {
+				s.On("Import", mock.Anything, []wallet.ExchangeRate{{Base: "USD", Quote: "EUR", Rate: "0.92", ValidFrom: validFrom, Source: "ecb"}}).Return(imported, nil)
+			},
+			ExpectedStatus: http.StatusCreated,
+		},
+		{
+			Name:           "Wrong Input Test",
+			ContentType:    "application/json",
+			Body:           `{"rates":"0.92"}`,
+			Mock:           func(s *MockRateService) {},
+			ExpectedStatus: http.StatusBadRequest,
+			ExpectedCode:   responses.CodeInvalidRequest,
+		},
+		{
+			Name:           "Invalid CSV Test",
+			ContentType:    handlers.CSVContentType,
+			Body:           "base,quote,rate\nUSD,EUR,0.92\nUSD,USD,1\n",
+			Mock:           func(s *MockRateService) {},
+			ExpectedStatus: http.StatusBadRequest,
+			ExpectedCode:   responses.CodeInvalidExchangeRate,
+			ExpectedDetail: "line 3: invalid exchange rate: base and quote currencies are the same",
+		},
+		{
+			Name:        "Invalid Rate Test",
+			ContentType: "application/json",
+			Body:        `{"rates":[{"base":"USD","quote":"EUR","rate":"0.92"}]}`,
+			Mock: func(s *MockRateService) {
+				s.On("Import", mock.Anything, mock.Anything).Return([]wallet.ExchangeRate(nil), fmt.Errorf("rate 1: %w", wallet.ErrInvalidExchangeRate))
+			},
+			ExpectedStatus: http.StatusBadRequest,
+			ExpectedCode:   responses.CodeInvalidExchangeRate,
+			ExpectedDetail: "rate 1: invalid exchange rate",
+		},
+		{
+			Name:        "Unknown Currency Test",
+			ContentType: "application/json",
+			Body:        `{"rates":[{"base":"USD","quote":"XXX","rate":"2","source":"ecb"}]}`,
+			Mock: func(s *MockRateService) {
+				s.On("Import", mock.Anything, mock.Anything).Return([]wallet.ExchangeRate(nil), wallet.ErrUnknownCurrency)
+			},
+			ExpectedStatus: http.StatusBadRequest,
+			ExpectedCode:   responses.CodeUnknownCurrency,
+		},
+		{
+			Name:        "Internal Server Error Test",
+			ContentType: "application/json",
+			Body:        `{"rates":[{"base":"USD","quote":"EUR","rate":"0.92","source":"ecb"}]}`,
+			Mock: func(s *MockRateService) {
+				s.On("Import", mock.Anything, mock.Anything).Return([]wallet.ExchangeRate(nil), customerror.NewError("", "", "error"))
+			},
+			ExpectedStatus: http.StatusInternalServerError,
+			ExpectedCode:   responses.CodeInternal,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			mockService := new(MockRateService)
+			test.Mock(mockService)
+			handler := handlers.NewRateHandler(mockService, false, slog.New(slog.DiscardHandler), noop.NewTracerProvider())
+
+			router := gin.Default()
+			handler.RegisterRoutes(&router.RouterGroup)
+
+			req, _ := http.NewRequest(http.MethodPost, "/rates?source=ecb", strings.NewReader(test.Body))
+			req.Header.Set("Content-Type", test.ContentType)
+			resp := httptest.NewRecorder()
+
+			router.ServeHTTP(resp, req)
+
+			assert.Equal(t, test.ExpectedStatus, resp.Code)
+			if test.ExpectedCode != "" {
+				var problem responses.Problem
+				err := json.Unmarshal(resp.Body.Bytes(), &problem)
+				assert.NoError(t, err)
+				assert.Equal(t, test.ExpectedCode, problem.Code)
+				if test.ExpectedDetail != "" {
+					assert.Equal(t, test.ExpectedDetail, problem.Detail)
+				}
+			} else {
+				var body struct {
+					Data responses.RatesData `json:"data"`
+				}
+				err := json.Unmarshal(resp.Body.Bytes(), &body)
+				assert.NoError(t, err)
+				assert.Equal(t, imported, body.Data.Rates)
+			}
+			mockService.AssertExpectations(t)
+		})
+	}
+}
+
+func TestRateHandler_ListRates(t *testing.T) {
+	current := []wallet.ExchangeRate{{ID: uuid.New(), Base: "USD", Quote: "JPY", Rate: "151.2345", ValidFrom: time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC), Source: "manual"}}
+
+	t.Run("Success Test", func(t *testing.T) {
+		mockService := new(MockRateService)
+		mockService.On("ListCurrent", mock.Anything).Return(current, nil)
+		handler := handlers.NewRateHandler(mockService, false, slog.New(slog.DiscardHandler), noop.NewTracerProvider())
+		router := gin.Default()
+		handler.RegisterRoutes(&router.RouterGroup)
+
+		req, _ := http.NewRequest(http.MethodGet, "/rates", nil)
+		resp := httptest.NewRecorder()
+		router.ServeHTTP(resp, req)
+
+		assert.Equal(t, http.StatusOK, resp.Code)
+		var body struct {
+			Data responses.RatesData `json:"data"`
+		}
+		assert.NoError(t, json.Unmarshal(resp.Body.Bytes(), &body))
+		assert.Equal(t, current, body.Data.Rates)
+		mockService.AssertExpectations(t)
+	})
+
+	t.Run("Internal Server Error Test", func(t *testing.T) {
+		mockService := new(MockRateService)
+		mockService.On("ListCurrent", mock.Anything).Return([]wallet.ExchangeRate(nil), errors.New("error"))
+		handler := handlers.NewRateHandler(mockService, false, slog.New(slog.DiscardHandler), noop.NewTracerProvider())
+		router := gin.Default()
+		handler.RegisterRoutes(&router.RouterGroup)
+
+		req, _ := http.NewRequest(http.MethodGet, "/rates", nil)
+		resp := httptest.NewRecorder()
+		router.ServeHTTP(resp, req)
+
+		assert.Equal(t, http.StatusInternalServerError, resp.Code)
+		mockService.AssertExpectations(t)
+	})
+}
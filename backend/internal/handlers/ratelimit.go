@@ -0,0 +1,65 @@
+package handlers
+
+import (
+	"backend/internal/ratelimit"
+	"backend/pkg/auth"
+	"backend/pkg/customerror"
+	"backend/pkg/responses"
+	"log/slog"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type RateLimitHandlerI interface {
+	LimitClient(ctx *gin.Context)
+}
+
+type RateLimitHandler struct {
+	Limiter      ratelimit.LimiterI
+	LegacyStatus bool
+	Logger       *slog.Logger
+}
+
+func NewRateLimitHandler(limiter ratelimit.LimiterI, legacyStatus bool, logger *slog.Logger) RateLimitHandlerI {
+	return &RateLimitHandler{
+		Limiter:      limiter,
+		LegacyStatus: legacyStatus,
+		Logger:       logger,
+	}
+}
+
+// LimitClient throttles each authenticated caller on its own bucket. It must
+// run after Authenticate.
+func (RateLimitHandler *RateLimitHandler) LimitClient(ctx *gin.Context) {
+	principal := auth.FromContext(ctx.Request.Context())
+	if principal == nil {
+		ctx.Next()
+		return
+	}
+	retryAfter, err := RateLimitHandler.Limiter.AllowClient(ctx.Request.Context(), principal.ID)
+	if !allow(ctx, retryAfter, err, RateLimitHandler.LegacyStatus, RateLimitHandler.Logger) {
+		return
+	}
+	ctx.Next()
+}
+
+// allow answers 429 with Retry-After and returns false when retryAfter is set.
+// A failing store lets the request through: an outage of the limiter must not
+// stop payments.
+func allow(ctx *gin.Context, retryAfter time.Duration, err error, legacyStatus bool, logger *slog.Logger) bool {
+	if err != nil {
+		err = customerror.AppendModule(err, "allow")
+		logger.ErrorContext(ctx.Request.Context(), "rate limiter unavailable", slog.Any("error", err))
+		return true
+	}
+	if retryAfter <= 0 {
+		return true
+	}
+	seconds := max(1, int(math.Ceil(retryAfter.Seconds())))
+	ctx.Header("Retry-After", strconv.Itoa(seconds))
+	abortWithProblem(ctx, responses.ProblemRateLimited, legacyStatus)
+	return false
+}
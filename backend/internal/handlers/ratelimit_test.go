@@ -0,0 +1,143 @@
+package handlers_test
+
+import (
+	"backend/internal/handlers"
+	"backend/pkg/auth"
+	"backend/pkg/responses"
+	"backend/pkg/wallet"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+type MockLimiter struct {
+	mock.Mock
+}
+
+func (m *MockLimiter) AllowClient(ctx context.Context, clientID string) (time.Duration, error) {
+	args := m.Called(ctx, clientID)
+	return args.Get(0).(time.Duration), args.Error(1)
+}
+
+func (m *MockLimiter) AllowWallet(ctx context.Context, clientID string, walletID uuid.UUID) (time.Duration, error) {
+	args := m.Called(ctx, clientID, walletID)
+	return args.Get(0).(time.Duration), args.Error(1)
+}
+
+type LimitClientTest struct {
+	Name               string
+	RetryAfter         time.Duration
+	Err                error
+	ExpectedStatus     int
+	ExpectedRetryAfter string
+}
+
+func TestRateLimitHandler_LimitClient(t *testing.T) {
+	tests := []LimitClientTest{
+		{
+			Name:           "Allowed Test",
+			ExpectedStatus: http.StatusOK,
+		},
+		{
+			Name:               "Throttled Test",
+			RetryAfter:         1500 * time.Millisecond,
+			ExpectedStatus:     http.StatusTooManyRequests,
+			ExpectedRetryAfter: "2",
+		},
+		{
+			Name:           "Store Failure Test",
+			Err:            errors.New("connection refused"),
+			ExpectedStatus: http.StatusOK,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			limiter := new(MockLimiter)
+			limiter.On("AllowClient", mock.Anything, "key-1").Return(test.RetryAfter, test.Err)
+			handler := handlers.NewRateLimitHandler(limiter, false, slog.New(slog.DiscardHandler))
+
+			router := gin.Default()
+			router.Use(func(ctx *gin.Context) {
+				ctx.Request = ctx.Request.WithContext(auth.NewContext(ctx.Request.Context(), &auth.Principal{ID: "key-1"}))
+			}, handler.LimitClient)
+			router.GET("/wallets", func(ctx *gin.Context) { ctx.Status(http.StatusOK) })
+
+			req, _ := http.NewRequest(http.MethodGet, "/wallets", nil)
+			resp := httptest.NewRecorder()
+
+			router.ServeHTTP(resp, req)
+
+			assert.Equal(t, test.ExpectedStatus, resp.Code)
+			assert.Equal(t, test.ExpectedRetryAfter, resp.Header().Get("Retry-After"))
+			limiter.AssertExpectations(t)
+		})
+	}
+}
+
+func TestWalletHandler_RateLimit(t *testing.T) {
+	testID := uuid.New()
+	mockService := new(MockService)
+	limiter := new(MockLimiter)
+	// The bucket is the caller's own, so a stranger throttled on the wallet
+	// leaves its owner untouched.
+	limiter.On("AllowWallet", mock.Anything, "user-2", testID).Return(3*time.Second, nil)
+	handler := handlers.NewWalletHandler(mockService, limiter, false, slog.New(slog.DiscardHandler), noop.NewTracerProvider())
+
+	router := gin.Default()
+	router.Use(func(ctx *gin.Context) {
+		ctx.Request = ctx.Request.WithContext(auth.NewContext(ctx.Request.Context(), &auth.Principal{ID: "user-2", Subject: "user-2"}))
+	})
+	router.GET("/wallets/:id", handler.GetBalance)
+
+	req, _ := http.NewRequest(http.MethodGet, "/wallets/"+testID.String(), nil)
+	resp := httptest.NewRecorder()
+
+	router.ServeHTTP(resp, req)
+
+	assert.Equal(t, http.StatusTooManyRequests, resp.Code)
+	assert.Equal(t, "3", resp.Header().Get("Retry-After"))
+	var problem responses.Problem
+	err := json.Unmarshal(resp.Body.Bytes(), &problem)
+	assert.NoError(t, err)
+	assert.Equal(t, responses.CodeRateLimited, problem.Code)
+	mockService.AssertExpectations(t)
+	limiter.AssertExpectations(t)
+}
+
+func TestWalletHandler_HoldRateLimit(t *testing.T) {
+	hold := &wallet.Hold{ID: uuid.New(), WalletID: uuid.New(), Amount: 300, Status: wallet.HoldStatusActive}
+
+	for _, path := range []string{"/holds/" + hold.ID.String() + "/capture", "/holds/" + hold.ID.String() + "/release"} {
+		t.Run(path, func(t *testing.T) {
+			mockService := new(MockService)
+			mockService.On("GetHold", mock.Anything, hold.ID).Return(hold, nil)
+			limiter := new(MockLimiter)
+			// Holds are charged to the bucket of the wallet they are on.
+			limiter.On("AllowWallet", mock.Anything, "", hold.WalletID).Return(time.Second, nil)
+			handler := handlers.NewWalletHandler(mockService, limiter, false, slog.New(slog.DiscardHandler), noop.NewTracerProvider())
+
+			router := gin.Default()
+			handler.RegisterRoutes(&router.RouterGroup)
+
+			req, _ := http.NewRequest(http.MethodPost, path, http.NoBody)
+			resp := httptest.NewRecorder()
+
+			router.ServeHTTP(resp, req)
+
+			assert.Equal(t, http.StatusTooManyRequests, resp.Code)
+			mockService.AssertExpectations(t)
+			limiter.AssertExpectations(t)
+		})
+	}
+}
@@ -1,128 +1,482 @@
 package handlers
 
 import (
+	"backend/internal/logging"
+	"backend/internal/ratelimit"
+	"backend/internal/requestid"
 	"backend/internal/services"
+	"backend/internal/tracing"
+	"backend/pkg/auth"
 	"backend/pkg/customerror"
 	"backend/pkg/requests"
-	"log"
+	"backend/pkg/responses"
+	"backend/pkg/wallet"
+	"errors"
+	"io"
+	"log/slog"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
-	"github.com/jackc/pgx/v5"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 )
 
 type WalletHandlerI interface {
 	RegisterRoutes(router *gin.RouterGroup)
+	CreateWallet(ctx *gin.Context)
 	GetBalance(ctx *gin.Context)
 	UpdateBalance(ctx *gin.Context)
+	GetTransactions(ctx *gin.Context)
+	Transfer(ctx *gin.Context)
+	CreateHold(ctx *gin.Context)
+	GetHold(ctx *gin.Context)
+	CaptureHold(ctx *gin.Context)
+	ReleaseHold(ctx *gin.Context)
 }
 
 type WalletHandler struct {
 	WalletService services.WalletServiceI
+	// Limiter throttles each caller per wallet, so one hot wallet cannot take
+	// over the connection pool.
+	Limiter ratelimit.LimiterI
+	// LegacyStatus keeps the old behaviour of answering every request with
+	// HTTP 200 and reporting the real status only in the "status" field.
+	LegacyStatus bool
+	Logger       *slog.Logger
+	Tracer       trace.Tracer
 }
 
-func NewWalletHandler(walletService services.WalletServiceI) WalletHandlerI {
+func NewWalletHandler(walletService services.WalletServiceI, limiter ratelimit.LimiterI, legacyStatus bool, logger *slog.Logger, tracerProvider trace.TracerProvider) WalletHandlerI {
 	return &WalletHandler{
 		WalletService: walletService,
+		Limiter:       limiter,
+		LegacyStatus:  legacyStatus,
+		Logger:        logger,
+		Tracer:        tracerProvider.Tracer("backend/internal/handlers"),
+	}
+}
+
+// startSpan starts the handler span and makes it the parent of everything the
+// request context reaches afterwards.
+func (WalletHandler *WalletHandler) startSpan(ctx *gin.Context, name string) trace.Span {
+	spanCtx, span := WalletHandler.Tracer.Start(ctx.Request.Context(), name)
+	ctx.Request = ctx.Request.WithContext(spanCtx)
+	return span
+}
+
+// tagWallet adds the wallet id to the handler span and to every log record of
+// the request, including the access record written by the logging middleware.
+func tagWallet(ctx *gin.Context, id uuid.UUID) {
+	trace.SpanFromContext(ctx.Request.Context()).SetAttributes(tracing.WalletIDKey.String(id.String()))
+	ctx.Request = ctx.Request.WithContext(logging.WithAttrs(ctx.Request.Context(), slog.String("wallet_id", id.String())))
+}
+
+func tagTransfer(ctx *gin.Context, fromID uuid.UUID, toID uuid.UUID) {
+	tagWallet(ctx, fromID)
+	trace.SpanFromContext(ctx.Request.Context()).SetAttributes(tracing.ToWalletIDKey.String(toID.String()))
+	ctx.Request = ctx.Request.WithContext(logging.WithAttrs(ctx.Request.Context(), slog.String("to_wallet_id", toID.String())))
+}
+
+// allowWallet answers 429 and returns false when the caller has used up its
+// bucket for the wallet.
+func (WalletHandler *WalletHandler) allowWallet(ctx *gin.Context, id uuid.UUID) bool {
+	var clientID string
+	if principal := auth.FromContext(ctx.Request.Context()); principal != nil {
+		clientID = principal.ID
+	}
+	retryAfter, err := WalletHandler.Limiter.AllowWallet(ctx.Request.Context(), clientID, id)
+	return allow(ctx, retryAfter, err, WalletHandler.LegacyStatus, WalletHandler.Logger)
+}
+
+func (WalletHandler *WalletHandler) respond(ctx *gin.Context, status int, data interface{}) {
+	respond(ctx, status, data, WalletHandler.LegacyStatus)
+}
+
+func respond(ctx *gin.Context, status int, data interface{}, legacyStatus bool) {
+	if legacyStatus {
+		ctx.JSON(http.StatusOK, legacyEnvelope(ctx, responses.NewEnvelope(status, data)))
+		return
+	}
+	ctx.JSON(status, responses.NewEnvelope(status, data))
+}
+
+// legacyBodyKey marks requests whose legacy envelope keeps the payload under
+// "body", as POST /wallet always did.
+const legacyBodyKey = "legacyBody"
+
+// LegacyBody marks POST requests routed to path, the full path of the
+// UpdateBalance route. It goes in front of every other middleware, so errors
+// they answer with use the "body" envelope too.
+func LegacyBody(path string) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		if ctx.Request.Method == http.MethodPost && ctx.FullPath() == path {
+			ctx.Set(legacyBodyKey, true)
+		}
+		ctx.Next()
+	}
+}
+
+func legacyEnvelope(ctx *gin.Context, envelope responses.Envelope) interface{} {
+	if ctx.GetBool(legacyBodyKey) {
+		return envelope.LegacyBody()
+	}
+	return envelope
+}
+
+func (WalletHandler *WalletHandler) abortWithProblem(ctx *gin.Context, problem responses.Problem) {
+	abortWithProblem(ctx, problem, WalletHandler.LegacyStatus)
+}
+
+// abortWithProblem is shared by the handlers and the middlewares in front of
+// them, so clients see one error format whichever of them rejects a request.
+func abortWithProblem(ctx *gin.Context, problem responses.Problem, legacyStatus bool) {
+	if legacyStatus {
+		ctx.AbortWithStatusJSON(http.StatusOK, legacyEnvelope(ctx, responses.NewLegacyError(problem)))
+		return
+	}
+	problem.Instance = ctx.Request.URL.Path
+	problem.RequestID = requestid.FromContext(ctx.Request.Context())
+	ctx.Header("Content-Type", responses.ProblemContentType)
+	ctx.AbortWithStatusJSON(problem.Status, problem)
+}
+
+// abortWithError answers errors that no endpoint-specific check matched by
+// their kind. Internal errors are logged with the handler in the call stack.
+func (WalletHandler *WalletHandler) abortWithError(ctx *gin.Context, err error, module string) {
+	switch customerror.KindOf(err) {
+	case customerror.ErrNotFound:
+		WalletHandler.abortWithProblem(ctx, responses.ProblemNotFound)
+	case customerror.ErrConflict:
+		WalletHandler.abortWithProblem(ctx, responses.ProblemConflict)
+	case customerror.ErrValidation:
+		WalletHandler.abortWithProblem(ctx, responses.ProblemInvalidRequest)
+	case customerror.ErrUnauthorized:
+		WalletHandler.abortWithProblem(ctx, responses.ProblemUnauthorized)
+	case customerror.ErrForbidden:
+		WalletHandler.abortWithProblem(ctx, responses.ProblemForbidden)
+	default:
+		err = customerror.WithRequestID(customerror.AppendModule(err, module), requestid.FromContext(ctx.Request.Context()))
+		WalletHandler.Logger.ErrorContext(ctx.Request.Context(), "request failed", slog.Any("error", err))
+		span := trace.SpanFromContext(ctx.Request.Context())
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		WalletHandler.abortWithProblem(ctx, responses.ProblemInternal)
 	}
 }
 
 func (WalletHandler *WalletHandler) RegisterRoutes(router *gin.RouterGroup) {
 	router.POST("/wallet", WalletHandler.UpdateBalance)
+	router.POST("/wallets", WalletHandler.CreateWallet)
 	router.GET("/wallets/:id", WalletHandler.GetBalance)
+	router.GET("/wallets/:id/transactions", WalletHandler.GetTransactions)
+	router.POST("/transfers", WalletHandler.Transfer)
+	router.POST("/wallets/:id/holds", WalletHandler.CreateHold)
+	router.GET("/holds/:id", WalletHandler.GetHold)
+	router.POST("/holds/:id/capture", WalletHandler.CaptureHold)
+	router.POST("/holds/:id/release", WalletHandler.ReleaseHold)
 }
+
+func (WalletHandler *WalletHandler) CreateWallet(ctx *gin.Context) {
+	span := WalletHandler.startSpan(ctx, "WalletHandler.CreateWallet")
+	defer span.End()
+
+	var userRequest requests.CreateWalletRequest
+	err := ctx.ShouldBindJSON(&userRequest)
+	if err != nil && !errors.Is(err, io.EOF) {
+		WalletHandler.abortWithProblem(ctx, responses.ProblemInvalidRequest)
+		return
+	}
+	if userRequest.WalletId != uuid.Nil {
+		tagWallet(ctx, userRequest.WalletId)
+	}
+	decimal, ok := WalletHandler.decimalAmounts(ctx)
+	if !ok {
+		return
+	}
+	var in *wallet.Currency
+	if decimal {
+		in, err = WalletHandler.WalletService.GetCurrency(ctx.Request.Context(), userRequest.Currency)
+		if errors.Is(err, wallet.ErrUnknownCurrency) {
+			WalletHandler.abortWithProblem(ctx, responses.ProblemUnknownCurrency)
+			return
+		}
+		if err != nil {
+			WalletHandler.abortWithError(ctx, err, "CreateWallet")
+			return
+		}
+	}
+	amount, ok := WalletHandler.parseAmount(ctx, userRequest.Amount, in)
+	if !ok {
+		return
+	}
+	createdWallet, err := WalletHandler.WalletService.CreateWallet(ctx.Request.Context(), userRequest.WalletId, amount, userRequest.Currency)
+	if errors.Is(err, customerror.ErrInvalidAmount) {
+		WalletHandler.abortWithProblem(ctx, responses.ProblemInvalidAmount)
+		return
+	}
+	if errors.Is(err, wallet.ErrUnknownCurrency) {
+		WalletHandler.abortWithProblem(ctx, responses.ProblemUnknownCurrency)
+		return
+	}
+	if errors.Is(err, wallet.ErrAlreadyExists) {
+		WalletHandler.abortWithProblem(ctx, responses.ProblemWalletAlreadyExists)
+		return
+	}
+	if err != nil {
+		WalletHandler.abortWithError(ctx, err, "CreateWallet")
+		return
+	}
+
+	WalletHandler.respond(ctx, http.StatusCreated, responses.WalletData{
+		ID:         createdWallet.ID,
+		Balance:    responses.Amount{Value: createdWallet.Amount, In: in},
+		Currency:   createdWallet.Currency.Code,
+		MinorUnits: createdWallet.Currency.MinorUnits,
+	})
+}
+
 func (WalletHandler *WalletHandler) GetBalance(ctx *gin.Context) {
+	span := WalletHandler.startSpan(ctx, "WalletHandler.GetBalance")
+	defer span.End()
+
 	idStr := ctx.Param("id")
 	id, err := uuid.Parse(idStr)
 	if err != nil {
-		ctx.AbortWithStatusJSON(http.StatusOK, gin.H{
-			"status": http.StatusBadRequest,
-			"data":   gin.H{},
-			"error":  "Wrong uuid",
-		})
+		WalletHandler.abortWithProblem(ctx, responses.ProblemInvalidWalletID)
+		return
+	}
+	tagWallet(ctx, id)
+	decimal, ok := WalletHandler.decimalAmounts(ctx)
+	if !ok {
+		return
+	}
+	if !WalletHandler.allowWallet(ctx, id) {
 		return
 	}
-	balance, err := WalletHandler.WalletService.GetBalance(id)
-	if err == pgx.ErrNoRows {
-		ctx.AbortWithStatusJSON(http.StatusOK, gin.H{
-			"status": http.StatusNotFound,
-			"data":   gin.H{},
-			"error":  "Wallet not found",
-		})
+	foundWallet, err := WalletHandler.WalletService.GetBalance(ctx.Request.Context(), id)
+	if errors.Is(err, wallet.ErrNotFound) {
+		WalletHandler.abortWithProblem(ctx, responses.ProblemWalletNotFound)
 		return
 	}
 	if err != nil {
-		customError := err.(customerror.CustomError)
-		customError.AppendModule("GetBalance")
-		log.Printf("%s", customError.Error())
-		ctx.AbortWithStatusJSON(http.StatusOK, gin.H{
-			"status": http.StatusInternalServerError,
-			"data":   gin.H{},
-			"error":  "Internal Server Error",
-		})
-		return
-	}
-
-	ctx.JSON(http.StatusOK, gin.H{
-		"status": http.StatusOK,
-		"data": gin.H{
-			"balance": balance,
-		},
-		"error": nil,
+		WalletHandler.abortWithError(ctx, err, "GetBalance")
+		return
+	}
+
+	in := amountsIn(decimal, foundWallet.Currency)
+	WalletHandler.respond(ctx, http.StatusOK, responses.BalanceData{
+		Balance:    responses.Amount{Value: foundWallet.Amount, In: in},
+		Available:  responses.Amount{Value: foundWallet.Available(), In: in},
+		Currency:   foundWallet.Currency.Code,
+		MinorUnits: foundWallet.Currency.MinorUnits,
 	})
 }
 
 func (WalletHandler *WalletHandler) UpdateBalance(ctx *gin.Context) {
+	span := WalletHandler.startSpan(ctx, "WalletHandler.UpdateBalance")
+	defer span.End()
+
 	var userRequest requests.UpdateBalanceRequest
 	err := ctx.ShouldBindJSON(&userRequest)
 	if err != nil {
-		ctx.AbortWithStatusJSON(http.StatusOK, gin.H{
-			"status": http.StatusBadRequest,
-			"body":   gin.H{},
-			"error":  "Wrong input",
-		})
+		WalletHandler.abortWithProblem(ctx, responses.ProblemInvalidRequest)
+		return
+	}
+	tagWallet(ctx, userRequest.WalletId)
+	decimal, ok := WalletHandler.decimalAmounts(ctx)
+	if !ok {
+		return
+	}
+	if !WalletHandler.allowWallet(ctx, userRequest.WalletId) {
+		return
+	}
+	var in *wallet.Currency
+	if decimal {
+		if in = WalletHandler.walletCurrency(ctx, userRequest.WalletId, "UpdateBalance"); in == nil {
+			return
+		}
+	}
+	amount, ok := WalletHandler.parseAmount(ctx, userRequest.Amount, in)
+	if !ok {
+		return
+	}
+	idempotencyKey := ctx.GetHeader("Idempotency-Key")
+	if idempotencyKey == "" {
+		idempotencyKey = userRequest.IdempotencyKey
+	}
+	transaction, err := WalletHandler.WalletService.UpdateBalance(ctx.Request.Context(), userRequest.WalletId, userRequest.OperationType, amount, userRequest.Currency, idempotencyKey)
+	if errors.Is(err, customerror.ErrInvalidAmount) {
+		WalletHandler.abortWithProblem(ctx, responses.ProblemInvalidAmount)
+		return
+	}
+	if errors.Is(err, customerror.ErrWrongAmount) {
+		WalletHandler.abortWithProblem(ctx, responses.ProblemInsufficientFunds)
+		return
+	}
+	if errors.Is(err, customerror.ErrAmountOutOfRange) {
+		WalletHandler.abortWithProblem(ctx, responses.ProblemAmountOutOfRange)
+		return
+	}
+	if errors.Is(err, customerror.ErrWrongOperation) {
+		WalletHandler.abortWithProblem(ctx, responses.ProblemInvalidOperation)
+		return
+	}
+	if errors.Is(err, wallet.ErrCurrencyMismatch) {
+		WalletHandler.abortWithProblem(ctx, responses.ProblemCurrencyMismatch)
+		return
+	}
+	if errors.Is(err, customerror.ErrWrongIdempotencyKey) {
+		WalletHandler.abortWithProblem(ctx, responses.ProblemInvalidIdempotencyKey)
+		return
+	}
+	if errors.Is(err, customerror.ErrIdempotencyKeyReused) {
+		WalletHandler.abortWithProblem(ctx, responses.ProblemIdempotencyKeyConflict)
+		return
+	}
+	if errors.Is(err, wallet.ErrNotFound) {
+		WalletHandler.abortWithProblem(ctx, responses.ProblemWalletNotFound)
+		return
+	}
+	if err != nil {
+		WalletHandler.abortWithError(ctx, err, "UpdateBalance")
+		return
+	}
+	if transaction.Replayed {
+		ctx.Header("Idempotent-Replayed", "true")
+	}
+	WalletHandler.respond(ctx, http.StatusOK, responses.TransactionData{
+		TransactionID: transaction.ID,
+		Balance:       responses.Amount{Value: transaction.Balance, In: in},
+	})
+}
+
+func (WalletHandler *WalletHandler) GetTransactions(ctx *gin.Context) {
+	span := WalletHandler.startSpan(ctx, "WalletHandler.GetTransactions")
+	defer span.End()
+
+	idStr := ctx.Param("id")
+	id, err := uuid.Parse(idStr)
+	if err != nil {
+		WalletHandler.abortWithProblem(ctx, responses.ProblemInvalidWalletID)
+		return
+	}
+	tagWallet(ctx, id)
+	decimal, ok := WalletHandler.decimalAmounts(ctx)
+	if !ok {
+		return
+	}
+	if !WalletHandler.allowWallet(ctx, id) {
+		return
+	}
+	var userRequest requests.GetTransactionsRequest
+	err = ctx.ShouldBindQuery(&userRequest)
+	if err != nil {
+		WalletHandler.abortWithProblem(ctx, responses.ProblemInvalidRequest)
+		return
+	}
+	page, err := WalletHandler.WalletService.GetTransactions(ctx.Request.Context(), id, userRequest)
+	if errors.Is(err, customerror.ErrWrongCursor) {
+		WalletHandler.abortWithProblem(ctx, responses.ProblemInvalidCursor)
+		return
+	}
+	if errors.Is(err, customerror.ErrWrongOperation) {
+		WalletHandler.abortWithProblem(ctx, responses.ProblemInvalidFilterOperation)
+		return
+	}
+	if errors.Is(err, wallet.ErrNotFound) {
+		WalletHandler.abortWithProblem(ctx, responses.ProblemWalletNotFound)
+		return
+	}
+	if err != nil {
+		WalletHandler.abortWithError(ctx, err, "GetTransactions")
+		return
+	}
+
+	data := responses.TransactionsData{
+		Transactions: responses.NewTransactions(page.Transactions, amountsIn(decimal, page.Currency)),
+	}
+	if page.NextCursor != "" {
+		data.NextCursor = &page.NextCursor
+	}
+	WalletHandler.respond(ctx, http.StatusOK, data)
+}
+
+func (WalletHandler *WalletHandler) Transfer(ctx *gin.Context) {
+	span := WalletHandler.startSpan(ctx, "WalletHandler.Transfer")
+	defer span.End()
+
+	var userRequest requests.TransferRequest
+	err := ctx.ShouldBindJSON(&userRequest)
+	if err != nil {
+		WalletHandler.abortWithProblem(ctx, responses.ProblemInvalidRequest)
+		return
+	}
+	tagTransfer(ctx, userRequest.FromWalletId, userRequest.ToWalletId)
+	decimal, ok := WalletHandler.decimalAmounts(ctx)
+	if !ok {
+		return
+	}
+	if !WalletHandler.allowWallet(ctx, userRequest.FromWalletId) {
+		return
+	}
+	// A decimal amount is in the currency of the source wallet.
+	var in *wallet.Currency
+	if decimal {
+		if in = WalletHandler.walletCurrency(ctx, userRequest.FromWalletId, "Transfer"); in == nil {
+			return
+		}
+	}
+	amount, ok := WalletHandler.parseAmount(ctx, userRequest.Amount, in)
+	if !ok {
+		return
+	}
+	transfer, err := WalletHandler.WalletService.Transfer(ctx.Request.Context(), userRequest.FromWalletId, userRequest.ToWalletId, amount, userRequest.Currency)
+	if errors.Is(err, customerror.ErrInvalidAmount) {
+		WalletHandler.abortWithProblem(ctx, responses.ProblemInvalidAmount)
+		return
+	}
+	if errors.Is(err, customerror.ErrAmountOutOfRange) {
+		WalletHandler.abortWithProblem(ctx, responses.ProblemAmountOutOfRange)
+		return
+	}
+	if errors.Is(err, customerror.ErrWrongAmount) {
+		WalletHandler.abortWithProblem(ctx, responses.ProblemInsufficientFunds)
+		return
+	}
+	if errors.Is(err, customerror.ErrSameWallet) {
+		WalletHandler.abortWithProblem(ctx, responses.ProblemSameWallet)
 		return
 	}
-	err = WalletHandler.WalletService.UpdateBalance(userRequest.WalletId, userRequest.OperationType, userRequest.Amount)
-	if err == customerror.ErrWrongAmount {
-		ctx.AbortWithStatusJSON(http.StatusOK, gin.H{
-			"status": http.StatusBadRequest,
-			"body":   gin.H{},
-			"error":  "Amount cant be less than zero",
-		})
+	if errors.Is(err, wallet.ErrCurrencyMismatch) {
+		WalletHandler.abortWithProblem(ctx, responses.ProblemCurrencyMismatch)
 		return
 	}
-	if err == customerror.ErrWrongOperation {
-		ctx.AbortWithStatusJSON(http.StatusOK, gin.H{
-			"status": http.StatusBadRequest,
-			"body":   gin.H{},
-			"error":  "Operation must be DEPOSIT or WITHDRAW",
-		})
+	if errors.Is(err, wallet.ErrExchangeRateNotFound) {
+		WalletHandler.abortWithProblem(ctx, responses.ProblemExchangeRateNotFound)
 		return
 	}
-	if err == pgx.ErrNoRows {
-		ctx.AbortWithStatusJSON(http.StatusOK, gin.H{
-			"status": http.StatusNotFound,
-			"body":   gin.H{},
-			"error":  "Wallet not found",
-		})
+	if errors.Is(err, wallet.ErrNotFound) {
+		WalletHandler.abortWithProblem(ctx, responses.ProblemWalletNotFound)
 		return
 	}
 	if err != nil {
-		customError := err.(customerror.CustomError)
-		customError.AppendModule("UpdateBalance")
-		log.Printf("%s", customError.Error())
-		ctx.AbortWithStatusJSON(http.StatusOK, gin.H{
-			"status": http.StatusInternalServerError,
-			"data":   gin.H{},
-			"error":  "Internal Server Error",
-		})
-		return
-	}
-	ctx.JSON(http.StatusOK, gin.H{
-		"status": http.StatusOK,
-		"body":   gin.H{},
-		"error":  nil,
+		WalletHandler.abortWithError(ctx, err, "Transfer")
+		return
+	}
+
+	var toBalance *responses.Amount
+	if principal := auth.FromContext(ctx.Request.Context()); principal == nil || principal.Owns(transfer.ToOwnerID) {
+		toBalance = &responses.Amount{Value: transfer.Credit.Balance, In: amountsIn(decimal, transfer.ToCurrency)}
+	}
+	WalletHandler.respond(ctx, http.StatusOK, responses.TransferData{
+		TransferID:     transfer.ID,
+		FromWalletID:   transfer.Debit.WalletID,
+		ToWalletID:     transfer.Credit.WalletID,
+		Amount:         responses.Amount{Value: -transfer.Debit.Amount, In: amountsIn(decimal, transfer.FromCurrency)},
+		CreditedAmount: responses.Amount{Value: transfer.Credit.Amount, In: amountsIn(decimal, transfer.ToCurrency)},
+		ExchangeRate:   transfer.Credit.ExchangeRate,
+		FromBalance:    responses.Amount{Value: transfer.Debit.Balance, In: amountsIn(decimal, transfer.FromCurrency)},
+		ToBalance:      toBalance,
 	})
 }
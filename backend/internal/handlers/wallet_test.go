@@ -2,34 +2,233 @@ package handlers_test
 
 import (
 	"backend/internal/handlers"
+	"backend/internal/ratelimit"
+	"backend/internal/requestid"
+	"backend/internal/tracing"
+	"backend/pkg/auth"
 	"backend/pkg/customerror"
 	"backend/pkg/requests"
+	"backend/pkg/responses"
+	"backend/pkg/wallet"
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
+	"log/slog"
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
-	"github.com/jackc/pgx/v5"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
 )
 
 type MockService struct {
 	mock.Mock
 }
 
-func (m *MockService) GetBalance(id uuid.UUID) (int64, error) {
-	args := m.Called(id)
-	return args.Get(0).(int64), args.Error(1)
+func (m *MockService) CreateWallet(ctx context.Context, id uuid.UUID, amount int64, currency string) (*wallet.Wallet, error) {
+	args := m.Called(ctx, id, amount, currency)
+	return args.Get(0).(*wallet.Wallet), args.Error(1)
 }
 
-func (m *MockService) UpdateBalance(id uuid.UUID, operationType string, amount int64) error {
-	args := m.Called(id, operationType, amount)
-	return args.Error(0)
+func (m *MockService) GetBalance(ctx context.Context, id uuid.UUID) (*wallet.Wallet, error) {
+	args := m.Called(ctx, id)
+	return args.Get(0).(*wallet.Wallet), args.Error(1)
+}
+
+func (m *MockService) GetCurrency(ctx context.Context, code string) (*wallet.Currency, error) {
+	args := m.Called(ctx, code)
+	return args.Get(0).(*wallet.Currency), args.Error(1)
+}
+
+func (m *MockService) UpdateBalance(ctx context.Context, id uuid.UUID, operationType string, amount int64, currency string, idempotencyKey string) (*wallet.Transaction, error) {
+	args := m.Called(ctx, id, operationType, amount, currency, idempotencyKey)
+	return args.Get(0).(*wallet.Transaction), args.Error(1)
+}
+
+type CreateWalletTest struct {
+	Name           string
+	Body           string
+	Mock           func(*MockService)
+	ExpectedStatus int
+	ExpectedBody   gin.H
+	ExpectedCode   string
+}
+
+func TestWalletHandler_CreateWallet(t *testing.T) {
+	testID := uuid.New()
+
+	tests := []CreateWalletTest{
+		{
+			Name: "Success Test",
+			Body: fmt.Sprintf(`{"walletId":"%s","amount":100}`, testID),
+			Mock: func(s *MockService) {
+				s.On("CreateWallet", mock.Anything, testID, int64(100), "").Return(&wallet.Wallet{ID: testID, Amount: 100, Currency: wallet.Currency{Code: "USD", MinorUnits: 2}}, nil)
+			},
+			ExpectedStatus: http.StatusCreated,
+			ExpectedBody: gin.H{
+				"status": float64(201),
+				"data": map[string]interface{}{
+					"id":         testID.String(),
+					"balance":    float64(100),
+					"currency":   "USD",
+					"minorUnits": float64(2),
+				},
+				"error": nil,
+			},
+		},
+		{
+			Name: "Currency Test",
+			Body: fmt.Sprintf(`{"walletId":"%s","amount":100,"currency":"JPY"}`, testID),
+			Mock: func(s *MockService) {
+				s.On("CreateWallet", mock.Anything, testID, int64(100), "JPY").Return(&wallet.Wallet{ID: testID, Amount: 100, Currency: wallet.Currency{Code: "JPY", MinorUnits: 0}}, nil)
+			},
+			ExpectedStatus: http.StatusCreated,
+			ExpectedBody: gin.H{
+				"status": float64(201),
+				"data": map[string]interface{}{
+					"id":         testID.String(),
+					"balance":    float64(100),
+					"currency":   "JPY",
+					"minorUnits": float64(0),
+				},
+				"error": nil,
+			},
+		},
+		{
+			Name: "Empty Body Test",
+			Body: "",
+			Mock: func(s *MockService) {
+				s.On("CreateWallet", mock.Anything, uuid.Nil, int64(0), "").Return(&wallet.Wallet{ID: testID, Amount: 0, Currency: wallet.Currency{Code: "USD", MinorUnits: 2}}, nil)
+			},
+			ExpectedStatus: http.StatusCreated,
+			ExpectedBody: gin.H{
+				"status": float64(201),
+				"data": map[string]interface{}{
+					"id":         testID.String(),
+					"balance":    float64(0),
+					"currency":   "USD",
+					"minorUnits": float64(2),
+				},
+				"error": nil,
+			},
+		},
+		{
+			Name:           "Wrong Input Test",
+			Body:           `{"walletId":"invalid"}`,
+			Mock:           func(s *MockService) {},
+			ExpectedStatus: http.StatusBadRequest,
+			ExpectedCode:   responses.CodeInvalidRequest,
+		},
+		{
+			Name: "Wrong Amount Test",
+			Body: `{"amount":-100}`,
+			Mock: func(s *MockService) {
+				s.On("CreateWallet", mock.Anything, uuid.Nil, int64(-100), "").Return((*wallet.Wallet)(nil), customerror.ErrInvalidAmount)
+			},
+			ExpectedStatus: http.StatusBadRequest,
+			ExpectedCode:   responses.CodeInvalidAmount,
+		},
+		{
+			Name: "Unknown Currency Test",
+			Body: fmt.Sprintf(`{"walletId":"%s","currency":"XXX"}`, testID),
+			Mock: func(s *MockService) {
+				s.On("CreateWallet", mock.Anything, testID, int64(0), "XXX").Return((*wallet.Wallet)(nil), wallet.ErrUnknownCurrency)
+			},
+			ExpectedStatus: http.StatusBadRequest,
+			ExpectedCode:   responses.CodeUnknownCurrency,
+		},
+		{
+			Name: "Duplicate Test",
+			Body: fmt.Sprintf(`{"walletId":"%s"}`, testID),
+			Mock: func(s *MockService) {
+				s.On("CreateWallet", mock.Anything, testID, int64(0), "").Return((*wallet.Wallet)(nil), wallet.ErrAlreadyExists)
+			},
+			ExpectedStatus: http.StatusConflict,
+			ExpectedCode:   responses.CodeWalletAlreadyExists,
+		},
+		{
+			Name: "Internal Server Error Test",
+			Body: "{}",
+			Mock: func(s *MockService) {
+				s.On("CreateWallet", mock.Anything, uuid.Nil, int64(0), "").Return((*wallet.Wallet)(nil), customerror.NewError("", "", "error"))
+			},
+			ExpectedStatus: http.StatusInternalServerError,
+			ExpectedCode:   responses.CodeInternal,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			mockService := new(MockService)
+			test.Mock(mockService)
+
+			handler := handlers.NewWalletHandler(mockService, ratelimit.NewNop(), false, slog.New(slog.DiscardHandler), noop.NewTracerProvider())
+
+			router := gin.Default()
+			router.POST("/wallets", handler.CreateWallet)
+
+			req, _ := http.NewRequest(http.MethodPost, "/wallets", bytes.NewBufferString(test.Body))
+			req.Header.Set("Content-Type", "application/json")
+			resp := httptest.NewRecorder()
+
+			router.ServeHTTP(resp, req)
+
+			assert.Equal(t, test.ExpectedStatus, resp.Code)
+
+			var body gin.H
+			err := json.Unmarshal(resp.Body.Bytes(), &body)
+			assert.NoError(t, err)
+			if test.ExpectedCode != "" {
+				assert.Equal(t, responses.ProblemContentType, resp.Header().Get("Content-Type"))
+				assert.Equal(t, test.ExpectedCode, body["code"])
+				assert.Equal(t, float64(test.ExpectedStatus), body["status"])
+			} else {
+				assert.Equal(t, test.ExpectedBody, body)
+			}
+
+			mockService.AssertExpectations(t)
+		})
+	}
+}
+
+func (m *MockService) GetTransactions(ctx context.Context, id uuid.UUID, request requests.GetTransactionsRequest) (*wallet.TransactionPage, error) {
+	args := m.Called(ctx, id, request)
+	return args.Get(0).(*wallet.TransactionPage), args.Error(1)
+}
+
+func (m *MockService) Transfer(ctx context.Context, fromID uuid.UUID, toID uuid.UUID, amount int64, currency string) (*wallet.Transfer, error) {
+	args := m.Called(ctx, fromID, toID, amount, currency)
+	return args.Get(0).(*wallet.Transfer), args.Error(1)
+}
+
+func (m *MockService) CreateHold(ctx context.Context, walletID uuid.UUID, amount int64, currency string, expiresAt *time.Time) (*wallet.Hold, error) {
+	args := m.Called(ctx, walletID, amount, currency, expiresAt)
+	return args.Get(0).(*wallet.Hold), args.Error(1)
+}
+
+func (m *MockService) GetHold(ctx context.Context, id uuid.UUID) (*wallet.Hold, error) {
+	args := m.Called(ctx, id)
+	return args.Get(0).(*wallet.Hold), args.Error(1)
+}
+
+func (m *MockService) CaptureHold(ctx context.Context, id uuid.UUID, amount *int64) (*wallet.Hold, error) {
+	args := m.Called(ctx, id, amount)
+	return args.Get(0).(*wallet.Hold), args.Error(1)
+}
+
+func (m *MockService) ReleaseHold(ctx context.Context, id uuid.UUID) (*wallet.Hold, error) {
+	args := m.Called(ctx, id)
+	return args.Get(0).(*wallet.Hold), args.Error(1)
 }
 
 type GetBalanceTest struct {
@@ -38,6 +237,7 @@ type GetBalanceTest struct {
 	Mock           func(*MockService)
 	ExpectedStatus int
 	ExpectedBody   gin.H
+	ExpectedCode   string
 }
 
 func TestWalletHandler_GetBalance(t *testing.T) {
@@ -48,13 +248,16 @@ func TestWalletHandler_GetBalance(t *testing.T) {
 			Name:     "Success Test",
 			WalletId: testID.String(),
 			Mock: func(s *MockService) {
-				s.On("GetBalance", testID).Return(int64(100), nil)
+				s.On("GetBalance", mock.Anything, testID).Return(&wallet.Wallet{ID: testID, Amount: 100, Held: 30, Currency: wallet.Currency{Code: "USD", MinorUnits: 2}}, nil)
 			},
 			ExpectedStatus: http.StatusOK,
 			ExpectedBody: gin.H{
 				"status": float64(200),
 				"data": map[string]interface{}{
-					"balance": float64(100),
+					"balance":    float64(100),
+					"available":  float64(70),
+					"currency":   "USD",
+					"minorUnits": float64(2),
 				},
 				"error": nil,
 			},
@@ -63,38 +266,44 @@ func TestWalletHandler_GetBalance(t *testing.T) {
 			Name:           "Invalid UUID Test",
 			WalletId:       "invalid",
 			Mock:           func(s *MockService) {},
-			ExpectedStatus: http.StatusOK,
-			ExpectedBody: gin.H{
-				"status": float64(400),
-				"data":   map[string]interface{}{},
-				"error":  "Wrong uuid",
-			},
+			ExpectedStatus: http.StatusBadRequest,
+			ExpectedCode:   responses.CodeInvalidWalletID,
 		},
 		{
 			Name:     "Not Found Test",
 			WalletId: testID.String(),
 			Mock: func(s *MockService) {
-				s.On("GetBalance", testID).Return(int64(0), pgx.ErrNoRows)
-			},
-			ExpectedStatus: http.StatusOK,
-			ExpectedBody: gin.H{
-				"status": float64(404),
-				"data":   map[string]interface{}{},
-				"error":  "Wallet not found",
+				s.On("GetBalance", mock.Anything, testID).Return((*wallet.Wallet)(nil), wallet.ErrNotFound)
 			},
+			ExpectedStatus: http.StatusNotFound,
+			ExpectedCode:   responses.CodeWalletNotFound,
 		},
 		{
 			Name:     "Internal Server Error Test",
 			WalletId: testID.String(),
 			Mock: func(s *MockService) {
-				s.On("GetBalance", testID).Return(int64(0), customerror.NewError("", "", "error"))
+				s.On("GetBalance", mock.Anything, testID).Return((*wallet.Wallet)(nil), customerror.NewError("", "", "error"))
 			},
-			ExpectedStatus: http.StatusOK,
-			ExpectedBody: gin.H{
-				"status": float64(500),
-				"data":   map[string]interface{}{},
-				"error":  "Internal Server Error",
+			ExpectedStatus: http.StatusInternalServerError,
+			ExpectedCode:   responses.CodeInternal,
+		},
+		{
+			Name:     "Foreign Error Test",
+			WalletId: testID.String(),
+			Mock: func(s *MockService) {
+				s.On("GetBalance", mock.Anything, testID).Return((*wallet.Wallet)(nil), context.DeadlineExceeded)
+			},
+			ExpectedStatus: http.StatusInternalServerError,
+			ExpectedCode:   responses.CodeInternal,
+		},
+		{
+			Name:     "Wrapped Not Found Test",
+			WalletId: testID.String(),
+			Mock: func(s *MockService) {
+				s.On("GetBalance", mock.Anything, testID).Return((*wallet.Wallet)(nil), fmt.Errorf("lookup: %w", wallet.ErrNotFound))
 			},
+			ExpectedStatus: http.StatusNotFound,
+			ExpectedCode:   responses.CodeWalletNotFound,
 		},
 	}
 
@@ -103,7 +312,7 @@ func TestWalletHandler_GetBalance(t *testing.T) {
 			mockService := new(MockService)
 			test.Mock(mockService)
 
-			handler := handlers.NewWalletHandler(mockService)
+			handler := handlers.NewWalletHandler(mockService, ratelimit.NewNop(), false, slog.New(slog.DiscardHandler), noop.NewTracerProvider())
 
 			router := gin.Default()
 			router.GET("/wallets/:id", handler.GetBalance)
@@ -118,7 +327,13 @@ func TestWalletHandler_GetBalance(t *testing.T) {
 			var body gin.H
 			err := json.Unmarshal(resp.Body.Bytes(), &body)
 			assert.NoError(t, err)
-			assert.Equal(t, test.ExpectedBody, body)
+			if test.ExpectedCode != "" {
+				assert.Equal(t, responses.ProblemContentType, resp.Header().Get("Content-Type"))
+				assert.Equal(t, test.ExpectedCode, body["code"])
+				assert.Equal(t, float64(test.ExpectedStatus), body["status"])
+			} else {
+				assert.Equal(t, test.ExpectedBody, body)
+			}
 
 			mockService.AssertExpectations(t)
 		})
@@ -126,15 +341,20 @@ func TestWalletHandler_GetBalance(t *testing.T) {
 }
 
 type UpdateBalanceTest struct {
-	Name           string
-	Request        requests.UpdateBalanceRequest
-	Mock           func(*MockService)
-	ExpectedStatus int
-	ExpectedBody   gin.H
+	Name            string
+	Request         requests.UpdateBalanceRequest
+	IdempotencyKey  string
+	Mock            func(*MockService)
+	ExpectedStatus  int
+	ExpectedBody    gin.H
+	ExpectedCode    string
+	ExpectedHeaders map[string]string
 }
 
 func TestWalletHandler_UpdateBalance(t *testing.T) {
 	testID := uuid.New()
+	testTransaction := &wallet.Transaction{ID: uuid.New(), WalletID: testID, OperationType: "DEPOSIT", Amount: 100, Balance: 100}
+	replayedTransaction := &wallet.Transaction{ID: uuid.New(), WalletID: testID, OperationType: "DEPOSIT", Amount: 100, Balance: 100, Replayed: true}
 
 	tests := []UpdateBalanceTest{
 		{
@@ -142,85 +362,166 @@ func TestWalletHandler_UpdateBalance(t *testing.T) {
 			Request: requests.UpdateBalanceRequest{
 				WalletId:      testID,
 				OperationType: "DEPOSIT",
-				Amount:        100,
+				Amount:        "100",
+			},
+			Mock: func(s *MockService) {
+				s.On("UpdateBalance", mock.Anything, testID, "DEPOSIT", int64(100), "", "").Return(testTransaction, nil)
+			},
+			ExpectedStatus: http.StatusOK,
+			ExpectedBody: gin.H{
+				"status": float64(200),
+				"data": map[string]interface{}{
+					"transactionId": testTransaction.ID.String(),
+					"balance":       float64(100),
+				},
+				"error": nil,
 			},
+		},
+		{
+			Name: "Idempotency Header Test",
+			Request: requests.UpdateBalanceRequest{
+				WalletId:       testID,
+				OperationType:  "DEPOSIT",
+				Amount:         "100",
+				IdempotencyKey: "body-key",
+			},
+			IdempotencyKey: "header-key",
 			Mock: func(s *MockService) {
-				s.On("UpdateBalance", testID, "DEPOSIT", int64(100)).Return(nil)
+				s.On("UpdateBalance", mock.Anything, testID, "DEPOSIT", int64(100), "", "header-key").Return(testTransaction, nil)
 			},
 			ExpectedStatus: http.StatusOK,
 			ExpectedBody: gin.H{
 				"status": float64(200),
-				"body":   map[string]interface{}{},
 				"error":  nil,
 			},
 		},
+		{
+			Name: "Idempotent Replay Test",
+			Request: requests.UpdateBalanceRequest{
+				WalletId:       testID,
+				OperationType:  "DEPOSIT",
+				Amount:         "100",
+				IdempotencyKey: "body-key",
+			},
+			Mock: func(s *MockService) {
+				s.On("UpdateBalance", mock.Anything, testID, "DEPOSIT", int64(100), "", "body-key").Return(replayedTransaction, nil)
+			},
+			ExpectedStatus: http.StatusOK,
+			ExpectedBody: gin.H{
+				"status": float64(200),
+				"data": map[string]interface{}{
+					"transactionId": replayedTransaction.ID.String(),
+					"balance":       float64(100),
+				},
+				"error": nil,
+			},
+			ExpectedHeaders: map[string]string{"Idempotent-Replayed": "true"},
+		},
+		{
+			Name: "Idempotency Key Reused Test",
+			Request: requests.UpdateBalanceRequest{
+				WalletId:      testID,
+				OperationType: "DEPOSIT",
+				Amount:        "100",
+			},
+			IdempotencyKey: "key",
+			Mock: func(s *MockService) {
+				s.On("UpdateBalance", mock.Anything, testID, "DEPOSIT", int64(100), "", "key").Return((*wallet.Transaction)(nil), customerror.ErrIdempotencyKeyReused)
+			},
+			ExpectedStatus: http.StatusConflict,
+			ExpectedCode:   responses.CodeIdempotencyKeyConflict,
+		},
 		{
 			Name: "Invalid Operation Type Test",
 			Request: requests.UpdateBalanceRequest{
 				WalletId:      testID,
 				OperationType: "INVALID",
-				Amount:        100,
+				Amount:        "100",
 			},
 			Mock: func(s *MockService) {
-				s.On("UpdateBalance", testID, "INVALID", int64(100)).Return(customerror.ErrWrongOperation)
+				s.On("UpdateBalance", mock.Anything, testID, "INVALID", int64(100), "", "").Return((*wallet.Transaction)(nil), customerror.ErrWrongOperation)
 			},
-			ExpectedStatus: http.StatusOK,
-			ExpectedBody: gin.H{
-				"status": float64(400),
-				"body":   map[string]interface{}{},
-				"error":  "Operation must be DEPOSIT or WITHDRAW",
+			ExpectedStatus: http.StatusBadRequest,
+			ExpectedCode:   responses.CodeInvalidOperation,
+		},
+		{
+			Name: "Negative Amount Test",
+			Request: requests.UpdateBalanceRequest{
+				WalletId:      testID,
+				OperationType: "WITHDRAW",
+				Amount:        "-500",
 			},
+			Mock: func(s *MockService) {
+				s.On("UpdateBalance", mock.Anything, testID, "WITHDRAW", int64(-500), "", "").Return((*wallet.Transaction)(nil), customerror.ErrInvalidAmount)
+			},
+			ExpectedStatus: http.StatusBadRequest,
+			ExpectedCode:   responses.CodeInvalidAmount,
+		},
+		{
+			Name: "Zero Amount Test",
+			Request: requests.UpdateBalanceRequest{
+				WalletId:      testID,
+				OperationType: "DEPOSIT",
+				Amount:        "0",
+			},
+			Mock: func(s *MockService) {
+				s.On("UpdateBalance", mock.Anything, testID, "DEPOSIT", int64(0), "", "").Return((*wallet.Transaction)(nil), customerror.ErrInvalidAmount)
+			},
+			ExpectedStatus: http.StatusBadRequest,
+			ExpectedCode:   responses.CodeInvalidAmount,
 		},
 		{
 			Name: "Wrong Amount Test",
 			Request: requests.UpdateBalanceRequest{
 				WalletId:      testID,
 				OperationType: "WITHDRAW",
-				Amount:        1000,
+				Amount:        "1000",
 			},
 			Mock: func(s *MockService) {
-				s.On("UpdateBalance", testID, "WITHDRAW", int64(1000)).Return(customerror.ErrWrongAmount)
+				s.On("UpdateBalance", mock.Anything, testID, "WITHDRAW", int64(1000), "", "").Return((*wallet.Transaction)(nil), customerror.ErrWrongAmount)
 			},
-			ExpectedStatus: http.StatusOK,
-			ExpectedBody: gin.H{
-				"status": float64(400),
-				"body":   map[string]interface{}{},
-				"error":  "Amount cant be less than zero",
+			ExpectedStatus: http.StatusBadRequest,
+			ExpectedCode:   responses.CodeInsufficientFunds,
+		},
+		{
+			Name: "Currency Mismatch Test",
+			Request: requests.UpdateBalanceRequest{
+				WalletId:      testID,
+				OperationType: "DEPOSIT",
+				Amount:        "100",
+				Currency:      "EUR",
+			},
+			Mock: func(s *MockService) {
+				s.On("UpdateBalance", mock.Anything, testID, "DEPOSIT", int64(100), "EUR", "").Return((*wallet.Transaction)(nil), wallet.ErrCurrencyMismatch)
 			},
+			ExpectedStatus: http.StatusBadRequest,
+			ExpectedCode:   responses.CodeCurrencyMismatch,
 		},
 		{
 			Name: "Not Found Test",
 			Request: requests.UpdateBalanceRequest{
 				WalletId:      testID,
 				OperationType: "DEPOSIT",
-				Amount:        100,
+				Amount:        "100",
 			},
 			Mock: func(s *MockService) {
-				s.On("UpdateBalance", testID, "DEPOSIT", int64(100)).Return(pgx.ErrNoRows)
-			},
-			ExpectedStatus: http.StatusOK,
-			ExpectedBody: gin.H{
-				"status": float64(404),
-				"body":   map[string]interface{}{},
-				"error":  "Wallet not found",
+				s.On("UpdateBalance", mock.Anything, testID, "DEPOSIT", int64(100), "", "").Return((*wallet.Transaction)(nil), wallet.ErrNotFound)
 			},
+			ExpectedStatus: http.StatusNotFound,
+			ExpectedCode:   responses.CodeWalletNotFound,
 		},
 		{
 			Name: "Internal Server Error Test",
 			Request: requests.UpdateBalanceRequest{
 				WalletId:      testID,
 				OperationType: "DEPOSIT",
-				Amount:        100,
+				Amount:        "100",
 			},
 			Mock: func(s *MockService) {
-				s.On("UpdateBalance", testID, "DEPOSIT", int64(100)).Return(customerror.NewError("", "", "error"))
-			},
-			ExpectedStatus: http.StatusOK,
-			ExpectedBody: gin.H{
-				"status": float64(500),
-				"data":   map[string]interface{}{},
-				"error":  "Internal Server Error",
+				s.On("UpdateBalance", mock.Anything, testID, "DEPOSIT", int64(100), "", "").Return((*wallet.Transaction)(nil), customerror.NewError("", "", "error"))
 			},
+			ExpectedStatus: http.StatusInternalServerError,
+			ExpectedCode:   responses.CodeInternal,
 		},
 	}
 
@@ -229,7 +530,7 @@ func TestWalletHandler_UpdateBalance(t *testing.T) {
 			mockService := new(MockService)
 			test.Mock(mockService)
 
-			handler := handlers.NewWalletHandler(mockService)
+			handler := handlers.NewWalletHandler(mockService, ratelimit.NewNop(), false, slog.New(slog.DiscardHandler), noop.NewTracerProvider())
 
 			router := gin.Default()
 			router.POST("/wallet", handler.UpdateBalance)
@@ -237,6 +538,9 @@ func TestWalletHandler_UpdateBalance(t *testing.T) {
 			body, _ := json.Marshal(test.Request)
 			req, _ := http.NewRequest(http.MethodPost, "/wallet", bytes.NewBuffer(body))
 			req.Header.Set("Content-Type", "application/json")
+			if test.IdempotencyKey != "" {
+				req.Header.Set("Idempotency-Key", test.IdempotencyKey)
+			}
 			resp := httptest.NewRecorder()
 
 			router.ServeHTTP(resp, req)
@@ -247,10 +551,778 @@ func TestWalletHandler_UpdateBalance(t *testing.T) {
 			err := json.Unmarshal(resp.Body.Bytes(), &responseBody)
 			assert.NoError(t, err)
 			fmt.Printf("%v", responseBody)
-			assert.Equal(t, test.ExpectedBody["status"], responseBody["status"])
-			assert.Equal(t, test.ExpectedBody["error"], responseBody["error"])
+			if test.ExpectedCode != "" {
+				assert.Equal(t, responses.ProblemContentType, resp.Header().Get("Content-Type"))
+				assert.Equal(t, test.ExpectedCode, responseBody["code"])
+				assert.Equal(t, float64(test.ExpectedStatus), responseBody["status"])
+			} else {
+				assert.Equal(t, test.ExpectedBody["status"], responseBody["status"])
+				assert.Equal(t, test.ExpectedBody["error"], responseBody["error"])
+			}
+			if test.ExpectedBody["data"] != nil {
+				assert.Equal(t, test.ExpectedBody["data"], responseBody["data"])
+			}
+			for header, value := range test.ExpectedHeaders {
+				assert.Equal(t, value, resp.Header().Get(header))
+			}
 
 			mockService.AssertExpectations(t)
 		})
 	}
 }
+
+type GetTransactionsTest struct {
+	Name           string
+	WalletId       string
+	Query          string
+	Mock           func(*MockService)
+	ExpectedStatus int
+	ExpectedBody   gin.H
+	ExpectedCode   string
+}
+
+func TestWalletHandler_GetTransactions(t *testing.T) {
+	testID := uuid.New()
+	testTime := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+	testTransaction := wallet.Transaction{
+		ID:            uuid.New(),
+		WalletID:      testID,
+		OperationType: "DEPOSIT",
+		Amount:        100,
+		Balance:       100,
+		CreatedAt:     testTime,
+	}
+	testTransactionBody := map[string]interface{}{
+		"id":            testTransaction.ID.String(),
+		"walletId":      testID.String(),
+		"operationType": "DEPOSIT",
+		"amount":        float64(100),
+		"balance":       float64(100),
+		"createdAt":     "2025-01-02T03:04:05Z",
+	}
+
+	tests := []GetTransactionsTest{
+		{
+			Name:     "Success Test",
+			WalletId: testID.String(),
+			Query:    "",
+			Mock: func(s *MockService) {
+				s.On("GetTransactions", mock.Anything, testID, requests.GetTransactionsRequest{}).Return(&wallet.TransactionPage{
+					Transactions: []wallet.Transaction{testTransaction},
+				}, nil)
+			},
+			ExpectedStatus: http.StatusOK,
+			ExpectedBody: gin.H{
+				"status": float64(200),
+				"data": map[string]interface{}{
+					"transactions": []interface{}{testTransactionBody},
+					"nextCursor":   nil,
+				},
+				"error": nil,
+			},
+		},
+		{
+			Name:     "Filters Test",
+			WalletId: testID.String(),
+			Query:    "?limit=1&operationType=DEPOSIT&order=oldest&from=2025-01-01T00:00:00Z&cursor=abc",
+			Mock: func(s *MockService) {
+				s.On("GetTransactions", mock.Anything, testID, requests.GetTransactionsRequest{
+					Limit:         1,
+					Cursor:        "abc",
+					OperationType: "DEPOSIT",
+					From:          time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC),
+					Order:         "oldest",
+				}).Return(&wallet.TransactionPage{
+					Transactions: []wallet.Transaction{testTransaction},
+					NextCursor:   "next",
+				}, nil)
+			},
+			ExpectedStatus: http.StatusOK,
+			ExpectedBody: gin.H{
+				"status": float64(200),
+				"data": map[string]interface{}{
+					"transactions": []interface{}{testTransactionBody},
+					"nextCursor":   "next",
+				},
+				"error": nil,
+			},
+		},
+		{
+			Name:           "Invalid UUID Test",
+			WalletId:       "invalid",
+			Mock:           func(s *MockService) {},
+			ExpectedStatus: http.StatusBadRequest,
+			ExpectedCode:   responses.CodeInvalidWalletID,
+		},
+		{
+			Name:           "Wrong Input Test",
+			WalletId:       testID.String(),
+			Query:          "?order=sideways",
+			Mock:           func(s *MockService) {},
+			ExpectedStatus: http.StatusBadRequest,
+			ExpectedCode:   responses.CodeInvalidRequest,
+		},
+		{
+			Name:     "Wrong Cursor Test",
+			WalletId: testID.String(),
+			Query:    "?cursor=invalid",
+			Mock: func(s *MockService) {
+				s.On("GetTransactions", mock.Anything, testID, requests.GetTransactionsRequest{Cursor: "invalid"}).Return((*wallet.TransactionPage)(nil), customerror.ErrWrongCursor)
+			},
+			ExpectedStatus: http.StatusBadRequest,
+			ExpectedCode:   responses.CodeInvalidCursor,
+		},
+		{
+			Name:     "Not Found Test",
+			WalletId: testID.String(),
+			Mock: func(s *MockService) {
+				s.On("GetTransactions", mock.Anything, testID, requests.GetTransactionsRequest{}).Return((*wallet.TransactionPage)(nil), wallet.ErrNotFound)
+			},
+			ExpectedStatus: http.StatusNotFound,
+			ExpectedCode:   responses.CodeWalletNotFound,
+		},
+		{
+			Name:     "Internal Server Error Test",
+			WalletId: testID.String(),
+			Mock: func(s *MockService) {
+				s.On("GetTransactions", mock.Anything, testID, requests.GetTransactionsRequest{}).Return((*wallet.TransactionPage)(nil), customerror.NewError("", "", "error"))
+			},
+			ExpectedStatus: http.StatusInternalServerError,
+			ExpectedCode:   responses.CodeInternal,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			mockService := new(MockService)
+			test.Mock(mockService)
+
+			handler := handlers.NewWalletHandler(mockService, ratelimit.NewNop(), false, slog.New(slog.DiscardHandler), noop.NewTracerProvider())
+
+			router := gin.Default()
+			router.GET("/wallets/:id/transactions", handler.GetTransactions)
+
+			req, _ := http.NewRequest(http.MethodGet, "/wallets/"+test.WalletId+"/transactions"+test.Query, nil)
+			resp := httptest.NewRecorder()
+
+			router.ServeHTTP(resp, req)
+
+			assert.Equal(t, test.ExpectedStatus, resp.Code)
+
+			var body gin.H
+			err := json.Unmarshal(resp.Body.Bytes(), &body)
+			assert.NoError(t, err)
+			if test.ExpectedCode != "" {
+				assert.Equal(t, responses.ProblemContentType, resp.Header().Get("Content-Type"))
+				assert.Equal(t, test.ExpectedCode, body["code"])
+				assert.Equal(t, float64(test.ExpectedStatus), body["status"])
+			} else {
+				assert.Equal(t, test.ExpectedBody, body)
+			}
+
+			mockService.AssertExpectations(t)
+		})
+	}
+}
+
+type TransferTest struct {
+	Name           string
+	Body           string
+	Principal      *auth.Principal
+	Mock           func(*MockService)
+	ExpectedStatus int
+	ExpectedBody   gin.H
+	ExpectedCode   string
+}
+
+func TestWalletHandler_Transfer(t *testing.T) {
+	fromID := uuid.New()
+	toID := uuid.New()
+	testTransfer := &wallet.Transfer{
+		ID:     uuid.New(),
+		Debit:  wallet.Transaction{WalletID: fromID, OperationType: "TRANSFER_OUT", Amount: -100, Balance: 0},
+		Credit: wallet.Transaction{WalletID: toID, OperationType: "TRANSFER_IN", Amount: 100, Balance: 100},
+	}
+	rate := "151.2345"
+	convertedTransfer := &wallet.Transfer{
+		ID:     uuid.New(),
+		Debit:  wallet.Transaction{WalletID: fromID, OperationType: "TRANSFER_OUT", Amount: -100, Balance: 0, ExchangeRate: &rate},
+		Credit: wallet.Transaction{WalletID: toID, OperationType: "TRANSFER_IN", Amount: 151, Balance: 151, ExchangeRate: &rate},
+	}
+	validBody := fmt.Sprintf(`{"fromWalletId":"%s","toWalletId":"%s","amount":100}`, fromID, toID)
+	owner := "user-1"
+	stranger := "user-2"
+	foreignTransfer := *testTransfer
+	foreignTransfer.ToOwnerID = &stranger
+	ownTransfer := *testTransfer
+	ownTransfer.ToOwnerID = &owner
+
+	tests := []TransferTest{
+		{
+			Name:      "Foreign Destination Test",
+			Body:      validBody,
+			Principal: &auth.Principal{ID: owner, Subject: owner},
+			Mock: func(s *MockService) {
+				s.On("Transfer", mock.Anything, fromID, toID, int64(100), "").Return(&foreignTransfer, nil)
+			},
+			ExpectedStatus: http.StatusOK,
+			ExpectedBody: gin.H{
+				"status": float64(200),
+				"data": map[string]interface{}{
+					"transferId":     testTransfer.ID.String(),
+					"fromWalletId":   fromID.String(),
+					"toWalletId":     toID.String(),
+					"amount":         float64(100),
+					"creditedAmount": float64(100),
+					"fromBalance":    float64(0),
+				},
+				"error": nil,
+			},
+		},
+		{
+			Name:      "Own Destination Test",
+			Body:      validBody,
+			Principal: &auth.Principal{ID: owner, Subject: owner},
+			Mock: func(s *MockService) {
+				s.On("Transfer", mock.Anything, fromID, toID, int64(100), "").Return(&ownTransfer, nil)
+			},
+			ExpectedStatus: http.StatusOK,
+			ExpectedBody: gin.H{
+				"status": float64(200),
+				"data": map[string]interface{}{
+					"transferId":     testTransfer.ID.String(),
+					"fromWalletId":   fromID.String(),
+					"toWalletId":     toID.String(),
+					"amount":         float64(100),
+					"creditedAmount": float64(100),
+					"fromBalance":    float64(0),
+					"toBalance":      float64(100),
+				},
+				"error": nil,
+			},
+		},
+		{
+			Name: "Success Test",
+			Body: validBody,
+			Mock: func(s *MockService) {
+				s.On("Transfer", mock.Anything, fromID, toID, int64(100), "").Return(testTransfer, nil)
+			},
+			ExpectedStatus: http.StatusOK,
+			ExpectedBody: gin.H{
+				"status": float64(200),
+				"data": map[string]interface{}{
+					"transferId":     testTransfer.ID.String(),
+					"fromWalletId":   fromID.String(),
+					"toWalletId":     toID.String(),
+					"amount":         float64(100),
+					"creditedAmount": float64(100),
+					"fromBalance":    float64(0),
+					"toBalance":      float64(100),
+				},
+				"error": nil,
+			},
+		},
+		{
+			Name: "Cross Currency Test",
+			Body: validBody,
+			Mock: func(s *MockService) {
+				s.On("Transfer", mock.Anything, fromID, toID, int64(100), "").Return(convertedTransfer, nil)
+			},
+			ExpectedStatus: http.StatusOK,
+			ExpectedBody: gin.H{
+				"status": float64(200),
+				"data": map[string]interface{}{
+					"transferId":     convertedTransfer.ID.String(),
+					"fromWalletId":   fromID.String(),
+					"toWalletId":     toID.String(),
+					"amount":         float64(100),
+					"creditedAmount": float64(151),
+					"exchangeRate":   rate,
+					"fromBalance":    float64(0),
+					"toBalance":      float64(151),
+				},
+				"error": nil,
+			},
+		},
+		{
+			Name: "Exchange Rate Not Found Test",
+			Body: validBody,
+			Mock: func(s *MockService) {
+				s.On("Transfer", mock.Anything, fromID, toID, int64(100), "").Return((*wallet.Transfer)(nil), wallet.ErrExchangeRateNotFound)
+			},
+			ExpectedStatus: http.StatusBadRequest,
+			ExpectedCode:   responses.CodeExchangeRateNotFound,
+		},
+		{
+			Name:           "Wrong Input Test",
+			Body:           fmt.Sprintf(`{"fromWalletId":"%s","amount":100}`, fromID),
+			Mock:           func(s *MockService) {},
+			ExpectedStatus: http.StatusBadRequest,
+			ExpectedCode:   responses.CodeInvalidRequest,
+		},
+		{
+			Name: "Insufficient Funds Test",
+			Body: validBody,
+			Mock: func(s *MockService) {
+				s.On("Transfer", mock.Anything, fromID, toID, int64(100), "").Return((*wallet.Transfer)(nil), customerror.ErrWrongAmount)
+			},
+			ExpectedStatus: http.StatusBadRequest,
+			ExpectedCode:   responses.CodeInsufficientFunds,
+		},
+		{
+			Name: "Invalid Amount Test",
+			Body: fmt.Sprintf(`{"fromWalletId":"%s","toWalletId":"%s","amount":0}`, fromID, toID),
+			Mock: func(s *MockService) {
+				s.On("Transfer", mock.Anything, fromID, toID, int64(0), "").Return((*wallet.Transfer)(nil), customerror.ErrInvalidAmount)
+			},
+			ExpectedStatus: http.StatusBadRequest,
+			ExpectedCode:   responses.CodeInvalidAmount,
+		},
+		{
+			Name: "Same Wallet Test",
+			Body: fmt.Sprintf(`{"fromWalletId":"%s","toWalletId":"%s","amount":100}`, fromID, fromID),
+			Mock: func(s *MockService) {
+				s.On("Transfer", mock.Anything, fromID, fromID, int64(100), "").Return((*wallet.Transfer)(nil), customerror.ErrSameWallet)
+			},
+			ExpectedStatus: http.StatusBadRequest,
+			ExpectedCode:   responses.CodeSameWallet,
+		},
+		{
+			Name: "Currency Mismatch Test",
+			Body: fmt.Sprintf(`{"fromWalletId":"%s","toWalletId":"%s","amount":100,"currency":"EUR"}`, fromID, toID),
+			Mock: func(s *MockService) {
+				s.On("Transfer", mock.Anything, fromID, toID, int64(100), "EUR").Return((*wallet.Transfer)(nil), wallet.ErrCurrencyMismatch)
+			},
+			ExpectedStatus: http.StatusBadRequest,
+			ExpectedCode:   responses.CodeCurrencyMismatch,
+		},
+		{
+			Name: "Not Found Test",
+			Body: validBody,
+			Mock: func(s *MockService) {
+				s.On("Transfer", mock.Anything, fromID, toID, int64(100), "").Return((*wallet.Transfer)(nil), wallet.ErrNotFound)
+			},
+			ExpectedStatus: http.StatusNotFound,
+			ExpectedCode:   responses.CodeWalletNotFound,
+		},
+		{
+			Name: "Internal Server Error Test",
+			Body: validBody,
+			Mock: func(s *MockService) {
+				s.On("Transfer", mock.Anything, fromID, toID, int64(100), "").Return((*wallet.Transfer)(nil), customerror.NewError("", "", "error"))
+			},
+			ExpectedStatus: http.StatusInternalServerError,
+			ExpectedCode:   responses.CodeInternal,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			mockService := new(MockService)
+			test.Mock(mockService)
+
+			handler := handlers.NewWalletHandler(mockService, ratelimit.NewNop(), false, slog.New(slog.DiscardHandler), noop.NewTracerProvider())
+
+			router := gin.Default()
+			if test.Principal != nil {
+				router.Use(func(ctx *gin.Context) {
+					ctx.Request = ctx.Request.WithContext(auth.NewContext(ctx.Request.Context(), test.Principal))
+				})
+			}
+			router.POST("/transfers", handler.Transfer)
+
+			req, _ := http.NewRequest(http.MethodPost, "/transfers", bytes.NewBufferString(test.Body))
+			req.Header.Set("Content-Type", "application/json")
+			resp := httptest.NewRecorder()
+
+			router.ServeHTTP(resp, req)
+
+			assert.Equal(t, test.ExpectedStatus, resp.Code)
+
+			var body gin.H
+			err := json.Unmarshal(resp.Body.Bytes(), &body)
+			assert.NoError(t, err)
+			if test.ExpectedCode != "" {
+				assert.Equal(t, responses.ProblemContentType, resp.Header().Get("Content-Type"))
+				assert.Equal(t, test.ExpectedCode, body["code"])
+				assert.Equal(t, float64(test.ExpectedStatus), body["status"])
+			} else {
+				assert.Equal(t, test.ExpectedBody, body)
+			}
+
+			mockService.AssertExpectations(t)
+		})
+	}
+}
+
+type LegacyStatusTest struct {
+	Name           string
+	LegacyStatus   bool
+	ExpectedStatus int
+}
+
+func TestWalletHandler_LegacyStatus(t *testing.T) {
+	testID := uuid.New()
+
+	tests := []LegacyStatusTest{
+		{
+			Name:           "Real Status Test",
+			LegacyStatus:   false,
+			ExpectedStatus: http.StatusNotFound,
+		},
+		{
+			Name:           "Legacy Envelope Test",
+			LegacyStatus:   true,
+			ExpectedStatus: http.StatusOK,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			mockService := new(MockService)
+			mockService.On("GetBalance", mock.Anything, testID).Return((*wallet.Wallet)(nil), wallet.ErrNotFound)
+
+			handler := handlers.NewWalletHandler(mockService, ratelimit.NewNop(), test.LegacyStatus, slog.New(slog.DiscardHandler), noop.NewTracerProvider())
+
+			router := gin.Default()
+			router.GET("/wallets/:id", handler.GetBalance)
+
+			req, _ := http.NewRequest(http.MethodGet, "/wallets/"+testID.String(), nil)
+			resp := httptest.NewRecorder()
+
+			router.ServeHTTP(resp, req)
+
+			assert.Equal(t, test.ExpectedStatus, resp.Code)
+
+			var body gin.H
+			err := json.Unmarshal(resp.Body.Bytes(), &body)
+			assert.NoError(t, err)
+			assert.Equal(t, float64(http.StatusNotFound), body["status"])
+			if test.LegacyStatus {
+				assert.Equal(t, "Wallet not found", body["error"])
+				assert.Equal(t, map[string]interface{}{}, body["data"])
+			} else {
+				assert.Equal(t, responses.CodeWalletNotFound, body["code"])
+				assert.Equal(t, "/wallets/"+testID.String(), body["instance"])
+			}
+
+			mockService.AssertExpectations(t)
+		})
+	}
+}
+
+func TestWalletHandler_LegacyBody(t *testing.T) {
+	testID := uuid.New()
+	testTransaction := &wallet.Transaction{ID: uuid.New(), WalletID: testID, OperationType: "DEPOSIT", Amount: 100, Balance: 100}
+
+	mockService := new(MockService)
+	mockService.On("UpdateBalance", mock.Anything, testID, "DEPOSIT", int64(100), "", "").Return(testTransaction, nil).Once()
+	mockService.On("UpdateBalance", mock.Anything, testID, "WITHDRAW", int64(100), "", "").Return((*wallet.Transaction)(nil), customerror.ErrWrongAmount).Once()
+
+	handler := handlers.NewWalletHandler(mockService, ratelimit.NewNop(), true, slog.New(slog.DiscardHandler), noop.NewTracerProvider())
+
+	router := gin.Default()
+	router.POST("/wallet", handlers.LegacyBody("/wallet"), handler.UpdateBalance)
+
+	post := func(operationType string) gin.H {
+		requestBody, _ := json.Marshal(requests.UpdateBalanceRequest{WalletId: testID, OperationType: operationType, Amount: "100"})
+		req, _ := http.NewRequest(http.MethodPost, "/wallet", bytes.NewBuffer(requestBody))
+		req.Header.Set("Content-Type", "application/json")
+		resp := httptest.NewRecorder()
+		router.ServeHTTP(resp, req)
+		assert.Equal(t, http.StatusOK, resp.Code)
+
+		var body gin.H
+		err := json.Unmarshal(resp.Body.Bytes(), &body)
+		assert.NoError(t, err)
+		return body
+	}
+
+	assert.Equal(t, gin.H{
+		"status": float64(200),
+		"body": map[string]interface{}{
+			"transactionId": testTransaction.ID.String(),
+			"balance":       float64(100),
+		},
+		"error": nil,
+	}, post("DEPOSIT"))
+	assert.Equal(t, gin.H{
+		"status": float64(400),
+		"body":   map[string]interface{}{},
+		"error":  "Amount cant be less than zero",
+	}, post("WITHDRAW"))
+
+	mockService.AssertExpectations(t)
+}
+
+type AmountFormatTest struct {
+	Name           string
+	Method         string
+	Path           string
+	Body           string
+	AmountFormat   string
+	Mock           func(*MockService)
+	ExpectedStatus int
+	ExpectedData   map[string]interface{}
+	ExpectedCode   string
+}
+
+func TestWalletHandler_AmountFormat(t *testing.T) {
+	fromID := uuid.New()
+	toID := uuid.New()
+	usd := wallet.Currency{Code: "USD", MinorUnits: 2}
+	jpy := wallet.Currency{Code: "JPY", MinorUnits: 0}
+	usdWallet := &wallet.Wallet{ID: fromID, Amount: 123456, Currency: usd}
+	rate := "151.2345"
+
+	tests := []AmountFormatTest{
+		{
+			Name:         "Create Wallet Test",
+			Method:       http.MethodPost,
+			Path:         "/wallets",
+			Body:         fmt.Sprintf(`{"walletId":"%s","amount":"1500","currency":"JPY"}`, toID),
+			AmountFormat: "decimal",
+			Mock: func(s *MockService) {
+				s.On("GetCurrency", mock.Anything, "JPY").Return(&jpy, nil)
+				s.On("CreateWallet", mock.Anything, toID, int64(1500), "JPY").Return(&wallet.Wallet{ID: toID, Amount: 1500, Currency: jpy}, nil)
+			},
+			ExpectedStatus: http.StatusCreated,
+			ExpectedData:   map[string]interface{}{"id": toID.String(), "balance": "1500", "currency": "JPY", "minorUnits": float64(0)},
+		},
+		{
+			Name:           "Get Balance Test",
+			Method:         http.MethodGet,
+			Path:           "/wallets/" + fromID.String(),
+			AmountFormat:   "DECIMAL",
+			Mock:           func(s *MockService) { s.On("GetBalance", mock.Anything, fromID).Return(usdWallet, nil) },
+			ExpectedStatus: http.StatusOK,
+			ExpectedData:   map[string]interface{}{"balance": "1234.56", "available": "1234.56", "currency": "USD", "minorUnits": float64(2)},
+		},
+		{
+			Name:         "Update Balance Test",
+			Method:       http.MethodPost,
+			Path:         "/wallet",
+			Body:         fmt.Sprintf(`{"valletId":"%s","operationType":"DEPOSIT","amount":"12.3"}`, fromID),
+			AmountFormat: "decimal",
+			Mock: func(s *MockService) {
+				s.On("GetBalance", mock.Anything, fromID).Return(usdWallet, nil)
+				s.On("UpdateBalance", mock.Anything, fromID, "DEPOSIT", int64(1230), "", "").Return(&wallet.Transaction{ID: fromID, Balance: 124686}, nil)
+			},
+			ExpectedStatus: http.StatusOK,
+			ExpectedData:   map[string]interface{}{"transactionId": fromID.String(), "balance": "1246.86"},
+		},
+		{
+			Name:         "Too Many Decimals Test",
+			Method:       http.MethodPost,
+			Path:         "/wallet",
+			Body:         fmt.Sprintf(`{"valletId":"%s","operationType":"DEPOSIT","amount":"12.345"}`, fromID),
+			AmountFormat: "decimal",
+			Mock: func(s *MockService) {
+				s.On("GetBalance", mock.Anything, fromID).Return(usdWallet, nil)
+			},
+			ExpectedStatus: http.StatusBadRequest,
+			ExpectedCode:   responses.CodeInvalidAmount,
+		},
+		{
+			Name:         "Decimal Overflow Test",
+			Method:       http.MethodPost,
+			Path:         "/wallet",
+			Body:         fmt.Sprintf(`{"valletId":"%s","operationType":"DEPOSIT","amount":"100000000000000000"}`, fromID),
+			AmountFormat: "decimal",
+			Mock: func(s *MockService) {
+				s.On("GetBalance", mock.Anything, fromID).Return(usdWallet, nil)
+			},
+			ExpectedStatus: http.StatusBadRequest,
+			ExpectedCode:   responses.CodeAmountOutOfRange,
+		},
+		{
+			Name:           "Minor Units Overflow Test",
+			Method:         http.MethodPost,
+			Path:           "/wallet",
+			Body:           fmt.Sprintf(`{"valletId":"%s","operationType":"DEPOSIT","amount":9223372036854775808}`, fromID),
+			Mock:           func(s *MockService) {},
+			ExpectedStatus: http.StatusBadRequest,
+			ExpectedCode:   responses.CodeAmountOutOfRange,
+		},
+		{
+			Name:           "Fractional Minor Units Test",
+			Method:         http.MethodPost,
+			Path:           "/wallet",
+			Body:           fmt.Sprintf(`{"valletId":"%s","operationType":"DEPOSIT","amount":12.5}`, fromID),
+			Mock:           func(s *MockService) {},
+			ExpectedStatus: http.StatusBadRequest,
+			ExpectedCode:   responses.CodeInvalidAmount,
+		},
+		{
+			Name:   "Balance Out Of Range Test",
+			Method: http.MethodPost,
+			Path:   "/wallet",
+			Body:   fmt.Sprintf(`{"valletId":"%s","operationType":"DEPOSIT","amount":100}`, fromID),
+			Mock: func(s *MockService) {
+				s.On("UpdateBalance", mock.Anything, fromID, "DEPOSIT", int64(100), "", "").Return((*wallet.Transaction)(nil), customerror.ErrAmountOutOfRange)
+			},
+			ExpectedStatus: http.StatusBadRequest,
+			ExpectedCode:   responses.CodeAmountOutOfRange,
+		},
+		{
+			Name:         "Transfer Test",
+			Method:       http.MethodPost,
+			Path:         "/transfers",
+			Body:         fmt.Sprintf(`{"fromWalletId":"%s","toWalletId":"%s","amount":"10"}`, fromID, toID),
+			AmountFormat: "decimal",
+			Mock: func(s *MockService) {
+				s.On("GetBalance", mock.Anything, fromID).Return(usdWallet, nil)
+				s.On("Transfer", mock.Anything, fromID, toID, int64(1000), "").Return(&wallet.Transfer{
+					ID:           fromID,
+					Debit:        wallet.Transaction{WalletID: fromID, Amount: -1000, Balance: 122456, ExchangeRate: &rate},
+					Credit:       wallet.Transaction{WalletID: toID, Amount: 1512, Balance: 1512, ExchangeRate: &rate},
+					FromCurrency: usd,
+					ToCurrency:   jpy,
+				}, nil)
+			},
+			ExpectedStatus: http.StatusOK,
+			ExpectedData: map[string]interface{}{
+				"transferId":     fromID.String(),
+				"fromWalletId":   fromID.String(),
+				"toWalletId":     toID.String(),
+				"amount":         "10.00",
+				"creditedAmount": "1512",
+				"exchangeRate":   rate,
+				"fromBalance":    "1224.56",
+				"toBalance":      "1512",
+			},
+		},
+		{
+			Name:         "Transfer From Missing Wallet Test",
+			Method:       http.MethodPost,
+			Path:         "/transfers",
+			Body:         fmt.Sprintf(`{"fromWalletId":"%s","toWalletId":"%s","amount":"10"}`, fromID, toID),
+			AmountFormat: "decimal",
+			Mock: func(s *MockService) {
+				s.On("GetBalance", mock.Anything, fromID).Return((*wallet.Wallet)(nil), wallet.ErrNotFound)
+			},
+			ExpectedStatus: http.StatusNotFound,
+			ExpectedCode:   responses.CodeWalletNotFound,
+		},
+		{
+			Name:           "Unknown Format Test",
+			Method:         http.MethodGet,
+			Path:           "/wallets/" + fromID.String(),
+			AmountFormat:   "float",
+			Mock:           func(s *MockService) {},
+			ExpectedStatus: http.StatusBadRequest,
+			ExpectedCode:   responses.CodeInvalidAmountFormat,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			mockService := new(MockService)
+			test.Mock(mockService)
+			handler := handlers.NewWalletHandler(mockService, ratelimit.NewNop(), false, slog.New(slog.DiscardHandler), noop.NewTracerProvider())
+
+			router := gin.Default()
+			handler.RegisterRoutes(&router.RouterGroup)
+
+			req, _ := http.NewRequest(test.Method, test.Path, bytes.NewBufferString(test.Body))
+			req.Header.Set("Content-Type", "application/json")
+			if test.AmountFormat != "" {
+				req.Header.Set(handlers.AmountFormatHeader, test.AmountFormat)
+			}
+			resp := httptest.NewRecorder()
+
+			router.ServeHTTP(resp, req)
+
+			assert.Equal(t, test.ExpectedStatus, resp.Code)
+			var body gin.H
+			err := json.Unmarshal(resp.Body.Bytes(), &body)
+			assert.NoError(t, err)
+			if test.ExpectedCode != "" {
+				assert.Equal(t, test.ExpectedCode, body["code"])
+			} else {
+				assert.Equal(t, test.ExpectedData, body["data"])
+			}
+			mockService.AssertExpectations(t)
+		})
+	}
+}
+
+func TestWalletHandler_RequestContext(t *testing.T) {
+	testID := uuid.New()
+	type ctxKey struct{}
+
+	mockService := new(MockService)
+	mockService.On("GetBalance", mock.MatchedBy(func(ctx context.Context) bool {
+		return ctx.Value(ctxKey{}) == "request"
+	}), testID).Return(&wallet.Wallet{ID: testID, Amount: 100, Currency: wallet.Currency{Code: "USD", MinorUnits: 2}}, nil)
+
+	handler := handlers.NewWalletHandler(mockService, ratelimit.NewNop(), false, slog.New(slog.DiscardHandler), noop.NewTracerProvider())
+
+	router := gin.Default()
+	router.GET("/wallets/:id", handler.GetBalance)
+
+	req, _ := http.NewRequestWithContext(context.WithValue(context.Background(), ctxKey{}, "request"), http.MethodGet, "/wallets/"+testID.String(), nil)
+	resp := httptest.NewRecorder()
+
+	router.ServeHTTP(resp, req)
+
+	assert.Equal(t, http.StatusOK, resp.Code)
+	mockService.AssertExpectations(t)
+}
+
+func TestWalletHandler_RequestID(t *testing.T) {
+	testID := uuid.New()
+
+	mockService := new(MockService)
+	mockService.On("GetBalance", mock.Anything, testID).Return((*wallet.Wallet)(nil), wallet.ErrNotFound)
+
+	handler := handlers.NewWalletHandler(mockService, ratelimit.NewNop(), false, slog.New(slog.DiscardHandler), noop.NewTracerProvider())
+
+	router := gin.Default()
+	router.Use(requestid.Middleware())
+	router.GET("/wallets/:id", handler.GetBalance)
+
+	req, _ := http.NewRequest(http.MethodGet, "/wallets/"+testID.String(), nil)
+	req.Header.Set(requestid.Header, "support-ticket-7")
+	resp := httptest.NewRecorder()
+
+	router.ServeHTTP(resp, req)
+
+	var problem responses.Problem
+	err := json.Unmarshal(resp.Body.Bytes(), &problem)
+	assert.NoError(t, err)
+	assert.Equal(t, "support-ticket-7", problem.RequestID)
+	assert.Equal(t, "support-ticket-7", resp.Header().Get(requestid.Header))
+	mockService.AssertExpectations(t)
+}
+
+func TestWalletHandler_Tracing(t *testing.T) {
+	testID := uuid.New()
+	spanRecorder := tracetest.NewSpanRecorder()
+	tracerProvider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(spanRecorder))
+
+	var serviceSpan trace.SpanContext
+	mockService := new(MockService)
+	mockService.On("GetBalance", mock.Anything, testID).Run(func(args mock.Arguments) {
+		serviceSpan = trace.SpanContextFromContext(args.Get(0).(context.Context))
+	}).Return((*wallet.Wallet)(nil), customerror.NewError("walletRepo.GetWallet", "127.0.0.1:8080", "connection reset"))
+
+	handler := handlers.NewWalletHandler(mockService, ratelimit.NewNop(), false, slog.New(slog.DiscardHandler), tracerProvider)
+
+	router := gin.Default()
+	router.GET("/wallets/:id", handler.GetBalance)
+
+	req, _ := http.NewRequest(http.MethodGet, "/wallets/"+testID.String(), nil)
+	resp := httptest.NewRecorder()
+
+	router.ServeHTTP(resp, req)
+
+	assert.Equal(t, http.StatusInternalServerError, resp.Code)
+	spans := spanRecorder.Ended()
+	assert.Len(t, spans, 1)
+	assert.Equal(t, "WalletHandler.GetBalance", spans[0].Name())
+	assert.Equal(t, spans[0].SpanContext(), serviceSpan)
+	assert.Contains(t, spans[0].Attributes(), tracing.WalletIDKey.String(testID.String()))
+	assert.Equal(t, codes.Error, spans[0].Status().Code)
+	mockService.AssertExpectations(t)
+}
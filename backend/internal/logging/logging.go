@@ -0,0 +1,84 @@
+package logging
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	FormatJSON = "json"
+	FormatText = "text"
+)
+
+// New builds the application logger. Every record also carries the attributes
+// stored in its context with WithAttrs.
+func New(w io.Writer, level slog.Level, format string) *slog.Logger {
+	options := &slog.HandlerOptions{Level: level}
+	var handler slog.Handler
+	if format == FormatText {
+		handler = slog.NewTextHandler(w, options)
+	} else {
+		handler = slog.NewJSONHandler(w, options)
+	}
+	return slog.New(ContextHandler{Handler: handler})
+}
+
+type attrsKey struct{}
+
+// WithAttrs returns a context whose log records include attrs in addition to
+// the attributes already stored in ctx.
+func WithAttrs(ctx context.Context, attrs ...slog.Attr) context.Context {
+	existing, _ := ctx.Value(attrsKey{}).([]slog.Attr)
+	merged := make([]slog.Attr, 0, len(existing)+len(attrs))
+	merged = append(merged, existing...)
+	merged = append(merged, attrs...)
+	return context.WithValue(ctx, attrsKey{}, merged)
+}
+
+// ContextHandler adds the attributes stored with WithAttrs to every record
+// logged with a context, such as the request and wallet ids.
+type ContextHandler struct {
+	slog.Handler
+}
+
+func (handler ContextHandler) Handle(ctx context.Context, record slog.Record) error {
+	if attrs, ok := ctx.Value(attrsKey{}).([]slog.Attr); ok {
+		record.AddAttrs(attrs...)
+	}
+	return handler.Handler.Handle(ctx, record)
+}
+
+func (handler ContextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return ContextHandler{Handler: handler.Handler.WithAttrs(attrs)}
+}
+
+func (handler ContextHandler) WithGroup(name string) slog.Handler {
+	return ContextHandler{Handler: handler.Handler.WithGroup(name)}
+}
+
+// Middleware replaces gin's text logger and writes one access record per
+// request once it is answered. It runs after requestid.Middleware, so the
+// record carries the request id.
+func Middleware(logger *slog.Logger) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		start := time.Now()
+		ctx.Next()
+
+		level := slog.LevelInfo
+		if ctx.Writer.Status() >= 500 {
+			level = slog.LevelError
+		}
+		logger.LogAttrs(ctx.Request.Context(), level, "request",
+			slog.String("method", ctx.Request.Method),
+			slog.String("path", ctx.Request.URL.Path),
+			slog.String("route", ctx.FullPath()),
+			slog.Int("status", ctx.Writer.Status()),
+			slog.Duration("latency", time.Since(start)),
+			slog.String("client_ip", ctx.ClientIP()),
+		)
+	}
+}
@@ -0,0 +1,61 @@
+package logging_test
+
+import (
+	"backend/internal/logging"
+	"backend/pkg/customerror"
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNew_ContextAttrs(t *testing.T) {
+	var buffer bytes.Buffer
+	logger := logging.New(&buffer, slog.LevelInfo, logging.FormatJSON)
+
+	ctx := logging.WithAttrs(context.Background(), slog.String("request_id", "req-1"))
+	ctx = logging.WithAttrs(ctx, slog.String("wallet_id", "wallet-1"))
+	logger.ErrorContext(ctx, "request failed", slog.Any("error", customerror.NewError("walletRepo.GetWallet", "127.0.0.1:80", "boom")))
+	logger.DebugContext(ctx, "dropped below level")
+
+	var record map[string]interface{}
+	err := json.Unmarshal(buffer.Bytes(), &record)
+	assert.NoError(t, err)
+	assert.Equal(t, "req-1", record["request_id"])
+	assert.Equal(t, "wallet-1", record["wallet_id"])
+	assert.Equal(t, map[string]interface{}{
+		"module":   "walletRepo.GetWallet",
+		"endpoint": "127.0.0.1:80",
+		"message":  "boom",
+		"kind":     "internal error",
+	}, record["error"])
+}
+
+func TestMiddleware(t *testing.T) {
+	var buffer bytes.Buffer
+	logger := logging.New(&buffer, slog.LevelInfo, logging.FormatJSON)
+
+	router := gin.New()
+	router.Use(logging.Middleware(logger))
+	router.GET("/wallets/:id", func(ctx *gin.Context) {
+		ctx.Request = ctx.Request.WithContext(logging.WithAttrs(ctx.Request.Context(), slog.String("wallet_id", ctx.Param("id"))))
+		ctx.Status(http.StatusNotFound)
+	})
+
+	req, _ := http.NewRequest(http.MethodGet, "/wallets/abc", nil)
+	router.ServeHTTP(httptest.NewRecorder(), req)
+
+	var record map[string]interface{}
+	err := json.Unmarshal(buffer.Bytes(), &record)
+	assert.NoError(t, err)
+	assert.Equal(t, "request", record["msg"])
+	assert.Equal(t, "/wallets/:id", record["route"])
+	assert.Equal(t, float64(http.StatusNotFound), record["status"])
+	assert.Equal(t, "abc", record["wallet_id"])
+}
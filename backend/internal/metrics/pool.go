@@ -0,0 +1,66 @@
+package metrics
+
+import (
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// StatSource is satisfied by *pgxpool.Pool and repos.PoolInterface.
+type StatSource interface {
+	Stat() *pgxpool.Stat
+}
+
+// PoolCollector reads pool statistics on every scrape instead of keeping
+// gauges in sync, so the values are never stale.
+type PoolCollector struct {
+	Pool StatSource
+
+	acquiredConns    *prometheus.Desc
+	idleConns        *prometheus.Desc
+	totalConns       *prometheus.Desc
+	maxConns         *prometheus.Desc
+	acquireCount     *prometheus.Desc
+	emptyAcquire     *prometheus.Desc
+	acquireDuration  *prometheus.Desc
+	emptyAcquireWait *prometheus.Desc
+}
+
+func NewPoolCollector(pool StatSource) prometheus.Collector {
+	desc := func(name string, help string) *prometheus.Desc {
+		return prometheus.NewDesc(prometheus.BuildFQName(namespace, "pool", name), help, nil, nil)
+	}
+	return &PoolCollector{
+		Pool:             pool,
+		acquiredConns:    desc("acquired_connections", "Connections currently in use."),
+		idleConns:        desc("idle_connections", "Connections currently idle."),
+		totalConns:       desc("total_connections", "Connections currently open."),
+		maxConns:         desc("max_connections", "Maximum size of the pool."),
+		acquireCount:     desc("acquires_total", "Successful connection acquires."),
+		emptyAcquire:     desc("empty_acquires_total", "Acquires that had to wait for a connection."),
+		acquireDuration:  desc("acquire_duration_seconds_total", "Time spent acquiring connections."),
+		emptyAcquireWait: desc("empty_acquire_wait_seconds_total", "Time spent waiting for a connection on an empty pool."),
+	}
+}
+
+func (collector *PoolCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- collector.acquiredConns
+	ch <- collector.idleConns
+	ch <- collector.totalConns
+	ch <- collector.maxConns
+	ch <- collector.acquireCount
+	ch <- collector.emptyAcquire
+	ch <- collector.acquireDuration
+	ch <- collector.emptyAcquireWait
+}
+
+func (collector *PoolCollector) Collect(ch chan<- prometheus.Metric) {
+	stat := collector.Pool.Stat()
+	ch <- prometheus.MustNewConstMetric(collector.acquiredConns, prometheus.GaugeValue, float64(stat.AcquiredConns()))
+	ch <- prometheus.MustNewConstMetric(collector.idleConns, prometheus.GaugeValue, float64(stat.IdleConns()))
+	ch <- prometheus.MustNewConstMetric(collector.totalConns, prometheus.GaugeValue, float64(stat.TotalConns()))
+	ch <- prometheus.MustNewConstMetric(collector.maxConns, prometheus.GaugeValue, float64(stat.MaxConns()))
+	ch <- prometheus.MustNewConstMetric(collector.acquireCount, prometheus.CounterValue, float64(stat.AcquireCount()))
+	ch <- prometheus.MustNewConstMetric(collector.emptyAcquire, prometheus.CounterValue, float64(stat.EmptyAcquireCount()))
+	ch <- prometheus.MustNewConstMetric(collector.acquireDuration, prometheus.CounterValue, stat.AcquireDuration().Seconds())
+	ch <- prometheus.MustNewConstMetric(collector.emptyAcquireWait, prometheus.CounterValue, stat.EmptyAcquireWaitTime().Seconds())
+}
@@ -0,0 +1,256 @@
+package migrations
+
+import (
+	"backend/pkg/customerror"
+	"context"
+	"embed"
+	"io/fs"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+//go:embed sql/*.sql
+var embedded embed.FS
+
+// lockID is the key of the advisory lock held while migrating, "wallet" in
+// ASCII. Every replica uses the same key, so only one migrates at a time.
+const lockID int64 = 0x77616c6c6574
+
+// fileName matches migration files such as 0001_create_wallet.up.sql.
+var fileName = regexp.MustCompile(`^(\d+)_(\w+)\.(up|down)\.sql$`)
+
+type Migration struct {
+	Version int64
+	Name    string
+	Up      string
+	Down    string
+}
+
+type MigrationStatus struct {
+	Migration
+	AppliedAt *time.Time
+}
+
+type MigratorI interface {
+	Up(ctx context.Context) ([]Migration, error)
+	Down(ctx context.Context) (*Migration, error)
+	Status(ctx context.Context) ([]MigrationStatus, error)
+}
+
+type PoolInterface interface {
+	Begin(ctx context.Context) (pgx.Tx, error)
+}
+
+// Migrator applies migrations in version order. Each command runs in a single
+// transaction under the advisory lock, so a failed migration leaves the schema
+// untouched and statements that cannot run in a transaction are not allowed.
+type Migrator struct {
+	Pool       PoolInterface
+	Migrations []Migration
+}
+
+func NewMigrator(pool PoolInterface) (MigratorI, error) {
+	files, err := fs.Sub(embedded, "sql")
+	if err != nil {
+		return &Migrator{}, customerror.Wrap(err, "NewMigrator", "")
+	}
+	migrations, err := Load(files)
+	if err != nil {
+		return &Migrator{}, customerror.AppendModule(err, "NewMigrator")
+	}
+	return &Migrator{
+		Pool:       pool,
+		Migrations: migrations,
+	}, nil
+}
+
+// Load reads numbered up/down pairs from the root of fsys and returns them
+// sorted by version. Every version must have both files.
+func Load(fsys fs.FS) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, customerror.Wrap(err, "migrations.Load", "")
+	}
+	byVersion := make(map[int64]*Migration)
+	for _, entry := range entries {
+		match := fileName.FindStringSubmatch(entry.Name())
+		if match == nil {
+			return nil, customerror.NewError("migrations.Load", entry.Name(), "wrong migration file name")
+		}
+		version, err := strconv.ParseInt(match[1], 10, 64)
+		if err != nil || version <= 0 {
+			return nil, customerror.NewError("migrations.Load", entry.Name(), "wrong migration version")
+		}
+		content, err := fs.ReadFile(fsys, entry.Name())
+		if err != nil {
+			return nil, customerror.Wrap(err, "migrations.Load", entry.Name())
+		}
+		migration, ok := byVersion[version]
+		if !ok {
+			migration = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = migration
+		}
+		if migration.Name != match[2] {
+			return nil, customerror.NewError("migrations.Load", entry.Name(), "duplicate migration version")
+		}
+		if match[3] == "up" {
+			migration.Up = string(content)
+		} else {
+			migration.Down = string(content)
+		}
+	}
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, migration := range byVersion {
+		if migration.Up == "" || migration.Down == "" {
+			return nil, customerror.NewError("migrations.Load", migration.label(), "missing up or down migration")
+		}
+		migrations = append(migrations, *migration)
+	}
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+	return migrations, nil
+}
+
+// label identifies the migration in errors, e.g. 1_create_wallet.
+func (migration Migration) label() string {
+	return strconv.FormatInt(migration.Version, 10) + "_" + migration.Name
+}
+
+// Up applies every migration that has not been applied yet and returns them.
+func (migrator *Migrator) Up(ctx context.Context) ([]Migration, error) {
+	var applied []Migration
+	err := migrator.withLock(ctx, func(tx pgx.Tx, appliedAt map[int64]time.Time) error {
+		for _, migration := range migrator.Migrations {
+			if _, ok := appliedAt[migration.Version]; ok {
+				continue
+			}
+			_, err := tx.Exec(ctx, migration.Up)
+			if err != nil {
+				return customerror.Wrap(err, "migrator.Up", migration.label())
+			}
+			insertQuery := "INSERT INTO schema_migrations (version, name) VALUES ($1, $2)"
+			_, err = tx.Exec(ctx, insertQuery, migration.Version, migration.Name)
+			if err != nil {
+				return customerror.Wrap(err, "migrator.Up", migration.label())
+			}
+			applied = append(applied, migration)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return applied, nil
+}
+
+// Down reverts the latest applied migration and returns it, or nil when no
+// migration is applied.
+func (migrator *Migrator) Down(ctx context.Context) (*Migration, error) {
+	var reverted *Migration
+	err := migrator.withLock(ctx, func(tx pgx.Tx, appliedAt map[int64]time.Time) error {
+		for i := len(migrator.Migrations) - 1; i >= 0; i-- {
+			migration := migrator.Migrations[i]
+			if _, ok := appliedAt[migration.Version]; !ok {
+				continue
+			}
+			_, err := tx.Exec(ctx, migration.Down)
+			if err != nil {
+				return customerror.Wrap(err, "migrator.Down", migration.label())
+			}
+			deleteQuery := "DELETE FROM schema_migrations WHERE version = $1"
+			_, err = tx.Exec(ctx, deleteQuery, migration.Version)
+			if err != nil {
+				return customerror.Wrap(err, "migrator.Down", migration.label())
+			}
+			reverted = &migration
+			return nil
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return reverted, nil
+}
+
+// Status lists every known migration with the time it was applied, if any.
+func (migrator *Migrator) Status(ctx context.Context) ([]MigrationStatus, error) {
+	statuses := make([]MigrationStatus, 0, len(migrator.Migrations))
+	err := migrator.withLock(ctx, func(tx pgx.Tx, appliedAt map[int64]time.Time) error {
+		for _, migration := range migrator.Migrations {
+			status := MigrationStatus{Migration: migration}
+			if at, ok := appliedAt[migration.Version]; ok {
+				status.AppliedAt = &at
+			}
+			statuses = append(statuses, status)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return statuses, nil
+}
+
+// withLock runs fn in a transaction holding the migration lock, passing it the
+// applied versions. The lock is released when the transaction ends.
+func (migrator *Migrator) withLock(ctx context.Context, fn func(tx pgx.Tx, appliedAt map[int64]time.Time) error) error {
+	tx, err := migrator.Pool.Begin(ctx)
+	if err != nil {
+		return customerror.Wrap(err, "migrator.withLock", "")
+	}
+	defer tx.Rollback(ctx)
+
+	_, err = tx.Exec(ctx, "SELECT pg_advisory_xact_lock($1)", lockID)
+	if err != nil {
+		return customerror.Wrap(err, "migrator.withLock", "")
+	}
+	createQuery := `
+	CREATE TABLE IF NOT EXISTS schema_migrations (
+		version BIGINT PRIMARY KEY,
+		name TEXT NOT NULL,
+		applied_at TIMESTAMPTZ NOT NULL DEFAULT now()
+	);`
+	_, err = tx.Exec(ctx, createQuery)
+	if err != nil {
+		return customerror.Wrap(err, "migrator.withLock", "")
+	}
+	appliedAt, err := appliedVersions(ctx, tx)
+	if err != nil {
+		return err
+	}
+	if err := fn(tx, appliedAt); err != nil {
+		return err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return customerror.Wrap(err, "migrator.withLock", "")
+	}
+	return nil
+}
+
+func appliedVersions(ctx context.Context, tx pgx.Tx) (map[int64]time.Time, error) {
+	rows, err := tx.Query(ctx, "SELECT version, applied_at FROM schema_migrations")
+	if err != nil {
+		return nil, customerror.Wrap(err, "migrator.appliedVersions", "")
+	}
+	defer rows.Close()
+
+	appliedAt := make(map[int64]time.Time)
+	for rows.Next() {
+		var version int64
+		var at time.Time
+		if err := rows.Scan(&version, &at); err != nil {
+			return nil, customerror.Wrap(err, "migrator.appliedVersions", "")
+		}
+		appliedAt[version] = at
+	}
+	if err := rows.Err(); err != nil {
+		return nil, customerror.Wrap(err, "migrator.appliedVersions", "")
+	}
+	return appliedAt, nil
+}
@@ -0,0 +1,319 @@
+package migrations_test
+
+import (
+	"backend/internal/migrations"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockPool struct {
+	mock.Mock
+}
+
+func (m *MockPool) Begin(ctx context.Context) (pgx.Tx, error) {
+	mockArgs := m.Called(ctx)
+	return mockArgs.Get(0).(pgx.Tx), mockArgs.Error(1)
+}
+
+type MockTx struct {
+	pgx.Tx
+	mock.Mock
+}
+
+func (m *MockTx) Exec(ctx context.Context, sql string, args ...interface{}) (pgconn.CommandTag, error) {
+	mockArgs := m.Called(ctx, sql, args)
+	return mockArgs.Get(0).(pgconn.CommandTag), mockArgs.Error(1)
+}
+
+func (m *MockTx) Query(ctx context.Context, sql string, args ...interface{}) (pgx.Rows, error) {
+	mockArgs := m.Called(ctx, sql, args)
+	return mockArgs.Get(0).(pgx.Rows), mockArgs.Error(1)
+}
+
+func (m *MockTx) Commit(ctx context.Context) error {
+	mockArgs := m.Called(ctx)
+	return mockArgs.Error(0)
+}
+
+func (m *MockTx) Rollback(ctx context.Context) error {
+	mockArgs := m.Called(ctx)
+	return mockArgs.Error(0)
+}
+
+type MockRows struct {
+	pgx.Rows
+	mock.Mock
+}
+
+func (m *MockRows) Next() bool {
+	args := m.Called()
+	return args.Bool(0)
+}
+
+func (m *MockRows) Scan(dest ...any) error {
+	args := m.Called(dest)
+	return args.Error(0)
+}
+
+func (m *MockRows) Err() error {
+	args := m.Called()
+	return args.Error(0)
+}
+
+func (m *MockRows) Close() {
+	m.Called()
+}
+
+var testMigrations = []migrations.Migration{
+	{Version: 1, Name: "create_wallet", Up: "CREATE TABLE wallet ();", Down: "DROP TABLE wallet;"},
+	{Version: 2, Name: "create_ledger", Up: "CREATE TABLE ledger ();", Down: "DROP TABLE ledger;"},
+}
+
+// expectLock sets up the statements every command runs before its own work
+// and makes schema_migrations report the given applied versions.
+func expectLock(mockTx *MockTx, mockRows *MockRows, applied ...int64) {
+	mockTx.On("Rollback", mock.Anything).Return(nil).Maybe()
+	mockTx.On("Exec", mock.Anything, "SELECT pg_advisory_xact_lock($1)", mock.Anything).Return(pgconn.CommandTag{}, nil).Once()
+	mockTx.On("Exec", mock.Anything, mock.MatchedBy(func(sql string) bool {
+		return strings.Contains(sql, "CREATE TABLE IF NOT EXISTS schema_migrations")
+	}), mock.Anything).Return(pgconn.CommandTag{}, nil).Once()
+	mockTx.On("Query", mock.Anything, "SELECT version, applied_at FROM schema_migrations", mock.Anything).Return(mockRows, nil).Once()
+	for _, version := range applied {
+		mockRows.On("Next").Return(true).Once()
+		mockRows.On("Scan", mock.Anything).Run(func(args mock.Arguments) {
+			dest := args.Get(0).([]any)
+			*dest[0].(*int64) = version
+			*dest[1].(*time.Time) = time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+		}).Return(nil).Once()
+	}
+	mockRows.On("Next").Return(false).Once()
+	mockRows.On("Err").Return(nil).Once()
+	mockRows.On("Close").Return().Once()
+}
+
+type LoadTest struct {
+	Name            string
+	Files           fstest.MapFS
+	WaitingVersions []int64
+	WantErr         bool
+}
+
+func TestLoad(t *testing.T) {
+	loadTests := []LoadTest{
+		{
+			Name: "Success Test",
+			Files: fstest.MapFS{
+				"0002_create_ledger.up.sql":   {Data: []byte("CREATE TABLE ledger ();")},
+				"0002_create_ledger.down.sql": {Data: []byte("DROP TABLE ledger;")},
+				"0001_create_wallet.up.sql":   {Data: []byte("CREATE TABLE wallet ();")},
+				"0001_create_wallet.down.sql": {Data: []byte("DROP TABLE wallet;")},
+			},
+			WaitingVersions: []int64{1, 2},
+		},
+		{
+			Name: "Missing down migration",
+			Files: fstest.MapFS{
+				"0001_create_wallet.up.sql": {Data: []byte("CREATE TABLE wallet ();")},
+			},
+			WantErr: true,
+		},
+		{
+			Name: "Wrong file name",
+			Files: fstest.MapFS{
+				"create_wallet.sql": {Data: []byte("CREATE TABLE wallet ();")},
+			},
+			WantErr: true,
+		},
+		{
+			Name: "Duplicate version",
+			Files: fstest.MapFS{
+				"0001_create_wallet.up.sql":   {Data: []byte("CREATE TABLE wallet ();")},
+				"0001_create_wallet.down.sql": {Data: []byte("DROP TABLE wallet;")},
+				"0001_create_ledger.up.sql":   {Data: []byte("CREATE TABLE ledger ();")},
+			},
+			WantErr: true,
+		},
+	}
+
+	for _, test := range loadTests {
+		t.Run(test.Name, func(t *testing.T) {
+			loaded, err := migrations.Load(test.Files)
+			if test.WantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			versions := make([]int64, 0, len(loaded))
+			for _, migration := range loaded {
+				versions = append(versions, migration.Version)
+			}
+			assert.Equal(t, test.WaitingVersions, versions)
+		})
+	}
+}
+
+func TestNewMigrator(t *testing.T) {
+	migrator, err := migrations.NewMigrator(new(MockPool))
+	assert.NoError(t, err)
+	assert.NotEmpty(t, migrator.(*migrations.Migrator).Migrations)
+}
+
+type UpTest struct {
+	Name            string
+	Applied         []int64
+	Mock            func(*MockTx)
+	WaitingVersions []int64
+	WantErr         bool
+}
+
+func TestMigrator_Up(t *testing.T) {
+	upTests := []UpTest{
+		{
+			Name:    "Applies pending migrations",
+			Applied: []int64{1},
+			Mock: func(m *MockTx) {
+				m.On("Exec", mock.Anything, "CREATE TABLE ledger ();", mock.Anything).Return(pgconn.CommandTag{}, nil).Once()
+				m.On("Exec", mock.Anything, "INSERT INTO schema_migrations (version, name) VALUES ($1, $2)", []interface{}{int64(2), "create_ledger"}).Return(pgconn.CommandTag{}, nil).Once()
+				m.On("Commit", mock.Anything).Return(nil).Once()
+			},
+			WaitingVersions: []int64{2},
+		},
+		{
+			Name:    "Up to date",
+			Applied: []int64{1, 2},
+			Mock: func(m *MockTx) {
+				m.On("Commit", mock.Anything).Return(nil).Once()
+			},
+		},
+		{
+			Name: "Failed migration is not committed",
+			Mock: func(m *MockTx) {
+				m.On("Exec", mock.Anything, "CREATE TABLE wallet ();", mock.Anything).Return(pgconn.CommandTag{}, errors.New("syntax error")).Once()
+			},
+			WantErr: true,
+		},
+	}
+
+	for _, test := range upTests {
+		t.Run(test.Name, func(t *testing.T) {
+			mockPool := new(MockPool)
+			mockTx := new(MockTx)
+			mockRows := new(MockRows)
+			mockPool.On("Begin", mock.Anything).Return(mockTx, nil).Once()
+			expectLock(mockTx, mockRows, test.Applied...)
+			test.Mock(mockTx)
+			migrator := &migrations.Migrator{
+				Pool:       mockPool,
+				Migrations: testMigrations,
+			}
+			applied, err := migrator.Up(context.Background())
+			if test.WantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+				versions := make([]int64, 0, len(applied))
+				for _, migration := range applied {
+					versions = append(versions, migration.Version)
+				}
+				assert.ElementsMatch(t, test.WaitingVersions, versions)
+			}
+			mockPool.AssertExpectations(t)
+			mockTx.AssertExpectations(t)
+			mockRows.AssertExpectations(t)
+		})
+	}
+}
+
+type DownTest struct {
+	Name           string
+	Applied        []int64
+	Mock           func(*MockTx)
+	WaitingVersion int64
+	WantErr        bool
+}
+
+func TestMigrator_Down(t *testing.T) {
+	downTests := []DownTest{
+		{
+			Name:    "Reverts latest migration",
+			Applied: []int64{1, 2},
+			Mock: func(m *MockTx) {
+				m.On("Exec", mock.Anything, "DROP TABLE ledger;", mock.Anything).Return(pgconn.CommandTag{}, nil).Once()
+				m.On("Exec", mock.Anything, "DELETE FROM schema_migrations WHERE version = $1", []interface{}{int64(2)}).Return(pgconn.CommandTag{}, nil).Once()
+				m.On("Commit", mock.Anything).Return(nil).Once()
+			},
+			WaitingVersion: 2,
+		},
+		{
+			Name: "Nothing applied",
+			Mock: func(m *MockTx) {
+				m.On("Commit", mock.Anything).Return(nil).Once()
+			},
+		},
+		{
+			Name:    "Failed revert is not committed",
+			Applied: []int64{1},
+			Mock: func(m *MockTx) {
+				m.On("Exec", mock.Anything, "DROP TABLE wallet;", mock.Anything).Return(pgconn.CommandTag{}, errors.New("dependent objects")).Once()
+			},
+			WantErr: true,
+		},
+	}
+
+	for _, test := range downTests {
+		t.Run(test.Name, func(t *testing.T) {
+			mockPool := new(MockPool)
+			mockTx := new(MockTx)
+			mockRows := new(MockRows)
+			mockPool.On("Begin", mock.Anything).Return(mockTx, nil).Once()
+			expectLock(mockTx, mockRows, test.Applied...)
+			test.Mock(mockTx)
+			migrator := &migrations.Migrator{
+				Pool:       mockPool,
+				Migrations: testMigrations,
+			}
+			reverted, err := migrator.Down(context.Background())
+			switch {
+			case test.WantErr:
+				assert.Error(t, err)
+			case test.WaitingVersion == 0:
+				assert.NoError(t, err)
+				assert.Nil(t, reverted)
+			default:
+				assert.NoError(t, err)
+				assert.Equal(t, test.WaitingVersion, reverted.Version)
+			}
+			mockPool.AssertExpectations(t)
+			mockTx.AssertExpectations(t)
+		})
+	}
+}
+
+func TestMigrator_Status(t *testing.T) {
+	mockPool := new(MockPool)
+	mockTx := new(MockTx)
+	mockRows := new(MockRows)
+	mockPool.On("Begin", mock.Anything).Return(mockTx, nil).Once()
+	expectLock(mockTx, mockRows, 1)
+	mockTx.On("Commit", mock.Anything).Return(nil).Once()
+	migrator := &migrations.Migrator{
+		Pool:       mockPool,
+		Migrations: testMigrations,
+	}
+
+	statuses, err := migrator.Status(context.Background())
+	assert.NoError(t, err)
+	assert.Len(t, statuses, 2)
+	assert.NotNil(t, statuses[0].AppliedAt)
+	assert.Nil(t, statuses[1].AppliedAt)
+	mockTx.AssertExpectations(t)
+}
@@ -0,0 +1,6 @@
+CREATE TABLE IF NOT EXISTS wallet (
+	id UUID PRIMARY KEY,
+	amount BIGINT NOT NULL DEFAULT 0 CHECK (amount >= 0)
+);
+
+CREATE INDEX IF NOT EXISTS wallet_id_idx ON wallet(id);
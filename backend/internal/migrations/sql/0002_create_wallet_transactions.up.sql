@@ -0,0 +1,10 @@
+CREATE TABLE IF NOT EXISTS wallet_transactions (
+	id UUID PRIMARY KEY,
+	wallet_id UUID NOT NULL REFERENCES wallet(id),
+	operation_type VARCHAR(16) NOT NULL,
+	amount BIGINT NOT NULL,
+	balance BIGINT NOT NULL,
+	created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS wallet_transactions_wallet_id_created_at_idx ON wallet_transactions(wallet_id, created_at, id);
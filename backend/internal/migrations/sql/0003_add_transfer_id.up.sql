@@ -0,0 +1 @@
+ALTER TABLE wallet_transactions ADD COLUMN IF NOT EXISTS transfer_id UUID;
@@ -0,0 +1,9 @@
+CREATE TABLE api_keys (
+	id UUID PRIMARY KEY,
+	name TEXT NOT NULL,
+	key_hash CHAR(64) NOT NULL UNIQUE,
+	scopes TEXT[] NOT NULL,
+	created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+	expires_at TIMESTAMPTZ,
+	revoked_at TIMESTAMPTZ
+);
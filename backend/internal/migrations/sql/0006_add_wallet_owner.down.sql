@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS wallet_owner_id_idx;
+ALTER TABLE wallet DROP COLUMN IF EXISTS owner_id;
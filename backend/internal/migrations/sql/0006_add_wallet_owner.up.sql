@@ -0,0 +1,2 @@
+ALTER TABLE wallet ADD COLUMN IF NOT EXISTS owner_id TEXT;
+CREATE INDEX IF NOT EXISTS wallet_owner_id_idx ON wallet (owner_id);
@@ -0,0 +1,2 @@
+ALTER TABLE wallet DROP COLUMN IF EXISTS currency;
+DROP TABLE IF EXISTS currencies;
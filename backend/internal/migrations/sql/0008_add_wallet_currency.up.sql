@@ -0,0 +1,13 @@
+CREATE TABLE currencies (
+	code CHAR(3) PRIMARY KEY,
+	minor_units SMALLINT NOT NULL CHECK (minor_units BETWEEN 0 AND 4)
+);
+
+INSERT INTO currencies (code, minor_units) VALUES
+	('USD', 2), ('EUR', 2), ('GBP', 2), ('CHF', 2), ('CNY', 2), ('RUB', 2),
+	('KZT', 2), ('TRY', 2), ('INR', 2), ('BRL', 2), ('AED', 2), ('JPY', 0),
+	('KRW', 0), ('BHD', 3), ('KWD', 3);
+
+-- Wallets created before currencies existed were implicitly in US dollars.
+ALTER TABLE wallet ADD COLUMN currency CHAR(3) NOT NULL DEFAULT 'USD' REFERENCES currencies(code);
+ALTER TABLE wallet ALTER COLUMN currency DROP DEFAULT;
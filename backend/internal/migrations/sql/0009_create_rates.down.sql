@@ -0,0 +1,2 @@
+ALTER TABLE wallet_transactions DROP COLUMN IF EXISTS exchange_rate, DROP COLUMN IF EXISTS rate_id;
+DROP TABLE IF EXISTS rates;
@@ -0,0 +1,18 @@
+CREATE TABLE rates (
+	id UUID PRIMARY KEY,
+	base_currency CHAR(3) NOT NULL REFERENCES currencies(code),
+	quote_currency CHAR(3) NOT NULL REFERENCES currencies(code),
+	rate NUMERIC NOT NULL CHECK (rate > 0),
+	valid_from TIMESTAMPTZ NOT NULL,
+	valid_to TIMESTAMPTZ,
+	source VARCHAR(64) NOT NULL,
+	created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+	CHECK (base_currency <> quote_currency),
+	CHECK (valid_to IS NULL OR valid_to > valid_from)
+);
+
+CREATE INDEX rates_pair_valid_from_idx ON rates(base_currency, quote_currency, valid_from DESC);
+
+ALTER TABLE wallet_transactions
+	ADD COLUMN rate_id UUID REFERENCES rates(id),
+	ADD COLUMN exchange_rate NUMERIC;
@@ -0,0 +1,6 @@
+ALTER TABLE wallet_transactions DROP COLUMN IF EXISTS held_amount, DROP COLUMN IF EXISTS hold_id;
+ALTER TABLE wallet DROP CONSTRAINT IF EXISTS wallet_available_check;
+ALTER TABLE wallet DROP COLUMN IF EXISTS held;
+ALTER TABLE wallet DROP CONSTRAINT IF EXISTS wallet_amount_check;
+ALTER TABLE wallet ADD CONSTRAINT wallet_amount_check CHECK (amount >= 0);
+DROP TABLE IF EXISTS holds;
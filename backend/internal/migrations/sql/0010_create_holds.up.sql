@@ -0,0 +1,24 @@
+CREATE TABLE holds (
+	id UUID PRIMARY KEY,
+	wallet_id UUID NOT NULL REFERENCES wallet(id),
+	amount BIGINT NOT NULL CHECK (amount > 0),
+	captured BIGINT NOT NULL DEFAULT 0,
+	status VARCHAR(16) NOT NULL DEFAULT 'ACTIVE' CHECK (status IN ('ACTIVE', 'CAPTURED', 'RELEASED')),
+	expires_at TIMESTAMPTZ NOT NULL,
+	created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+	updated_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+	CHECK (captured BETWEEN 0 AND amount)
+);
+
+CREATE INDEX holds_wallet_id_idx ON holds(wallet_id);
+
+-- held is the sum of the active holds of the wallet. Only the available
+-- balance, amount - held, has to stay non-negative, so a hold cannot reserve
+-- funds that are already spent or reserved.
+ALTER TABLE wallet ADD COLUMN held BIGINT NOT NULL DEFAULT 0 CHECK (held >= 0);
+ALTER TABLE wallet DROP CONSTRAINT wallet_amount_check;
+ALTER TABLE wallet ADD CONSTRAINT wallet_available_check CHECK (amount - held >= 0);
+
+ALTER TABLE wallet_transactions
+	ADD COLUMN hold_id UUID REFERENCES holds(id),
+	ADD COLUMN held_amount BIGINT NOT NULL DEFAULT 0;
@@ -0,0 +1 @@
+DROP INDEX IF EXISTS holds_active_expires_at_idx;
@@ -0,0 +1,2 @@
+-- The expiry worker only ever looks for active holds past their expiry.
+CREATE INDEX holds_active_expires_at_idx ON holds(expires_at) WHERE status = 'ACTIVE';
@@ -0,0 +1,6 @@
+-- A key claimed by several callers keeps only its oldest claim.
+DELETE FROM idempotency_keys newer USING idempotency_keys older
+WHERE newer.key = older.key AND (newer.created_at, newer.client_id) > (older.created_at, older.client_id);
+ALTER TABLE idempotency_keys DROP CONSTRAINT IF EXISTS idempotency_keys_pkey;
+ALTER TABLE idempotency_keys DROP COLUMN IF EXISTS client_id;
+ALTER TABLE idempotency_keys ADD PRIMARY KEY (key);
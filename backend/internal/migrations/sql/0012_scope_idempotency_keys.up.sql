@@ -0,0 +1,5 @@
+-- Keys are picked by clients, so two clients may pick the same one; each
+-- caller gets its own key space. Keys claimed so far belong to no caller.
+ALTER TABLE idempotency_keys ADD COLUMN IF NOT EXISTS client_id VARCHAR(255) NOT NULL DEFAULT '';
+ALTER TABLE idempotency_keys DROP CONSTRAINT IF EXISTS idempotency_keys_pkey;
+ALTER TABLE idempotency_keys ADD PRIMARY KEY (client_id, key);
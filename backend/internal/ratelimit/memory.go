@@ -0,0 +1,79 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// sweepInterval is how often the memory store drops buckets that refilled
+// completely, and the postgres store buckets left idle; such a bucket
+// behaves exactly like a missing one.
+const sweepInterval = time.Minute
+
+type bucket struct {
+	tokens  float64
+	updated time.Time
+	limit   Limit
+}
+
+func (bucket *bucket) refill(now time.Time) {
+	elapsed := now.Sub(bucket.updated).Seconds()
+	bucket.tokens = min(float64(bucket.limit.Burst), bucket.tokens+elapsed*bucket.limit.Rate)
+	bucket.updated = now
+}
+
+// MemoryStore keeps the buckets in process memory. Every replica limits on its
+// own, so the effective limit grows with the number of replicas.
+type MemoryStore struct {
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	lastSweep time.Time
+	Now       func() time.Time
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		buckets: make(map[string]*bucket),
+		Now:     time.Now,
+	}
+}
+
+func (store *MemoryStore) Take(ctx context.Context, key string, limit Limit) (time.Duration, error) {
+	now := store.Now()
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	if now.Sub(store.lastSweep) >= sweepInterval {
+		store.sweep(now)
+	}
+	current, ok := store.buckets[key]
+	if !ok {
+		current = &bucket{tokens: float64(limit.Burst), updated: now}
+		store.buckets[key] = current
+	}
+	current.limit = limit
+	current.refill(now)
+	if current.tokens < 1 {
+		return wait(current.tokens, limit), nil
+	}
+	current.tokens--
+	return 0, nil
+}
+
+func (store *MemoryStore) sweep(now time.Time) {
+	for key, current := range store.buckets {
+		current.refill(now)
+		if current.tokens >= float64(current.limit.Burst) {
+			delete(store.buckets, key)
+		}
+	}
+	store.lastSweep = now
+}
+
+// Len returns the number of buckets held.
+func (store *MemoryStore) Len() int {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	return len(store.buckets)
+}
@@ -0,0 +1,102 @@
+package ratelimit
+
+import (
+	"backend/pkg/customerror"
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// idleBucketAge is how long a bucket has to go untouched before the sweep of
+// the postgres store deletes it. Any sane limit refills its bucket well within
+// that time, and a missing bucket behaves exactly like a full one.
+const idleBucketAge = time.Hour
+
+type PoolInterface interface {
+	Exec(ctx context.Context, sql string, arguments ...interface{}) (pgconn.CommandTag, error)
+	QueryRow(ctx context.Context, sql string, args ...interface{}) pgx.Row
+}
+
+// PostgresStore keeps the buckets in the rate_limit_buckets table, so every
+// replica draws from the same buckets. Each take is a single statement, and
+// the row lock it holds serializes concurrent takes of one key. Run deletes
+// idle buckets in the background, so the table does not gain a row for every
+// wallet ever used.
+type PostgresStore struct {
+	Pool   PoolInterface
+	Logger *slog.Logger
+}
+
+func NewPostgresStore(pool PoolInterface, logger *slog.Logger) *PostgresStore {
+	return &PostgresStore{
+		Pool:   pool,
+		Logger: logger,
+	}
+}
+
+// The bucket is refilled and decremented in the upsert. When fewer than one
+// token is left the WHERE clause skips the update and no row comes back.
+const (
+	takeQuery = `INSERT INTO rate_limit_buckets AS b (key, tokens, updated_at) VALUES ($1, $2::float8 - 1, now())
+	ON CONFLICT (key) DO UPDATE SET
+		tokens = LEAST($2::float8, b.tokens + EXTRACT(EPOCH FROM now() - b.updated_at) * $3::float8) - 1,
+		updated_at = now()
+	WHERE LEAST($2::float8, b.tokens + EXTRACT(EPOCH FROM now() - b.updated_at) * $3::float8) >= 1
+	RETURNING tokens`
+	tokensQuery = `SELECT LEAST($2::float8, tokens + EXTRACT(EPOCH FROM now() - updated_at) * $3::float8)
+	FROM rate_limit_buckets WHERE key = $1`
+	sweepQuery = "DELETE FROM rate_limit_buckets WHERE updated_at < now() - make_interval(secs => $1)"
+)
+
+func (store *PostgresStore) Take(ctx context.Context, key string, limit Limit) (time.Duration, error) {
+	burst := float64(limit.Burst)
+	var tokens float64
+	err := store.Pool.QueryRow(ctx, takeQuery, key, burst, limit.Rate).Scan(&tokens)
+	if err == nil {
+		return 0, nil
+	}
+	if !errors.Is(err, pgx.ErrNoRows) {
+		return 0, customerror.Wrap(err, "ratelimit.PostgresStore.Take", key)
+	}
+	err = store.Pool.QueryRow(ctx, tokensQuery, key, burst, limit.Rate).Scan(&tokens)
+	if err != nil {
+		return 0, customerror.Wrap(err, "ratelimit.PostgresStore.Take", key)
+	}
+	return wait(tokens, limit), nil
+}
+
+// Run sweeps idle buckets right away and then every sweepInterval until ctx
+// is cancelled. Sweeps stay off the request path, so a slow or failing one
+// neither delays takes nor turns rate limiting off; failures are logged and
+// retried by the next sweep.
+func (store *PostgresStore) Run(ctx context.Context) {
+	ticker := time.NewTicker(sweepInterval)
+	defer ticker.Stop()
+	for {
+		store.sweep(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (store *PostgresStore) sweep(ctx context.Context) {
+	command, err := store.Pool.Exec(ctx, sweepQuery, idleBucketAge.Seconds())
+	if ctx.Err() != nil {
+		return
+	}
+	if err != nil {
+		err = customerror.Wrap(err, "ratelimit.PostgresStore.sweep", "rate_limit_buckets")
+		store.Logger.ErrorContext(ctx, "cannot sweep idle rate limit buckets", slog.Any("error", err))
+		return
+	}
+	if command.RowsAffected() > 0 {
+		store.Logger.DebugContext(ctx, "idle rate limit buckets swept", slog.Int64("count", command.RowsAffected()))
+	}
+}
@@ -0,0 +1,76 @@
+package ratelimit
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Limit is a token bucket: Burst requests may arrive at once, after which the
+// bucket refills at Rate requests per second. A zero Limit never throttles.
+type Limit struct {
+	Rate  float64
+	Burst int
+}
+
+func (limit Limit) Enabled() bool {
+	return limit.Rate > 0 && limit.Burst > 0
+}
+
+// StoreI holds the buckets. Take removes one token from the bucket of key and
+// returns zero, or leaves the bucket untouched and returns how long the caller
+// has to wait for the next token.
+type StoreI interface {
+	Take(ctx context.Context, key string, limit Limit) (time.Duration, error)
+}
+
+// LimiterI is what the handlers see. Both methods return the time to wait
+// before retrying, or zero when the request may proceed. The wallet bucket
+// belongs to the caller and the wallet together, so a caller hammering a
+// wallet it may not even use cannot throttle the owner of that wallet.
+type LimiterI interface {
+	AllowClient(ctx context.Context, clientID string) (time.Duration, error)
+	AllowWallet(ctx context.Context, clientID string, walletID uuid.UUID) (time.Duration, error)
+}
+
+type Limiter struct {
+	Store  StoreI
+	Client Limit
+	Wallet Limit
+}
+
+func NewLimiter(store StoreI, client Limit, wallet Limit) LimiterI {
+	return &Limiter{
+		Store:  store,
+		Client: client,
+		Wallet: wallet,
+	}
+}
+
+// NewNop returns a limiter that lets everything through.
+func NewNop() LimiterI {
+	return &Limiter{}
+}
+
+func (limiter *Limiter) AllowClient(ctx context.Context, clientID string) (time.Duration, error) {
+	if !limiter.Client.Enabled() {
+		return 0, nil
+	}
+	return limiter.Store.Take(ctx, "client:"+clientID, limiter.Client)
+}
+
+func (limiter *Limiter) AllowWallet(ctx context.Context, clientID string, walletID uuid.UUID) (time.Duration, error) {
+	if !limiter.Wallet.Enabled() {
+		return 0, nil
+	}
+	return limiter.Store.Take(ctx, "wallet:"+clientID+":"+walletID.String(), limiter.Wallet)
+}
+
+// wait is how long a bucket holding tokens needs until one token is available.
+func wait(tokens float64, limit Limit) time.Duration {
+	if tokens >= 1 {
+		return 0
+	}
+	return time.Duration((1 - tokens) / limit.Rate * float64(time.Second))
+}
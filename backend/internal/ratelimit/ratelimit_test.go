@@ -0,0 +1,180 @@
+package ratelimit_test
+
+import (
+	"backend/internal/ratelimit"
+	"context"
+	"errors"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestMemoryStore_Take(t *testing.T) {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	store := ratelimit.NewMemoryStore()
+	store.Now = func() time.Time { return now }
+	limit := ratelimit.Limit{Rate: 2, Burst: 3}
+
+	for range limit.Burst {
+		retryAfter, err := store.Take(context.Background(), "client:a", limit)
+		assert.NoError(t, err)
+		assert.Zero(t, retryAfter)
+	}
+	retryAfter, err := store.Take(context.Background(), "client:a", limit)
+	assert.NoError(t, err)
+	assert.Equal(t, 500*time.Millisecond, retryAfter)
+
+	// Other keys have their own bucket.
+	retryAfter, err = store.Take(context.Background(), "client:b", limit)
+	assert.NoError(t, err)
+	assert.Zero(t, retryAfter)
+
+	now = now.Add(500 * time.Millisecond)
+	retryAfter, err = store.Take(context.Background(), "client:a", limit)
+	assert.NoError(t, err)
+	assert.Zero(t, retryAfter)
+
+	// Buckets that refilled completely are dropped.
+	now = now.Add(time.Hour)
+	_, err = store.Take(context.Background(), "client:c", limit)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, store.Len())
+}
+
+type MockStore struct {
+	mock.Mock
+}
+
+func (m *MockStore) Take(ctx context.Context, key string, limit ratelimit.Limit) (time.Duration, error) {
+	args := m.Called(ctx, key, limit)
+	return args.Get(0).(time.Duration), args.Error(1)
+}
+
+func TestLimiter(t *testing.T) {
+	walletID := uuid.New()
+	clientLimit := ratelimit.Limit{Rate: 1, Burst: 1}
+	store := new(MockStore)
+	store.On("Take", mock.Anything, "client:key-1", clientLimit).Return(time.Second, nil).Once()
+	limiter := ratelimit.NewLimiter(store, clientLimit, ratelimit.Limit{})
+
+	retryAfter, err := limiter.AllowClient(context.Background(), "key-1")
+	assert.NoError(t, err)
+	assert.Equal(t, time.Second, retryAfter)
+
+	// A disabled limit never reaches the store.
+	retryAfter, err = limiter.AllowWallet(context.Background(), "key-1", walletID)
+	assert.NoError(t, err)
+	assert.Zero(t, retryAfter)
+	store.AssertExpectations(t)
+}
+
+type MockPool struct {
+	mock.Mock
+}
+
+func (m *MockPool) Exec(ctx context.Context, sql string, args ...interface{}) (pgconn.CommandTag, error) {
+	mockArgs := m.Called(ctx, sql, args)
+	return mockArgs.Get(0).(pgconn.CommandTag), mockArgs.Error(1)
+}
+
+func (m *MockPool) QueryRow(ctx context.Context, sql string, args ...interface{}) pgx.Row {
+	mockArgs := m.Called(ctx, sql, args)
+	return mockArgs.Get(0).(pgx.Row)
+}
+
+type MockRow struct {
+	mock.Mock
+}
+
+func (m *MockRow) Scan(dest ...any) error {
+	args := m.Called(dest)
+	return args.Error(0)
+}
+
+type PostgresTakeTest struct {
+	Name              string
+	Mock              func(*MockPool)
+	WaitingRetryAfter time.Duration
+	WantErr           bool
+}
+
+func TestPostgresStore_Take(t *testing.T) {
+	limit := ratelimit.Limit{Rate: 4, Burst: 10}
+	arguments := []interface{}{"wallet:w", float64(10), float64(4)}
+	rowWith := func(tokens float64, err error) *MockRow {
+		row := new(MockRow)
+		row.On("Scan", mock.Anything).Run(func(args mock.Arguments) {
+			*args.Get(0).([]any)[0].(*float64) = tokens
+		}).Return(err)
+		return row
+	}
+
+	tests := []PostgresTakeTest{
+		{
+			Name: "Allowed Test",
+			Mock: func(p *MockPool) {
+				p.On("QueryRow", mock.Anything, mock.Anything, arguments).Return(rowWith(9, nil)).Once()
+			},
+		},
+		{
+			Name: "Throttled Test",
+			Mock: func(p *MockPool) {
+				p.On("QueryRow", mock.Anything, mock.Anything, arguments).Return(rowWith(0, pgx.ErrNoRows)).Once()
+				p.On("QueryRow", mock.Anything, mock.Anything, arguments).Return(rowWith(0.5, nil)).Once()
+			},
+			WaitingRetryAfter: 125 * time.Millisecond,
+		},
+		{
+			Name: "Error Test",
+			Mock: func(p *MockPool) {
+				p.On("QueryRow", mock.Anything, mock.Anything, arguments).Return(rowWith(0, errors.New("connection refused"))).Once()
+			},
+			WantErr: true,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			// Takes never sweep; an unexpected Exec fails the test.
+			mockPool := new(MockPool)
+			test.Mock(mockPool)
+			store := ratelimit.NewPostgresStore(mockPool, slog.New(slog.DiscardHandler))
+
+			retryAfter, err := store.Take(context.Background(), "wallet:w", limit)
+			if test.WantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, test.WaitingRetryAfter, retryAfter)
+			}
+			mockPool.AssertExpectations(t)
+		})
+	}
+}
+
+func TestPostgresStore_Run(t *testing.T) {
+	sweepQuery := "DELETE FROM rate_limit_buckets WHERE updated_at < now() - make_interval(secs => $1)"
+	ctx, cancel := context.WithCancel(context.Background())
+	mockPool := new(MockPool)
+	// Run sweeps right away; the cancel stops it before the next tick.
+	mockPool.On("Exec", mock.Anything, sweepQuery, []interface{}{float64(3600)}).
+		Run(func(mock.Arguments) { cancel() }).Return(pgconn.NewCommandTag("DELETE 2"), nil).Once()
+	store := ratelimit.NewPostgresStore(mockPool, slog.New(slog.DiscardHandler))
+
+	done := make(chan struct{})
+	go func() {
+		store.Run(ctx)
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Run did not return after cancel")
+	}
+	mockPool.AssertExpectations(t)
+}
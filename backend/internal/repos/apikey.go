@@ -0,0 +1,170 @@
+package repos
+
+import (
+	"backend/internal/tracing"
+	"backend/pkg/auth"
+	"backend/pkg/config"
+	"backend/pkg/customerror"
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"go.opentelemetry.io/otel/trace"
+)
+
+type APIKeyRepositoryI interface {
+	CreateAPIKey(ctx context.Context, key *auth.APIKey) error
+	GetAPIKey(ctx context.Context, id uuid.UUID) (*auth.APIKey, error)
+	GetAPIKeyByHash(ctx context.Context, hash string) (*auth.APIKey, error)
+	ListAPIKeys(ctx context.Context) ([]auth.APIKey, error)
+	RevokeAPIKey(ctx context.Context, id uuid.UUID) error
+	RotateAPIKey(ctx context.Context, id uuid.UUID, replacement *auth.APIKey, expiresAt time.Time) error
+}
+
+type APIKeyRepository struct {
+	Pool   PoolInterface
+	Host   string
+	Port   string
+	Logger *slog.Logger
+	Tracer trace.Tracer
+}
+
+func NewAPIKeyRepository(pool PoolInterface, appConfig *config.Config, logger *slog.Logger, tracerProvider trace.TracerProvider) APIKeyRepositoryI {
+	return &APIKeyRepository{
+		Pool:   pool,
+		Host:   appConfig.WebHost,
+		Port:   appConfig.WebPort,
+		Logger: logger,
+		Tracer: tracerProvider.Tracer("backend/internal/repos"),
+	}
+}
+
+const apiKeyColumns = "id, name, key_hash, scopes, created_at, expires_at, revoked_at"
+
+func scanAPIKey(row pgx.Row) (*auth.APIKey, error) {
+	var key auth.APIKey
+	err := row.Scan(&key.ID, &key.Name, &key.Hash, &key.Scopes, &key.CreatedAt, &key.ExpiresAt, &key.RevokedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &key, nil
+}
+
+func (apiKeyRepo *APIKeyRepository) CreateAPIKey(ctx context.Context, key *auth.APIKey) (err error) {
+	ctx, span := apiKeyRepo.Tracer.Start(ctx, "apiKeyRepo.CreateAPIKey", trace.WithAttributes(tracing.APIKeyIDKey.String(key.ID.String())))
+	defer func() { tracing.End(span, err) }()
+
+	insertQuery := "INSERT INTO api_keys (id, name, key_hash, scopes, expires_at) VALUES ($1, $2, $3, $4, $5) RETURNING created_at"
+	err = apiKeyRepo.Pool.QueryRow(ctx, insertQuery, key.ID, key.Name, key.Hash, key.Scopes, key.ExpiresAt).Scan(&key.CreatedAt)
+	if err != nil {
+		return customerror.Wrap(err, "apiKeyRepo.CreateAPIKey", apiKeyRepo.Host+":"+apiKeyRepo.Port)
+	}
+	return nil
+}
+
+func (apiKeyRepo *APIKeyRepository) GetAPIKey(ctx context.Context, id uuid.UUID) (_ *auth.APIKey, err error) {
+	ctx, span := apiKeyRepo.Tracer.Start(ctx, "apiKeyRepo.GetAPIKey", trace.WithAttributes(tracing.APIKeyIDKey.String(id.String())))
+	defer func() { tracing.End(span, err) }()
+
+	selectQuery := "SELECT " + apiKeyColumns + " FROM api_keys WHERE id = $1"
+	key, err := scanAPIKey(apiKeyRepo.Pool.QueryRow(ctx, selectQuery, id))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, auth.ErrAPIKeyNotFound
+	}
+	if err != nil {
+		return nil, customerror.Wrap(err, "apiKeyRepo.GetAPIKey", apiKeyRepo.Host+":"+apiKeyRepo.Port)
+	}
+	return key, nil
+}
+
+func (apiKeyRepo *APIKeyRepository) GetAPIKeyByHash(ctx context.Context, hash string) (_ *auth.APIKey, err error) {
+	ctx, span := apiKeyRepo.Tracer.Start(ctx, "apiKeyRepo.GetAPIKeyByHash")
+	defer func() { tracing.End(span, err) }()
+
+	selectQuery := "SELECT " + apiKeyColumns + " FROM api_keys WHERE key_hash = $1"
+	key, err := scanAPIKey(apiKeyRepo.Pool.QueryRow(ctx, selectQuery, hash))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, auth.ErrAPIKeyNotFound
+	}
+	if err != nil {
+		return nil, customerror.Wrap(err, "apiKeyRepo.GetAPIKeyByHash", apiKeyRepo.Host+":"+apiKeyRepo.Port)
+	}
+	return key, nil
+}
+
+func (apiKeyRepo *APIKeyRepository) ListAPIKeys(ctx context.Context) (_ []auth.APIKey, err error) {
+	ctx, span := apiKeyRepo.Tracer.Start(ctx, "apiKeyRepo.ListAPIKeys")
+	defer func() { tracing.End(span, err) }()
+
+	selectQuery := "SELECT " + apiKeyColumns + " FROM api_keys ORDER BY created_at, id"
+	rows, err := apiKeyRepo.Pool.Query(ctx, selectQuery)
+	if err != nil {
+		return nil, customerror.Wrap(err, "apiKeyRepo.ListAPIKeys", apiKeyRepo.Host+":"+apiKeyRepo.Port)
+	}
+	defer rows.Close()
+
+	keys := []auth.APIKey{}
+	for rows.Next() {
+		key, err := scanAPIKey(rows)
+		if err != nil {
+			return nil, customerror.Wrap(err, "apiKeyRepo.ListAPIKeys", apiKeyRepo.Host+":"+apiKeyRepo.Port)
+		}
+		keys = append(keys, *key)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, customerror.Wrap(err, "apiKeyRepo.ListAPIKeys", apiKeyRepo.Host+":"+apiKeyRepo.Port)
+	}
+	return keys, nil
+}
+
+func (apiKeyRepo *APIKeyRepository) RevokeAPIKey(ctx context.Context, id uuid.UUID) (err error) {
+	ctx, span := apiKeyRepo.Tracer.Start(ctx, "apiKeyRepo.RevokeAPIKey", trace.WithAttributes(tracing.APIKeyIDKey.String(id.String())))
+	defer func() { tracing.End(span, err) }()
+
+	updateQuery := "UPDATE api_keys SET revoked_at = now() WHERE id = $1 AND revoked_at IS NULL"
+	command, err := apiKeyRepo.Pool.Exec(ctx, updateQuery, id)
+	if err != nil {
+		return customerror.Wrap(err, "apiKeyRepo.RevokeAPIKey", apiKeyRepo.Host+":"+apiKeyRepo.Port)
+	}
+	if command.RowsAffected() == 0 {
+		return auth.ErrAPIKeyNotFound
+	}
+	return nil
+}
+
+// RotateAPIKey stores replacement and lets the key it replaces expire at
+// expiresAt, so clients can switch over without downtime. Both happen in one
+// database transaction.
+func (apiKeyRepo *APIKeyRepository) RotateAPIKey(ctx context.Context, id uuid.UUID, replacement *auth.APIKey, expiresAt time.Time) (err error) {
+	ctx, span := apiKeyRepo.Tracer.Start(ctx, "apiKeyRepo.RotateAPIKey", trace.WithAttributes(tracing.APIKeyIDKey.String(id.String())))
+	defer func() { tracing.End(span, err) }()
+
+	tx, err := apiKeyRepo.Pool.Begin(ctx)
+	if err != nil {
+		return customerror.Wrap(err, "apiKeyRepo.RotateAPIKey", apiKeyRepo.Host+":"+apiKeyRepo.Port)
+	}
+	defer tx.Rollback(ctx)
+
+	expireQuery := `UPDATE api_keys SET expires_at = LEAST(COALESCE(expires_at, $2), $2)
+	WHERE id = $1 AND revoked_at IS NULL AND (expires_at IS NULL OR expires_at > now())`
+	command, err := tx.Exec(ctx, expireQuery, id, expiresAt)
+	if err != nil {
+		return customerror.Wrap(err, "apiKeyRepo.RotateAPIKey", apiKeyRepo.Host+":"+apiKeyRepo.Port)
+	}
+	if command.RowsAffected() == 0 {
+		return auth.ErrAPIKeyNotFound
+	}
+	insertQuery := "INSERT INTO api_keys (id, name, key_hash, scopes, expires_at) VALUES ($1, $2, $3, $4, $5) RETURNING created_at"
+	err = tx.QueryRow(ctx, insertQuery, replacement.ID, replacement.Name, replacement.Hash, replacement.Scopes, replacement.ExpiresAt).Scan(&replacement.CreatedAt)
+	if err != nil {
+		return customerror.Wrap(err, "apiKeyRepo.RotateAPIKey", apiKeyRepo.Host+":"+apiKeyRepo.Port)
+	}
+	err = tx.Commit(ctx)
+	if err != nil {
+		return customerror.Wrap(err, "apiKeyRepo.RotateAPIKey", apiKeyRepo.Host+":"+apiKeyRepo.Port)
+	}
+	return nil
+}
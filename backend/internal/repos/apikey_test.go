@@ -0,0 +1,169 @@
+package repos_test
+
+import (
+	"backend/internal/repos"
+	"backend/pkg/auth"
+	"backend/pkg/customerror"
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+func newAPIKeyRepository(pool *MockPool) *repos.APIKeyRepository {
+	return &repos.APIKeyRepository{
+		Pool:   pool,
+		Host:   "127.0.0.1",
+		Port:   "8080",
+		Logger: slog.New(slog.DiscardHandler),
+		Tracer: noop.NewTracerProvider().Tracer(""),
+	}
+}
+
+type GetAPIKeyByHashTest struct {
+	Name         string
+	Mock         func(*MockPool, *MockRow)
+	WaitingKey   *auth.APIKey
+	WaitingError error
+}
+
+func TestAPIKeyRepository_GetAPIKeyByHash(t *testing.T) {
+	testKey := &auth.APIKey{
+		ID:     uuid.New(),
+		Name:   "billing",
+		Hash:   auth.HashAPIKey("wk_secret"),
+		Scopes: []string{auth.ScopeWalletRead},
+	}
+	tests := []GetAPIKeyByHashTest{
+		{
+			Name: "Success Test",
+			Mock: func(p *MockPool, r *MockRow) {
+				p.On("QueryRow", mock.Anything, mock.Anything, []interface{}{testKey.Hash}).Return(r)
+				r.On("Scan", mock.Anything).Run(func(args mock.Arguments) {
+					dest := args.Get(0).([]interface{})
+					*dest[0].(*uuid.UUID) = testKey.ID
+					*dest[1].(*string) = testKey.Name
+					*dest[2].(*string) = testKey.Hash
+					*dest[3].(*[]string) = testKey.Scopes
+				}).Return(nil)
+			},
+			WaitingKey: testKey,
+		},
+		{
+			Name: "Not Found Test",
+			Mock: func(p *MockPool, r *MockRow) {
+				p.On("QueryRow", mock.Anything, mock.Anything, mock.Anything).Return(r)
+				r.On("Scan", mock.Anything).Return(pgx.ErrNoRows)
+			},
+			WaitingError: auth.ErrAPIKeyNotFound,
+		},
+		{
+			Name: "Other Error Test",
+			Mock: func(p *MockPool, r *MockRow) {
+				p.On("QueryRow", mock.Anything, mock.Anything, mock.Anything).Return(r)
+				r.On("Scan", mock.Anything).Return(errors.New("Other error"))
+			},
+			WaitingError: customerror.NewError("apiKeyRepo.GetAPIKeyByHash", "127.0.0.1:8080", "Other error"),
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			mockPool := new(MockPool)
+			mockRow := new(MockRow)
+			test.Mock(mockPool, mockRow)
+			repo := newAPIKeyRepository(mockPool)
+			key, err := repo.GetAPIKeyByHash(context.Background(), testKey.Hash)
+			if test.WaitingError != nil {
+				assert.ErrorIs(t, err, test.WaitingError)
+				assert.Nil(t, key)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, test.WaitingKey, key)
+			}
+			mockPool.AssertExpectations(t)
+			mockRow.AssertExpectations(t)
+		})
+	}
+}
+
+func TestAPIKeyRepository_RevokeAPIKey(t *testing.T) {
+	testID := uuid.New()
+	mockPool := new(MockPool)
+	mockPool.On("Exec", mock.Anything, mock.Anything, []interface{}{testID}).Return(pgconn.NewCommandTag("UPDATE 0"), nil).Once()
+	repo := newAPIKeyRepository(mockPool)
+
+	err := repo.RevokeAPIKey(context.Background(), testID)
+	assert.ErrorIs(t, err, auth.ErrAPIKeyNotFound)
+	mockPool.AssertExpectations(t)
+}
+
+type RotateAPIKeyTest struct {
+	Name         string
+	Mock         func(*MockTx, *MockRow)
+	WaitingError error
+}
+
+func TestAPIKeyRepository_RotateAPIKey(t *testing.T) {
+	testID := uuid.New()
+	expiresAt := time.Now().Add(time.Hour)
+	replacement := &auth.APIKey{ID: uuid.New(), Name: "billing", Hash: auth.HashAPIKey("wk_new"), Scopes: []string{auth.ScopeWalletWrite}}
+	isExpire := mock.MatchedBy(func(sql string) bool { return strings.HasPrefix(sql, "UPDATE api_keys SET expires_at") })
+
+	tests := []RotateAPIKeyTest{
+		{
+			Name: "Success Test",
+			Mock: func(tx *MockTx, r *MockRow) {
+				tx.On("Exec", mock.Anything, isExpire, []interface{}{testID, expiresAt}).Return(pgconn.NewCommandTag("UPDATE 1"), nil).Once()
+				tx.On("QueryRow", mock.Anything, mock.Anything, mock.Anything).Return(r).Once()
+				r.On("Scan", mock.Anything).Return(nil).Once()
+				tx.On("Commit", mock.Anything).Return(nil).Once()
+			},
+		},
+		{
+			Name: "Inactive Key Test",
+			Mock: func(tx *MockTx, r *MockRow) {
+				tx.On("Exec", mock.Anything, isExpire, mock.Anything).Return(pgconn.NewCommandTag("UPDATE 0"), nil).Once()
+			},
+			WaitingError: auth.ErrAPIKeyNotFound,
+		},
+		{
+			Name: "Insert Error Test",
+			Mock: func(tx *MockTx, r *MockRow) {
+				tx.On("Exec", mock.Anything, isExpire, mock.Anything).Return(pgconn.NewCommandTag("UPDATE 1"), nil).Once()
+				tx.On("QueryRow", mock.Anything, mock.Anything, mock.Anything).Return(r).Once()
+				r.On("Scan", mock.Anything).Return(errors.New("duplicate key")).Once()
+			},
+			WaitingError: customerror.NewError("apiKeyRepo.RotateAPIKey", "127.0.0.1:8080", "duplicate key"),
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			mockPool := new(MockPool)
+			mockTx := new(MockTx)
+			mockRow := new(MockRow)
+			mockPool.On("Begin", mock.Anything).Return(mockTx, nil).Once()
+			mockTx.On("Rollback", mock.Anything).Return(nil).Maybe()
+			test.Mock(mockTx, mockRow)
+			repo := newAPIKeyRepository(mockPool)
+
+			err := repo.RotateAPIKey(context.Background(), testID, replacement, expiresAt)
+			if test.WaitingError != nil {
+				assert.ErrorIs(t, err, test.WaitingError)
+			} else {
+				assert.NoError(t, err)
+			}
+			mockPool.AssertExpectations(t)
+			mockTx.AssertExpectations(t)
+			mockRow.AssertExpectations(t)
+		})
+	}
+}
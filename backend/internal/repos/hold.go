@@ -0,0 +1,311 @@
+package repos
+
+import (
+	"backend/internal/tracing"
+	"backend/pkg/customerror"
+	"backend/pkg/wallet"
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Holds are read with the currency of the wallet they are on.
+const (
+	holdColumns = "h.id, h.wallet_id, h.amount, h.captured, h.status, h.expires_at, h.created_at, c.code, c.minor_units"
+	holdTables  = "holds h JOIN wallet w ON w.id = h.wallet_id JOIN currencies c ON c.code = w.currency"
+)
+
+func scanHold(row pgx.Row, dest ...any) (*wallet.Hold, error) {
+	var hold wallet.Hold
+	err := row.Scan(append([]any{
+		&hold.ID, &hold.WalletID, &hold.Amount, &hold.Captured, &hold.Status,
+		&hold.ExpiresAt, &hold.CreatedAt, &hold.Currency.Code, &hold.Currency.MinorUnits,
+	}, dest...)...)
+	if err != nil {
+		return nil, err
+	}
+	return &hold, nil
+}
+
+// CreateHold reserves amount of the wallet until expiresAt. The reservation
+// raises the held funds of the wallet, so it fails like a withdrawal when the
+// available balance is too low.
+func (walletRepo *WalletRepository) CreateHold(ctx context.Context, id uuid.UUID, walletID uuid.UUID, amount int64, expiresAt time.Time) (_ *wallet.Hold, err error) {
+	ctx, span := walletRepo.Tracer.Start(ctx, "walletRepo.CreateHold", trace.WithAttributes(
+		tracing.WalletIDKey.String(walletID.String()),
+		tracing.HoldIDKey.String(id.String()),
+	))
+	defer func() { tracing.End(span, err) }()
+
+	tx, err := walletRepo.Pool.Begin(ctx)
+	if err != nil {
+		return nil, customerror.Wrap(err, "walletRepo.CreateHold", walletRepo.Host+":"+walletRepo.Port)
+	}
+	defer tx.Rollback(ctx)
+
+	hold := wallet.Hold{
+		ID:        id,
+		WalletID:  walletID,
+		Amount:    amount,
+		Status:    wallet.HoldStatusActive,
+		ExpiresAt: expiresAt,
+	}
+	var balance int64
+	reserveQuery := `UPDATE wallet w SET held = w.held + $1 FROM currencies c WHERE w.id = $2 AND c.code = w.currency
+	RETURNING w.amount, c.code, c.minor_units`
+	err = tx.QueryRow(ctx, reserveQuery, amount, walletID).Scan(&balance, &hold.Currency.Code, &hold.Currency.MinorUnits)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, wallet.ErrNotFound
+		}
+		switch pgErrorCode(err) {
+		case checkViolation:
+			return nil, customerror.ErrWrongAmount
+		case numericValueOutOfRange:
+			return nil, customerror.ErrAmountOutOfRange
+		}
+		return nil, customerror.Wrap(err, "walletRepo.CreateHold", walletRepo.Host+":"+walletRepo.Port)
+	}
+	insertQuery := "INSERT INTO holds (id, wallet_id, amount, expires_at) VALUES ($1, $2, $3, $4) RETURNING created_at"
+	err = tx.QueryRow(ctx, insertQuery, id, walletID, amount, expiresAt).Scan(&hold.CreatedAt)
+	if err != nil {
+		return nil, customerror.Wrap(err, "walletRepo.CreateHold", walletRepo.Host+":"+walletRepo.Port)
+	}
+	err = insertTransaction(ctx, tx, &wallet.Transaction{
+		ID:            uuid.New(),
+		WalletID:      walletID,
+		OperationType: wallet.OperationHold,
+		Balance:       balance,
+		HoldID:        &hold.ID,
+		HeldAmount:    amount,
+	})
+	if err != nil {
+		return nil, customerror.Wrap(err, "walletRepo.CreateHold", walletRepo.Host+":"+walletRepo.Port)
+	}
+	err = tx.Commit(ctx)
+	if err != nil {
+		return nil, customerror.Wrap(err, "walletRepo.CreateHold", walletRepo.Host+":"+walletRepo.Port)
+	}
+	return &hold, nil
+}
+
+func (walletRepo *WalletRepository) GetHold(ctx context.Context, id uuid.UUID) (_ *wallet.Hold, err error) {
+	ctx, span := walletRepo.Tracer.Start(ctx, "walletRepo.GetHold", trace.WithAttributes(tracing.HoldIDKey.String(id.String())))
+	defer func() { tracing.End(span, err) }()
+
+	selectQuery := "SELECT " + holdColumns + " FROM " + holdTables + " WHERE h.id = $1"
+	hold, err := scanHold(walletRepo.Pool.QueryRow(ctx, selectQuery, id))
+	if err == nil {
+		return hold, nil
+	}
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, wallet.ErrHoldNotFound
+	}
+	return nil, customerror.Wrap(err, "walletRepo.GetHold", walletRepo.Host+":"+walletRepo.Port)
+}
+
+// lockHold locks an active hold for the rest of tx and reports whether it has
+// expired by the clock of the database. Holds are always locked before their
+// wallet.
+func lockHold(ctx context.Context, tx pgx.Tx, id uuid.UUID) (*wallet.Hold, bool, error) {
+	var expired bool
+	selectQuery := "SELECT " + holdColumns + ", h.expires_at <= now() FROM " + holdTables + " WHERE h.id = $1 FOR UPDATE OF h"
+	hold, err := scanHold(tx.QueryRow(ctx, selectQuery, id), &expired)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, false, wallet.ErrHoldNotFound
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	if hold.Status != wallet.HoldStatusActive {
+		return nil, false, wallet.ErrHoldNotActive
+	}
+	return hold, expired, nil
+}
+
+// CaptureHold spends amount of the hold, all of it when amount is nil, and
+// releases the rest in the same database transaction. Expired holds can only
+// be released.
+func (walletRepo *WalletRepository) CaptureHold(ctx context.Context, id uuid.UUID, amount *int64) (_ *wallet.Hold, err error) {
+	ctx, span := walletRepo.Tracer.Start(ctx, "walletRepo.CaptureHold", trace.WithAttributes(tracing.HoldIDKey.String(id.String())))
+	defer func() { tracing.End(span, err) }()
+
+	tx, err := walletRepo.Pool.Begin(ctx)
+	if err != nil {
+		return nil, customerror.Wrap(err, "walletRepo.CaptureHold", walletRepo.Host+":"+walletRepo.Port)
+	}
+	defer tx.Rollback(ctx)
+
+	hold, expired, err := lockHold(ctx, tx, id)
+	if errors.Is(err, wallet.ErrHoldNotFound) || errors.Is(err, wallet.ErrHoldNotActive) {
+		return nil, err
+	}
+	if err != nil {
+		return nil, customerror.Wrap(err, "walletRepo.CaptureHold", walletRepo.Host+":"+walletRepo.Port)
+	}
+	if expired {
+		return nil, wallet.ErrHoldExpired
+	}
+	captured := hold.Amount
+	if amount != nil {
+		captured = *amount
+	}
+	if captured <= 0 || captured > hold.Amount {
+		return nil, fmt.Errorf("%w: capture must be positive and within the hold", customerror.ErrInvalidAmount)
+	}
+
+	// Spending held funds leaves the available balance as it was, so the
+	// capture cannot fail for lack of funds.
+	var balance int64
+	captureQuery := "UPDATE wallet SET amount = amount - $1, held = held - $2 WHERE id = $3 RETURNING amount"
+	err = tx.QueryRow(ctx, captureQuery, captured, hold.Amount, hold.WalletID).Scan(&balance)
+	if err != nil {
+		return nil, customerror.Wrap(err, "walletRepo.CaptureHold", walletRepo.Host+":"+walletRepo.Port)
+	}
+	entries := []wallet.Transaction{{
+		ID:            uuid.New(),
+		WalletID:      hold.WalletID,
+		OperationType: wallet.OperationCapture,
+		Amount:        -captured,
+		Balance:       balance,
+		HoldID:        &hold.ID,
+		HeldAmount:    -captured,
+	}}
+	if remainder := hold.Amount - captured; remainder > 0 {
+		entries = append(entries, wallet.Transaction{
+			ID:            uuid.New(),
+			WalletID:      hold.WalletID,
+			OperationType: wallet.OperationRelease,
+			Balance:       balance,
+			HoldID:        &hold.ID,
+			HeldAmount:    -remainder,
+		})
+	}
+	for i := range entries {
+		err = insertTransaction(ctx, tx, &entries[i])
+		if err != nil {
+			return nil, customerror.Wrap(err, "walletRepo.CaptureHold", walletRepo.Host+":"+walletRepo.Port)
+		}
+	}
+	hold.Status, hold.Captured = wallet.HoldStatusCaptured, captured
+	err = closeHold(ctx, tx, hold)
+	if err != nil {
+		return nil, customerror.Wrap(err, "walletRepo.CaptureHold", walletRepo.Host+":"+walletRepo.Port)
+	}
+	err = tx.Commit(ctx)
+	if err != nil {
+		return nil, customerror.Wrap(err, "walletRepo.CaptureHold", walletRepo.Host+":"+walletRepo.Port)
+	}
+	return hold, nil
+}
+
+// ReleaseHold gives the whole hold back to the available balance.
+func (walletRepo *WalletRepository) ReleaseHold(ctx context.Context, id uuid.UUID) (_ *wallet.Hold, err error) {
+	ctx, span := walletRepo.Tracer.Start(ctx, "walletRepo.ReleaseHold", trace.WithAttributes(tracing.HoldIDKey.String(id.String())))
+	defer func() { tracing.End(span, err) }()
+
+	tx, err := walletRepo.Pool.Begin(ctx)
+	if err != nil {
+		return nil, customerror.Wrap(err, "walletRepo.ReleaseHold", walletRepo.Host+":"+walletRepo.Port)
+	}
+	defer tx.Rollback(ctx)
+
+	hold, _, err := lockHold(ctx, tx, id)
+	if errors.Is(err, wallet.ErrHoldNotFound) || errors.Is(err, wallet.ErrHoldNotActive) {
+		return nil, err
+	}
+	if err != nil {
+		return nil, customerror.Wrap(err, "walletRepo.ReleaseHold", walletRepo.Host+":"+walletRepo.Port)
+	}
+	err = releaseHold(ctx, tx, hold)
+	if err != nil {
+		return nil, customerror.Wrap(err, "walletRepo.ReleaseHold", walletRepo.Host+":"+walletRepo.Port)
+	}
+	err = tx.Commit(ctx)
+	if err != nil {
+		return nil, customerror.Wrap(err, "walletRepo.ReleaseHold", walletRepo.Host+":"+walletRepo.Port)
+	}
+	return hold, nil
+}
+
+// releaseHold returns a locked hold to the available balance, journals the
+// release and closes the hold.
+func releaseHold(ctx context.Context, tx pgx.Tx, hold *wallet.Hold) error {
+	transaction := wallet.Transaction{
+		ID:            uuid.New(),
+		WalletID:      hold.WalletID,
+		OperationType: wallet.OperationRelease,
+		HoldID:        &hold.ID,
+		HeldAmount:    -hold.Amount,
+	}
+	releaseQuery := "UPDATE wallet SET held = held - $1 WHERE id = $2 RETURNING amount"
+	err := tx.QueryRow(ctx, releaseQuery, hold.Amount, hold.WalletID).Scan(&transaction.Balance)
+	if err != nil {
+		return err
+	}
+	err = insertTransaction(ctx, tx, &transaction)
+	if err != nil {
+		return err
+	}
+	hold.Status = wallet.HoldStatusReleased
+	return closeHold(ctx, tx, hold)
+}
+
+func closeHold(ctx context.Context, tx pgx.Tx, hold *wallet.Hold) error {
+	closeQuery := "UPDATE holds SET status = $1, captured = $2, updated_at = now() WHERE id = $3"
+	_, err := tx.Exec(ctx, closeQuery, hold.Status, hold.Captured, hold.ID)
+	return err
+}
+
+// ReleaseExpiredHolds releases up to limit active holds that have expired and
+// returns them. Holds another transaction has locked, such as one being
+// captured or released by another replica, are skipped rather than waited
+// for; they are either gone or picked up by the next batch.
+func (walletRepo *WalletRepository) ReleaseExpiredHolds(ctx context.Context, limit int) (_ []wallet.Hold, err error) {
+	ctx, span := walletRepo.Tracer.Start(ctx, "walletRepo.ReleaseExpiredHolds")
+	defer func() { tracing.End(span, err) }()
+
+	tx, err := walletRepo.Pool.Begin(ctx)
+	if err != nil {
+		return nil, customerror.Wrap(err, "walletRepo.ReleaseExpiredHolds", walletRepo.Host+":"+walletRepo.Port)
+	}
+	defer tx.Rollback(ctx)
+
+	// Wallets are updated in id order, as transfers do, so two batches cannot
+	// deadlock on each other's wallets.
+	selectQuery := "SELECT " + holdColumns + " FROM " + holdTables +
+		" WHERE h.status = $1 AND h.expires_at <= now() ORDER BY h.wallet_id, h.id LIMIT $2 FOR UPDATE OF h SKIP LOCKED"
+	rows, err := tx.Query(ctx, selectQuery, wallet.HoldStatusActive, limit)
+	if err != nil {
+		return nil, customerror.Wrap(err, "walletRepo.ReleaseExpiredHolds", walletRepo.Host+":"+walletRepo.Port)
+	}
+	holds := make([]wallet.Hold, 0, limit)
+	for rows.Next() {
+		hold, err := scanHold(rows)
+		if err != nil {
+			rows.Close()
+			return nil, customerror.Wrap(err, "walletRepo.ReleaseExpiredHolds", walletRepo.Host+":"+walletRepo.Port)
+		}
+		holds = append(holds, *hold)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return nil, customerror.Wrap(err, "walletRepo.ReleaseExpiredHolds", walletRepo.Host+":"+walletRepo.Port)
+	}
+	for i := range holds {
+		err = releaseHold(ctx, tx, &holds[i])
+		if err != nil {
+			return nil, customerror.Wrap(err, "walletRepo.ReleaseExpiredHolds", walletRepo.Host+":"+walletRepo.Port)
+		}
+	}
+	err = tx.Commit(ctx)
+	if err != nil {
+		return nil, customerror.Wrap(err, "walletRepo.ReleaseExpiredHolds", walletRepo.Host+":"+walletRepo.Port)
+	}
+	return holds, nil
+}
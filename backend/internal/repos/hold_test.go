@@ -0,0 +1,371 @@
+package repos_test
+
+import (
+	"backend/internal/repos"
+	"backend/pkg/customerror"
+	"backend/pkg/wallet"
+	"context"
+	"errors"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+const (
+	reserveQuery = `UPDATE wallet w SET held = w.held + $1 FROM currencies c WHERE w.id = $2 AND c.code = w.currency
+	RETURNING w.amount, c.code, c.minor_units`
+	lockHoldQuery = "SELECT h.id, h.wallet_id, h.amount, h.captured, h.status, h.expires_at, h.created_at, c.code, c.minor_units, h.expires_at <= now() " +
+		"FROM holds h JOIN wallet w ON w.id = h.wallet_id JOIN currencies c ON c.code = w.currency WHERE h.id = $1 FOR UPDATE OF h"
+	captureQuery = "UPDATE wallet SET amount = amount - $1, held = held - $2 WHERE id = $3 RETURNING amount"
+	releaseQuery = "UPDATE wallet SET held = held - $1 WHERE id = $2 RETURNING amount"
+	closeQuery   = "UPDATE holds SET status = $1, captured = $2, updated_at = now() WHERE id = $3"
+	ledgerQuery  = `INSERT INTO wallet_transactions (id, wallet_id, operation_type, amount, balance, transfer_id, rate_id, exchange_rate, hold_id, held_amount)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8::numeric, $9, $10) RETURNING created_at`
+)
+
+func newHoldRepository(pool *MockPool) *repos.WalletRepository {
+	return &repos.WalletRepository{
+		Pool:   pool,
+		Host:   "127.0.0.1",
+		Port:   "8080",
+		Logger: slog.New(slog.DiscardHandler),
+		Tracer: noop.NewTracerProvider().Tracer(""),
+	}
+}
+
+// holdRow answers the lock of hold, as active and expired or not.
+func holdRow(hold wallet.Hold, expired bool) *MockRow {
+	row := new(MockRow)
+	row.On("Scan", mock.Anything).Run(func(args mock.Arguments) {
+		dest := args.Get(0).([]interface{})
+		*dest[0].(*uuid.UUID) = hold.ID
+		*dest[1].(*uuid.UUID) = hold.WalletID
+		*dest[2].(*int64) = hold.Amount
+		*dest[4].(*string) = hold.Status
+		*dest[9].(*bool) = expired
+	}).Return(nil)
+	return row
+}
+
+// ledgerEntry matches the arguments of a journaled hold entry.
+func ledgerEntry(operationType string, amount int64, heldAmount int64) interface{} {
+	return mock.MatchedBy(func(args []interface{}) bool {
+		return args[2] == operationType && args[3] == amount && args[9] == heldAmount
+	})
+}
+
+type CreateHoldTest struct {
+	Name         string
+	Mock         func(*MockPool, *MockTx, *MockRow)
+	WaitingError error
+}
+
+func TestWalletRepository_CreateHold(t *testing.T) {
+	holdID := uuid.New()
+	walletID := uuid.New()
+	expiresAt := time.Date(2026, 1, 8, 0, 0, 0, 0, time.UTC)
+
+	tests := []CreateHoldTest{
+		{
+			Name: "Success Test",
+			Mock: func(p *MockPool, tx *MockTx, r *MockRow) {
+				p.On("Begin", mock.Anything).Return(tx, nil)
+				tx.On("QueryRow", mock.Anything, reserveQuery, []interface{}{int64(300), walletID}).Return(r).Once()
+				tx.On("QueryRow", mock.Anything, mock.Anything, []interface{}{holdID, walletID, int64(300), expiresAt}).Return(r).Once()
+				tx.On("QueryRow", mock.Anything, ledgerQuery, ledgerEntry(wallet.OperationHold, 0, 300)).Return(r).Once()
+				r.On("Scan", mock.Anything).Return(nil).Times(3)
+				tx.On("Commit", mock.Anything).Return(nil)
+				tx.On("Rollback", mock.Anything).Return(pgx.ErrTxClosed)
+			},
+		},
+		{
+			Name: "Insufficient Funds Test",
+			Mock: func(p *MockPool, tx *MockTx, r *MockRow) {
+				p.On("Begin", mock.Anything).Return(tx, nil)
+				tx.On("QueryRow", mock.Anything, reserveQuery, mock.Anything).Return(r).Once()
+				r.On("Scan", mock.Anything).Return(&pgconn.PgError{Code: "23514"}).Once()
+				tx.On("Rollback", mock.Anything).Return(nil)
+			},
+			WaitingError: customerror.ErrWrongAmount,
+		},
+		{
+			Name: "Not Found Test",
+			Mock: func(p *MockPool, tx *MockTx, r *MockRow) {
+				p.On("Begin", mock.Anything).Return(tx, nil)
+				tx.On("QueryRow", mock.Anything, reserveQuery, mock.Anything).Return(r).Once()
+				r.On("Scan", mock.Anything).Return(pgx.ErrNoRows).Once()
+				tx.On("Rollback", mock.Anything).Return(nil)
+			},
+			WaitingError: wallet.ErrNotFound,
+		},
+		{
+			Name: "Commit Error Test",
+			Mock: func(p *MockPool, tx *MockTx, r *MockRow) {
+				p.On("Begin", mock.Anything).Return(tx, nil)
+				tx.On("QueryRow", mock.Anything, mock.Anything, mock.Anything).Return(r).Times(3)
+				r.On("Scan", mock.Anything).Return(nil).Times(3)
+				tx.On("Commit", mock.Anything).Return(errors.New("error"))
+				tx.On("Rollback", mock.Anything).Return(nil)
+			},
+			WaitingError: customerror.NewError("walletRepo.CreateHold", "127.0.0.1:8080", "error"),
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			mockPool := new(MockPool)
+			mockTx := new(MockTx)
+			mockRow := new(MockRow)
+			test.Mock(mockPool, mockTx, mockRow)
+
+			hold, err := newHoldRepository(mockPool).CreateHold(context.Background(), holdID, walletID, 300, expiresAt)
+			if test.WaitingError != nil {
+				assert.EqualError(t, err, test.WaitingError.Error())
+				assert.Nil(t, hold)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, holdID, hold.ID)
+				assert.Equal(t, wallet.HoldStatusActive, hold.Status)
+				assert.Equal(t, expiresAt, hold.ExpiresAt)
+			}
+			mockPool.AssertExpectations(t)
+			mockTx.AssertExpectations(t)
+			mockRow.AssertExpectations(t)
+		})
+	}
+}
+
+type CaptureHoldTest struct {
+	Name            string
+	Amount          *int64
+	Mock            func(*MockTx, *MockRow)
+	WaitingCaptured int64
+	WaitingError    error
+}
+
+func TestWalletRepository_CaptureHold(t *testing.T) {
+	walletID := uuid.New()
+	active := wallet.Hold{ID: uuid.New(), WalletID: walletID, Amount: 100, Status: wallet.HoldStatusActive}
+	captured := active
+	captured.Status = wallet.HoldStatusCaptured
+	amount := func(value int64) *int64 { return &value }
+
+	tests := []CaptureHoldTest{
+		{
+			Name: "Full Capture Test",
+			Mock: func(tx *MockTx, r *MockRow) {
+				tx.On("QueryRow", mock.Anything, lockHoldQuery, []interface{}{active.ID}).Return(holdRow(active, false)).Once()
+				tx.On("QueryRow", mock.Anything, captureQuery, []interface{}{int64(100), int64(100), walletID}).Return(r).Once()
+				tx.On("QueryRow", mock.Anything, ledgerQuery, ledgerEntry(wallet.OperationCapture, -100, -100)).Return(r).Once()
+				r.On("Scan", mock.Anything).Return(nil).Twice()
+				tx.On("Exec", mock.Anything, closeQuery, []interface{}{wallet.HoldStatusCaptured, int64(100), active.ID}).Return(pgconn.CommandTag{}, nil).Once()
+				tx.On("Commit", mock.Anything).Return(nil)
+			},
+			WaitingCaptured: 100,
+		},
+		{
+			Name:   "Partial Capture Test",
+			Amount: amount(60),
+			Mock: func(tx *MockTx, r *MockRow) {
+				tx.On("QueryRow", mock.Anything, lockHoldQuery, []interface{}{active.ID}).Return(holdRow(active, false)).Once()
+				tx.On("QueryRow", mock.Anything, captureQuery, []interface{}{int64(60), int64(100), walletID}).Return(r).Once()
+				tx.On("QueryRow", mock.Anything, ledgerQuery, ledgerEntry(wallet.OperationCapture, -60, -60)).Return(r).Once()
+				tx.On("QueryRow", mock.Anything, ledgerQuery, ledgerEntry(wallet.OperationRelease, 0, -40)).Return(r).Once()
+				r.On("Scan", mock.Anything).Return(nil).Times(3)
+				tx.On("Exec", mock.Anything, closeQuery, []interface{}{wallet.HoldStatusCaptured, int64(60), active.ID}).Return(pgconn.CommandTag{}, nil).Once()
+				tx.On("Commit", mock.Anything).Return(nil)
+			},
+			WaitingCaptured: 60,
+		},
+		{
+			Name:   "Exceeds Hold Test",
+			Amount: amount(101),
+			Mock: func(tx *MockTx, r *MockRow) {
+				tx.On("QueryRow", mock.Anything, lockHoldQuery, mock.Anything).Return(holdRow(active, false)).Once()
+			},
+			WaitingError: customerror.ErrInvalidAmount,
+		},
+		{
+			Name:   "Zero Amount Test",
+			Amount: amount(0),
+			Mock: func(tx *MockTx, r *MockRow) {
+				tx.On("QueryRow", mock.Anything, lockHoldQuery, mock.Anything).Return(holdRow(active, false)).Once()
+			},
+			WaitingError: customerror.ErrInvalidAmount,
+		},
+		{
+			Name: "Expired Test",
+			Mock: func(tx *MockTx, r *MockRow) {
+				tx.On("QueryRow", mock.Anything, lockHoldQuery, mock.Anything).Return(holdRow(active, true)).Once()
+			},
+			WaitingError: wallet.ErrHoldExpired,
+		},
+		{
+			Name: "Not Active Test",
+			Mock: func(tx *MockTx, r *MockRow) {
+				tx.On("QueryRow", mock.Anything, lockHoldQuery, mock.Anything).Return(holdRow(captured, false)).Once()
+			},
+			WaitingError: wallet.ErrHoldNotActive,
+		},
+		{
+			Name: "Not Found Test",
+			Mock: func(tx *MockTx, r *MockRow) {
+				tx.On("QueryRow", mock.Anything, lockHoldQuery, mock.Anything).Return(r).Once()
+				r.On("Scan", mock.Anything).Return(pgx.ErrNoRows).Once()
+			},
+			WaitingError: wallet.ErrHoldNotFound,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			mockPool := new(MockPool)
+			mockTx := new(MockTx)
+			mockRow := new(MockRow)
+			mockPool.On("Begin", mock.Anything).Return(mockTx, nil)
+			mockTx.On("Rollback", mock.Anything).Return(nil)
+			test.Mock(mockTx, mockRow)
+
+			hold, err := newHoldRepository(mockPool).CaptureHold(context.Background(), active.ID, test.Amount)
+			if test.WaitingError != nil {
+				assert.ErrorIs(t, err, test.WaitingError)
+				assert.Nil(t, hold)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, wallet.HoldStatusCaptured, hold.Status)
+				assert.Equal(t, test.WaitingCaptured, hold.Captured)
+			}
+			mockPool.AssertExpectations(t)
+			mockTx.AssertExpectations(t)
+			mockRow.AssertExpectations(t)
+		})
+	}
+}
+
+func TestWalletRepository_ReleaseHold(t *testing.T) {
+	active := wallet.Hold{ID: uuid.New(), WalletID: uuid.New(), Amount: 100, Status: wallet.HoldStatusActive}
+
+	t.Run("Success Test", func(t *testing.T) {
+		mockPool := new(MockPool)
+		mockTx := new(MockTx)
+		mockRow := new(MockRow)
+		mockPool.On("Begin", mock.Anything).Return(mockTx, nil)
+		mockTx.On("QueryRow", mock.Anything, lockHoldQuery, []interface{}{active.ID}).Return(holdRow(active, true)).Once()
+		mockTx.On("QueryRow", mock.Anything, releaseQuery, []interface{}{int64(100), active.WalletID}).Return(mockRow).Once()
+		mockTx.On("QueryRow", mock.Anything, ledgerQuery, ledgerEntry(wallet.OperationRelease, 0, -100)).Return(mockRow).Once()
+		mockRow.On("Scan", mock.Anything).Return(nil).Twice()
+		mockTx.On("Exec", mock.Anything, closeQuery, []interface{}{wallet.HoldStatusReleased, int64(0), active.ID}).Return(pgconn.CommandTag{}, nil).Once()
+		mockTx.On("Commit", mock.Anything).Return(nil)
+		mockTx.On("Rollback", mock.Anything).Return(pgx.ErrTxClosed)
+
+		hold, err := newHoldRepository(mockPool).ReleaseHold(context.Background(), active.ID)
+		assert.NoError(t, err)
+		assert.Equal(t, wallet.HoldStatusReleased, hold.Status)
+		mockTx.AssertExpectations(t)
+		mockRow.AssertExpectations(t)
+	})
+
+	t.Run("Release Error Test", func(t *testing.T) {
+		mockPool := new(MockPool)
+		mockTx := new(MockTx)
+		mockRow := new(MockRow)
+		mockPool.On("Begin", mock.Anything).Return(mockTx, nil)
+		mockTx.On("QueryRow", mock.Anything, lockHoldQuery, mock.Anything).Return(holdRow(active, false)).Once()
+		mockTx.On("QueryRow", mock.Anything, releaseQuery, mock.Anything).Return(mockRow).Once()
+		mockRow.On("Scan", mock.Anything).Return(errors.New("error")).Once()
+		mockTx.On("Rollback", mock.Anything).Return(nil)
+
+		hold, err := newHoldRepository(mockPool).ReleaseHold(context.Background(), active.ID)
+		assert.EqualError(t, err, customerror.NewError("walletRepo.ReleaseHold", "127.0.0.1:8080", "error").Error())
+		assert.Nil(t, hold)
+		mockTx.AssertExpectations(t)
+	})
+}
+
+func TestWalletRepository_GetHold(t *testing.T) {
+	mockPool := new(MockPool)
+	mockRow := new(MockRow)
+	mockPool.On("QueryRow", mock.Anything, mock.Anything, mock.Anything).Return(mockRow)
+	mockRow.On("Scan", mock.Anything).Return(pgx.ErrNoRows)
+
+	hold, err := newHoldRepository(mockPool).GetHold(context.Background(), uuid.New())
+	assert.ErrorIs(t, err, wallet.ErrHoldNotFound)
+	assert.Nil(t, hold)
+	mockPool.AssertExpectations(t)
+}
+
+func TestWalletRepository_ReleaseExpiredHolds(t *testing.T) {
+	expiredQuery := "SELECT h.id, h.wallet_id, h.amount, h.captured, h.status, h.expires_at, h.created_at, c.code, c.minor_units " +
+		"FROM holds h JOIN wallet w ON w.id = h.wallet_id JOIN currencies c ON c.code = w.currency " +
+		"WHERE h.status = $1 AND h.expires_at <= now() ORDER BY h.wallet_id, h.id LIMIT $2 FOR UPDATE OF h SKIP LOCKED"
+	expired := []wallet.Hold{
+		{ID: uuid.New(), WalletID: uuid.New(), Amount: 100, Status: wallet.HoldStatusActive},
+		{ID: uuid.New(), WalletID: uuid.New(), Amount: 250, Status: wallet.HoldStatusActive},
+	}
+
+	t.Run("Success Test", func(t *testing.T) {
+		mockPool := new(MockPool)
+		mockTx := new(MockTx)
+		mockRows := new(MockRows)
+		mockRow := new(MockRow)
+		mockPool.On("Begin", mock.Anything).Return(mockTx, nil)
+		mockTx.On("Query", mock.Anything, expiredQuery, []interface{}{wallet.HoldStatusActive, 10}).Return(mockRows, nil)
+		mockRows.On("Next").Return(true).Twice()
+		mockRows.On("Next").Return(false).Once()
+		for _, hold := range expired {
+			mockRows.On("Scan", mock.Anything).Run(func(args mock.Arguments) {
+				dest := args.Get(0).([]interface{})
+				*dest[0].(*uuid.UUID) = hold.ID
+				*dest[1].(*uuid.UUID) = hold.WalletID
+				*dest[2].(*int64) = hold.Amount
+				*dest[4].(*string) = hold.Status
+			}).Return(nil).Once()
+			mockTx.On("QueryRow", mock.Anything, releaseQuery, []interface{}{hold.Amount, hold.WalletID}).Return(mockRow).Once()
+			mockTx.On("QueryRow", mock.Anything, ledgerQuery, ledgerEntry(wallet.OperationRelease, 0, -hold.Amount)).Return(mockRow).Once()
+			mockTx.On("Exec", mock.Anything, closeQuery, []interface{}{wallet.HoldStatusReleased, int64(0), hold.ID}).Return(pgconn.CommandTag{}, nil).Once()
+		}
+		mockRows.On("Close").Return()
+		mockRows.On("Err").Return(nil)
+		mockRow.On("Scan", mock.Anything).Return(nil).Times(4)
+		mockTx.On("Commit", mock.Anything).Return(nil)
+		mockTx.On("Rollback", mock.Anything).Return(pgx.ErrTxClosed)
+
+		holds, err := newHoldRepository(mockPool).ReleaseExpiredHolds(context.Background(), 10)
+		assert.NoError(t, err)
+		assert.Len(t, holds, 2)
+		for i, hold := range holds {
+			assert.Equal(t, expired[i].ID, hold.ID)
+			assert.Equal(t, wallet.HoldStatusReleased, hold.Status)
+		}
+		mockTx.AssertExpectations(t)
+		mockRows.AssertExpectations(t)
+		mockRow.AssertExpectations(t)
+	})
+
+	t.Run("Release Error Test", func(t *testing.T) {
+		mockPool := new(MockPool)
+		mockTx := new(MockTx)
+		mockRows := new(MockRows)
+		mockRow := new(MockRow)
+		mockPool.On("Begin", mock.Anything).Return(mockTx, nil)
+		mockTx.On("Query", mock.Anything, expiredQuery, mock.Anything).Return(mockRows, nil)
+		mockRows.On("Next").Return(true).Once()
+		mockRows.On("Next").Return(false).Once()
+		mockRows.On("Scan", mock.Anything).Return(nil).Once()
+		mockRows.On("Close").Return()
+		mockRows.On("Err").Return(nil)
+		mockTx.On("QueryRow", mock.Anything, releaseQuery, mock.Anything).Return(mockRow).Once()
+		mockRow.On("Scan", mock.Anything).Return(errors.New("error")).Once()
+		mockTx.On("Rollback", mock.Anything).Return(nil)
+
+		holds, err := newHoldRepository(mockPool).ReleaseExpiredHolds(context.Background(), 10)
+		assert.EqualError(t, err, customerror.NewError("walletRepo.ReleaseExpiredHolds", "127.0.0.1:8080", "error").Error())
+		assert.Nil(t, holds)
+		mockTx.AssertExpectations(t)
+	})
+}
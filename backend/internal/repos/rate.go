@@ -0,0 +1,109 @@
+package repos
+
+import (
+	"backend/internal/tracing"
+	"backend/pkg/config"
+	"backend/pkg/customerror"
+	"backend/pkg/wallet"
+	"context"
+	"log/slog"
+
+	"github.com/jackc/pgx/v5"
+	"go.opentelemetry.io/otel/trace"
+)
+
+type RateRepositoryI interface {
+	CreateRates(ctx context.Context, rates []wallet.ExchangeRate) error
+	ListCurrentRates(ctx context.Context) ([]wallet.ExchangeRate, error)
+}
+
+type RateRepository struct {
+	Pool   PoolInterface
+	Host   string
+	Port   string
+	Logger *slog.Logger
+	Tracer trace.Tracer
+}
+
+func NewRateRepository(pool PoolInterface, appConfig *config.Config, logger *slog.Logger, tracerProvider trace.TracerProvider) RateRepositoryI {
+	return &RateRepository{
+		Pool:   pool,
+		Host:   appConfig.WebHost,
+		Port:   appConfig.WebPort,
+		Logger: logger,
+		Tracer: tracerProvider.Tracer("backend/internal/repos"),
+	}
+}
+
+// The rate is read back as text so it keeps every digit it was loaded with.
+const rateColumns = "id, base_currency, quote_currency, rate::text, valid_from, valid_to, source, created_at"
+
+func scanRate(row pgx.Row) (*wallet.ExchangeRate, error) {
+	var rate wallet.ExchangeRate
+	err := row.Scan(&rate.ID, &rate.Base, &rate.Quote, &rate.Rate, &rate.ValidFrom, &rate.ValidTo, &rate.Source, &rate.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &rate, nil
+}
+
+// CreateRates stores rates in one database transaction, so an import either
+// loads every row or none.
+func (rateRepo *RateRepository) CreateRates(ctx context.Context, rates []wallet.ExchangeRate) (err error) {
+	ctx, span := rateRepo.Tracer.Start(ctx, "rateRepo.CreateRates", trace.WithAttributes(tracing.RowCountKey.Int(len(rates))))
+	defer func() { tracing.End(span, err) }()
+
+	tx, err := rateRepo.Pool.Begin(ctx)
+	if err != nil {
+		return customerror.Wrap(err, "rateRepo.CreateRates", rateRepo.Host+":"+rateRepo.Port)
+	}
+	defer tx.Rollback(ctx)
+
+	insertQuery := `INSERT INTO rates (id, base_currency, quote_currency, rate, valid_from, valid_to, source)
+	VALUES ($1, $2, $3, $4::numeric, $5, $6, $7) RETURNING created_at`
+	for i := range rates {
+		rate := &rates[i]
+		err = tx.QueryRow(ctx, insertQuery, rate.ID, rate.Base, rate.Quote, rate.Rate, rate.ValidFrom, rate.ValidTo, rate.Source).Scan(&rate.CreatedAt)
+		if pgErrorCode(err) == foreignKeyViolation {
+			return wallet.ErrUnknownCurrency
+		}
+		if err != nil {
+			return customerror.Wrap(err, "rateRepo.CreateRates", rateRepo.Host+":"+rateRepo.Port)
+		}
+	}
+	err = tx.Commit(ctx)
+	if err != nil {
+		return customerror.Wrap(err, "rateRepo.CreateRates", rateRepo.Host+":"+rateRepo.Port)
+	}
+	return nil
+}
+
+// ListCurrentRates returns the rate transfers would use right now for every
+// pair that has one, ordered by pair.
+func (rateRepo *RateRepository) ListCurrentRates(ctx context.Context) (_ []wallet.ExchangeRate, err error) {
+	ctx, span := rateRepo.Tracer.Start(ctx, "rateRepo.ListCurrentRates")
+	defer func() { tracing.End(span, err) }()
+
+	selectQuery := `SELECT DISTINCT ON (base_currency, quote_currency) ` + rateColumns + ` FROM rates
+	WHERE valid_from <= now() AND (valid_to IS NULL OR valid_to > now())
+	ORDER BY base_currency, quote_currency, valid_from DESC, created_at DESC`
+	rows, err := rateRepo.Pool.Query(ctx, selectQuery)
+	if err != nil {
+		return nil, customerror.Wrap(err, "rateRepo.ListCurrentRates", rateRepo.Host+":"+rateRepo.Port)
+	}
+	defer rows.Close()
+
+	rates := []wallet.ExchangeRate{}
+	defer func() { span.SetAttributes(tracing.RowCountKey.Int(len(rates))) }()
+	for rows.Next() {
+		rate, err := scanRate(rows)
+		if err != nil {
+			return nil, customerror.Wrap(err, "rateRepo.ListCurrentRates", rateRepo.Host+":"+rateRepo.Port)
+		}
+		rates = append(rates, *rate)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, customerror.Wrap(err, "rateRepo.ListCurrentRates", rateRepo.Host+":"+rateRepo.Port)
+	}
+	return rates, nil
+}
@@ -0,0 +1,165 @@
+package repos_test
+
+import (
+	"backend/internal/repos"
+	"backend/pkg/customerror"
+	"backend/pkg/wallet"
+	"context"
+	"errors"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+func newRateRepository(pool *MockPool) *repos.RateRepository {
+	return &repos.RateRepository{
+		Pool:   pool,
+		Host:   "127.0.0.1",
+		Port:   "8080",
+		Logger: slog.New(slog.DiscardHandler),
+		Tracer: noop.NewTracerProvider().Tracer(""),
+	}
+}
+
+type CreateRatesTest struct {
+	Name         string
+	Mock         func(*MockTx, *MockRow)
+	WaitingError error
+}
+
+func TestRateRepository_CreateRates(t *testing.T) {
+	validFrom := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	createdAt := time.Date(2026, 1, 2, 0, 0, 0, 0, time.UTC)
+	rates := []wallet.ExchangeRate{
+		{ID: uuid.New(), Base: "USD", Quote: "EUR", Rate: "0.92", ValidFrom: validFrom, Source: "ecb"},
+		{ID: uuid.New(), Base: "EUR", Quote: "USD", Rate: "1.087", ValidFrom: validFrom, Source: "ecb"},
+	}
+
+	tests := []CreateRatesTest{
+		{
+			Name: "Success Test",
+			Mock: func(tx *MockTx, r *MockRow) {
+				tx.On("QueryRow", mock.Anything, mock.Anything, []interface{}{rates[0].ID, "USD", "EUR", "0.92", validFrom, (*time.Time)(nil), "ecb"}).Return(r).Once()
+				tx.On("QueryRow", mock.Anything, mock.Anything, []interface{}{rates[1].ID, "EUR", "USD", "1.087", validFrom, (*time.Time)(nil), "ecb"}).Return(r).Once()
+				r.On("Scan", mock.Anything).Run(func(args mock.Arguments) {
+					*args.Get(0).([]interface{})[0].(*time.Time) = createdAt
+				}).Return(nil).Twice()
+				tx.On("Commit", mock.Anything).Return(nil).Once()
+			},
+		},
+		{
+			Name: "Unknown Currency Test",
+			Mock: func(tx *MockTx, r *MockRow) {
+				tx.On("QueryRow", mock.Anything, mock.Anything, mock.Anything).Return(r).Once()
+				r.On("Scan", mock.Anything).Return(&pgconn.PgError{Code: "23503"}).Once()
+			},
+			WaitingError: wallet.ErrUnknownCurrency,
+		},
+		{
+			Name: "Commit Error Test",
+			Mock: func(tx *MockTx, r *MockRow) {
+				tx.On("QueryRow", mock.Anything, mock.Anything, mock.Anything).Return(r).Twice()
+				r.On("Scan", mock.Anything).Return(nil).Twice()
+				tx.On("Commit", mock.Anything).Return(errors.New("error")).Once()
+			},
+			WaitingError: customerror.NewError("rateRepo.CreateRates", "127.0.0.1:8080", "error"),
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			mockPool := new(MockPool)
+			mockTx := new(MockTx)
+			mockRow := new(MockRow)
+			mockPool.On("Begin", mock.Anything).Return(mockTx, nil).Once()
+			mockTx.On("Rollback", mock.Anything).Return(nil).Maybe()
+			test.Mock(mockTx, mockRow)
+			repo := newRateRepository(mockPool)
+
+			created := append([]wallet.ExchangeRate(nil), rates...)
+			err := repo.CreateRates(context.Background(), created)
+			if test.WaitingError != nil {
+				assert.ErrorIs(t, err, test.WaitingError)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, createdAt, created[0].CreatedAt)
+				assert.Equal(t, createdAt, created[1].CreatedAt)
+			}
+			mockPool.AssertExpectations(t)
+			mockTx.AssertExpectations(t)
+			mockRow.AssertExpectations(t)
+		})
+	}
+}
+
+type ListCurrentRatesTest struct {
+	Name         string
+	Mock         func(*MockPool, *MockRows)
+	WaitingRates []wallet.ExchangeRate
+	WaitingError error
+}
+
+func TestRateRepository_ListCurrentRates(t *testing.T) {
+	testRate := wallet.ExchangeRate{
+		ID:        uuid.New(),
+		Base:      "USD",
+		Quote:     "JPY",
+		Rate:      "151.2345",
+		ValidFrom: time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC),
+		Source:    "manual",
+	}
+
+	tests := []ListCurrentRatesTest{
+		{
+			Name: "Success Test",
+			Mock: func(p *MockPool, r *MockRows) {
+				p.On("Query", mock.Anything, mock.Anything, []interface{}(nil)).Return(r, nil)
+				r.On("Next").Return(true).Once()
+				r.On("Scan", mock.Anything).Run(func(args mock.Arguments) {
+					dest := args.Get(0).([]interface{})
+					*dest[0].(*uuid.UUID) = testRate.ID
+					*dest[1].(*string) = testRate.Base
+					*dest[2].(*string) = testRate.Quote
+					*dest[3].(*string) = testRate.Rate
+					*dest[4].(*time.Time) = testRate.ValidFrom
+					*dest[6].(*string) = testRate.Source
+				}).Return(nil).Once()
+				r.On("Next").Return(false).Once()
+				r.On("Err").Return(nil)
+				r.On("Close").Return()
+			},
+			WaitingRates: []wallet.ExchangeRate{testRate},
+		},
+		{
+			Name: "Query Error Test",
+			Mock: func(p *MockPool, r *MockRows) {
+				p.On("Query", mock.Anything, mock.Anything, mock.Anything).Return((*MockRows)(nil), errors.New("error"))
+			},
+			WaitingError: customerror.NewError("rateRepo.ListCurrentRates", "127.0.0.1:8080", "error"),
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			mockPool := new(MockPool)
+			mockRows := new(MockRows)
+			test.Mock(mockPool, mockRows)
+			repo := newRateRepository(mockPool)
+
+			rates, err := repo.ListCurrentRates(context.Background())
+			if test.WaitingError != nil {
+				assert.ErrorIs(t, err, test.WaitingError)
+				assert.Nil(t, rates)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, test.WaitingRates, rates)
+			}
+			mockPool.AssertExpectations(t)
+			mockRows.AssertExpectations(t)
+		})
+	}
+}
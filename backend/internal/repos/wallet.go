@@ -1,112 +1,450 @@
 package repos
 
 import (
+	"backend/internal/tracing"
 	"backend/pkg/config"
 	"backend/pkg/customerror"
 	"backend/pkg/wallet"
+	"bytes"
 	"context"
+	"errors"
 	"fmt"
+	"log/slog"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgxpool"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// SQLSTATE codes the repository translates into domain errors.
+const (
+	uniqueViolation     = "23505"
+	checkViolation      = "23514"
+	foreignKeyViolation = "23503"
+	// numericValueOutOfRange is raised when a balance leaves the BIGINT range.
+	numericValueOutOfRange = "22003"
 )
 
 type WalletRepositoryI interface {
-	CreateTables(ctx context.Context) error
+	CreateWallet(ctx context.Context, id uuid.UUID, amount int64, currency string, ownerID *string) (*wallet.Wallet, error)
 	GetWallet(ctx context.Context, id uuid.UUID) (*wallet.Wallet, error)
-	UpdateWallet(ctx context.Context, id uuid.UUID, delta int64) error
+	GetCurrency(ctx context.Context, code string) (*wallet.Currency, error)
+	UpdateWallet(ctx context.Context, id uuid.UUID, operationType string, delta int64, idempotencyKey *wallet.IdempotencyKey) (*wallet.Transaction, error)
+	ListTransactions(ctx context.Context, filter wallet.TransactionFilter) ([]wallet.Transaction, error)
+	Transfer(ctx context.Context, fromID uuid.UUID, toID uuid.UUID, amount int64) (*wallet.Transfer, error)
+	CreateHold(ctx context.Context, id uuid.UUID, walletID uuid.UUID, amount int64, expiresAt time.Time) (*wallet.Hold, error)
+	GetHold(ctx context.Context, id uuid.UUID) (*wallet.Hold, error)
+	CaptureHold(ctx context.Context, id uuid.UUID, amount *int64) (*wallet.Hold, error)
+	ReleaseHold(ctx context.Context, id uuid.UUID) (*wallet.Hold, error)
+	ReleaseExpiredHolds(ctx context.Context, limit int) ([]wallet.Hold, error)
 	ClosePull()
 }
 
 type PoolInterface interface {
 	Exec(ctx context.Context, sql string, arguments ...interface{}) (pgconn.CommandTag, error)
 	QueryRow(ctx context.Context, sql string, args ...interface{}) pgx.Row
+	Query(ctx context.Context, sql string, args ...interface{}) (pgx.Rows, error)
+	Begin(ctx context.Context) (pgx.Tx, error)
+	Ping(ctx context.Context) error
+	Stat() *pgxpool.Stat
 	Close()
 }
 
 type WalletRepository struct {
-	Pool PoolInterface
-	Host string
-	Port string
+	Pool   PoolInterface
+	Host   string
+	Port   string
+	Logger *slog.Logger
+	Tracer trace.Tracer
 }
 
-func NewWalletRepository(appConfig *config.Config) (WalletRepositoryI, error) {
+// NewPool connects to the database described by appConfig. The pool is shared
+// by the repository and the migrator, and traces every query it runs.
+func NewPool(appConfig *config.Config, tracerProvider trace.TracerProvider) (*pgxpool.Pool, error) {
 	connStr := fmt.Sprintf("postgres://%s:%s@%s:%s/%s", appConfig.DbUser, appConfig.DbPassword, appConfig.DbHost, appConfig.DbPort, appConfig.DbName)
 	config, err := pgxpool.ParseConfig(connStr)
 	if err != nil {
-		return &WalletRepository{}, customerror.NewError("NewWalletRepository", appConfig.WebHost+":"+appConfig.WebPort, err.Error())
+		return nil, customerror.Wrap(err, "NewPool", appConfig.WebHost+":"+appConfig.WebPort)
 	}
 	config.MaxConns = 100
 	config.MinConns = 10
 	config.MaxConnLifetime = 1 * time.Hour
 	config.MaxConnIdleTime = 15 * time.Minute
+	config.ConnConfig.Tracer = tracing.NewQueryTracer(tracerProvider)
 	pool, err := pgxpool.NewWithConfig(context.Background(), config)
 	if err != nil {
-		return &WalletRepository{}, customerror.NewError("NewWalletRepository", appConfig.WebHost+":"+appConfig.WebPort, err.Error())
+		return nil, customerror.Wrap(err, "NewPool", appConfig.WebHost+":"+appConfig.WebPort)
 	}
 
 	if err := pool.Ping(context.Background()); err != nil {
 		pool.Close()
-		return &WalletRepository{}, customerror.NewError("NewWalletRepository", appConfig.WebHost+":"+appConfig.WebPort, err.Error())
+		return nil, customerror.Wrap(err, "NewPool", appConfig.WebHost+":"+appConfig.WebPort)
 	}
+	return pool, nil
+}
+
+func NewWalletRepository(pool PoolInterface, appConfig *config.Config, logger *slog.Logger, tracerProvider trace.TracerProvider) WalletRepositoryI {
 	return &WalletRepository{
-		Pool: pool,
-		Host: appConfig.WebHost,
-		Port: appConfig.WebPort,
-	}, nil
-}
-
-func (walletRepo *WalletRepository) CreateTables(ctx context.Context) error {
-	createTableQuery := `
-	CREATE TABLE IF NOT EXISTS wallet (
-		id UUID PRIMARY KEY,
-		amount BIGINT NOT NULL DEFAULT 0 CHECK (amount >= 0)
-	);`
-	_, err := walletRepo.Pool.Exec(ctx, createTableQuery)
+		Pool:   pool,
+		Host:   appConfig.WebHost,
+		Port:   appConfig.WebPort,
+		Logger: logger,
+		Tracer: tracerProvider.Tracer("backend/internal/repos"),
+	}
+}
+
+func (walletRepo *WalletRepository) CreateWallet(ctx context.Context, id uuid.UUID, amount int64, currency string, ownerID *string) (_ *wallet.Wallet, err error) {
+	ctx, span := walletRepo.Tracer.Start(ctx, "walletRepo.CreateWallet", trace.WithAttributes(tracing.WalletIDKey.String(id.String())))
+	defer func() { tracing.End(span, err) }()
+
+	var createdWallet wallet.Wallet
+	insertQuery := `WITH created AS (
+		INSERT INTO wallet (id, amount, currency, owner_id) VALUES ($1, $2, $3, $4) RETURNING id, amount, owner_id, currency
+	)
+	SELECT created.id, created.amount, created.owner_id, created.currency, currencies.minor_units
+	FROM created JOIN currencies ON currencies.code = created.currency`
+	err = walletRepo.Pool.QueryRow(ctx, insertQuery, id, amount, currency, ownerID).Scan(
+		&createdWallet.ID, &createdWallet.Amount, &createdWallet.OwnerID, &createdWallet.Currency.Code, &createdWallet.Currency.MinorUnits,
+	)
+	if err == nil {
+		return &createdWallet, nil
+	}
+	switch pgErrorCode(err) {
+	case uniqueViolation:
+		return nil, wallet.ErrAlreadyExists
+	case checkViolation:
+		return nil, customerror.ErrInvalidAmount
+	case foreignKeyViolation:
+		return nil, wallet.ErrUnknownCurrency
+	}
+	return nil, customerror.Wrap(err, "walletRepo.CreateWallet", walletRepo.Host+":"+walletRepo.Port)
+}
+
+func (walletRepo *WalletRepository) GetWallet(ctx context.Context, id uuid.UUID) (_ *wallet.Wallet, err error) {
+	ctx, span := walletRepo.Tracer.Start(ctx, "walletRepo.GetWallet", trace.WithAttributes(tracing.WalletIDKey.String(id.String())))
+	defer func() { tracing.End(span, err) }()
+
+	var foundWallet wallet.Wallet
+	selectQuery := `SELECT w.id, w.amount, w.held, w.owner_id, w.currency, c.minor_units
+	FROM wallet w JOIN currencies c ON c.code = w.currency WHERE w.id = $1`
+	err = walletRepo.Pool.QueryRow(ctx, selectQuery, id).Scan(
+		&foundWallet.ID, &foundWallet.Amount, &foundWallet.Held, &foundWallet.OwnerID, &foundWallet.Currency.Code, &foundWallet.Currency.MinorUnits,
+	)
+	if err == nil {
+		return &foundWallet, nil
+	}
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, wallet.ErrNotFound
+	}
+	return nil, customerror.Wrap(err, "walletRepo.GetWallet", walletRepo.Host+":"+walletRepo.Port)
+}
+
+func (walletRepo *WalletRepository) GetCurrency(ctx context.Context, code string) (_ *wallet.Currency, err error) {
+	ctx, span := walletRepo.Tracer.Start(ctx, "walletRepo.GetCurrency")
+	defer func() { tracing.End(span, err) }()
+
+	var currency wallet.Currency
+	selectQuery := "SELECT code, minor_units FROM currencies WHERE code = $1"
+	err = walletRepo.Pool.QueryRow(ctx, selectQuery, code).Scan(&currency.Code, &currency.MinorUnits)
+	if err == nil {
+		return &currency, nil
+	}
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, wallet.ErrUnknownCurrency
+	}
+	return nil, customerror.Wrap(err, "walletRepo.GetCurrency", walletRepo.Host+":"+walletRepo.Port)
+}
+
+// UpdateWallet applies delta to the wallet balance and journals it. When an
+// idempotency key is given it is claimed in the same database transaction, and
+// a key that was already claimed replays the transaction it produced instead.
+func (walletRepo *WalletRepository) UpdateWallet(ctx context.Context, id uuid.UUID, operationType string, delta int64, idempotencyKey *wallet.IdempotencyKey) (_ *wallet.Transaction, err error) {
+	ctx, span := walletRepo.Tracer.Start(ctx, "walletRepo.UpdateWallet", trace.WithAttributes(
+		tracing.WalletIDKey.String(id.String()),
+		tracing.OperationTypeKey.String(operationType),
+	))
+	defer func() { tracing.End(span, err) }()
+
+	tx, err := walletRepo.Pool.Begin(ctx)
+	if err != nil {
+		return nil, customerror.Wrap(err, "walletRepo.UpdateWallet", walletRepo.Host+":"+walletRepo.Port)
+	}
+	defer tx.Rollback(ctx)
+
+	if idempotencyKey != nil {
+		claimQuery := "INSERT INTO idempotency_keys (client_id, key, request_hash) VALUES ($1, $2, $3) ON CONFLICT (client_id, key) DO NOTHING"
+		command, err := tx.Exec(ctx, claimQuery, idempotencyKey.ClientID, idempotencyKey.Key, idempotencyKey.RequestHash)
+		if err != nil {
+			return nil, customerror.Wrap(err, "walletRepo.UpdateWallet", walletRepo.Host+":"+walletRepo.Port)
+		}
+		if command.RowsAffected() == 0 {
+			return walletRepo.replayTransaction(ctx, tx, idempotencyKey)
+		}
+	}
+
+	transaction := wallet.Transaction{
+		ID:            uuid.New(),
+		WalletID:      id,
+		OperationType: operationType,
+		Amount:        delta,
+	}
+	updateQuery := "UPDATE wallet SET amount = amount + $1 WHERE id = $2 RETURNING amount"
+	err = tx.QueryRow(ctx, updateQuery, delta, id).Scan(&transaction.Balance)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, wallet.ErrNotFound
+		}
+		switch pgErrorCode(err) {
+		case checkViolation:
+			return nil, customerror.ErrWrongAmount
+		case numericValueOutOfRange:
+			return nil, customerror.ErrAmountOutOfRange
+		}
+		return nil, customerror.Wrap(err, "walletRepo.UpdateWallet", walletRepo.Host+":"+walletRepo.Port)
+	}
+	err = insertTransaction(ctx, tx, &transaction)
 	if err != nil {
-		return customerror.NewError("walletRepo.CreateTables", walletRepo.Host+":"+walletRepo.Port, err.Error())
+		return nil, customerror.Wrap(err, "walletRepo.UpdateWallet", walletRepo.Host+":"+walletRepo.Port)
 	}
-	createIndexQuery := `CREATE INDEX IF NOT EXISTS wallet_id_idx ON wallet(id);`
-	_, err = walletRepo.Pool.Exec(ctx, createIndexQuery)
+	if idempotencyKey != nil {
+		outcomeQuery := "UPDATE idempotency_keys SET transaction_id = $1 WHERE client_id = $2 AND key = $3"
+		_, err = tx.Exec(ctx, outcomeQuery, transaction.ID, idempotencyKey.ClientID, idempotencyKey.Key)
+		if err != nil {
+			return nil, customerror.Wrap(err, "walletRepo.UpdateWallet", walletRepo.Host+":"+walletRepo.Port)
+		}
+	}
+	err = tx.Commit(ctx)
 	if err != nil {
-		return customerror.NewError("walletRepo.CreateTables", walletRepo.Host+":"+walletRepo.Port, err.Error())
+		return nil, customerror.Wrap(err, "walletRepo.UpdateWallet", walletRepo.Host+":"+walletRepo.Port)
 	}
-	return nil
+	return &transaction, nil
 }
 
-func (walletRepo *WalletRepository) GetWallet(ctx context.Context, id uuid.UUID) (*wallet.Wallet, error) {
-	var wallet wallet.Wallet
-	selectQuery := "SELECT id, amount FROM wallet WHERE id = $1"
-	err := walletRepo.Pool.QueryRow(ctx, selectQuery, id).Scan(&wallet.ID, &wallet.Amount)
-	if err == nil {
-		return &wallet, nil
+func (walletRepo *WalletRepository) replayTransaction(ctx context.Context, tx pgx.Tx, idempotencyKey *wallet.IdempotencyKey) (*wallet.Transaction, error) {
+	var requestHash string
+	transaction := wallet.Transaction{Replayed: true}
+	selectQuery := `SELECT k.request_hash, t.id, t.wallet_id, t.operation_type, t.amount, t.balance, t.transfer_id, t.rate_id, t.exchange_rate::text, t.hold_id, t.held_amount, t.created_at
+	FROM idempotency_keys k JOIN wallet_transactions t ON t.id = k.transaction_id WHERE k.client_id = $1 AND k.key = $2`
+	err := tx.QueryRow(ctx, selectQuery, idempotencyKey.ClientID, idempotencyKey.Key).Scan(
+		&requestHash, &transaction.ID, &transaction.WalletID, &transaction.OperationType, &transaction.Amount,
+		&transaction.Balance, &transaction.TransferID, &transaction.RateID, &transaction.ExchangeRate, &transaction.HoldID, &transaction.HeldAmount,
+		&transaction.CreatedAt,
+	)
+	if err != nil {
+		return nil, customerror.Wrap(err, "walletRepo.replayTransaction", walletRepo.Host+":"+walletRepo.Port)
 	}
-	if err == pgx.ErrNoRows {
-		return nil, err
+	if requestHash != idempotencyKey.RequestHash {
+		walletRepo.Logger.WarnContext(ctx, "idempotency key reused with a different request", slog.String("idempotency_key", idempotencyKey.Key))
+		return nil, customerror.ErrIdempotencyKeyReused
 	}
-	return nil, customerror.NewError("walletRepo.GetWallet", walletRepo.Host+":"+walletRepo.Port, err.Error())
+	walletRepo.Logger.DebugContext(ctx, "replaying idempotent request",
+		slog.String("idempotency_key", idempotencyKey.Key),
+		slog.String("transaction_id", transaction.ID.String()),
+	)
+	return &transaction, nil
 }
 
-func (walletRepo *WalletRepository) UpdateWallet(ctx context.Context, id uuid.UUID, delta int64) error {
-	updateQuery := "UPDATE wallet set amount = amount + $1 WHERE id = $2"
-	command, err := walletRepo.Pool.Exec(ctx, updateQuery, delta, id)
+// Transfer moves amount between two wallets in one database transaction. Both
+// rows are locked in ascending id order before either balance changes, so two
+// opposite transfers between the same wallets wait for each other instead of
+// deadlocking. Between wallets of different currencies amount is debited in
+// the source currency and credited converted at the rate in effect when the
+// transaction started; both legs record that rate.
+func (walletRepo *WalletRepository) Transfer(ctx context.Context, fromID uuid.UUID, toID uuid.UUID, amount int64) (_ *wallet.Transfer, err error) {
+	ctx, span := walletRepo.Tracer.Start(ctx, "walletRepo.Transfer", trace.WithAttributes(
+		tracing.WalletIDKey.String(fromID.String()),
+		tracing.ToWalletIDKey.String(toID.String()),
+	))
+	defer func() { tracing.End(span, err) }()
+
+	tx, err := walletRepo.Pool.Begin(ctx)
 	if err != nil {
-		if pgErr, ok := err.(*pgconn.PgError); ok {
-			if pgErr.Code == "23514" {
-				return customerror.ErrWrongAmount
+		return nil, customerror.Wrap(err, "walletRepo.Transfer", walletRepo.Host+":"+walletRepo.Port)
+	}
+	defer tx.Rollback(ctx)
+
+	lockOrder := []uuid.UUID{fromID, toID}
+	if bytes.Compare(fromID[:], toID[:]) > 0 {
+		lockOrder = []uuid.UUID{toID, fromID}
+	}
+	lockQuery := "SELECT w.currency, c.minor_units, w.owner_id FROM wallet w JOIN currencies c ON c.code = w.currency WHERE w.id = $1 FOR UPDATE OF w"
+	currencies := make(map[uuid.UUID]wallet.Currency, len(lockOrder))
+	owners := make(map[uuid.UUID]*string, len(lockOrder))
+	for _, id := range lockOrder {
+		var currency wallet.Currency
+		var ownerID *string
+		err = tx.QueryRow(ctx, lockQuery, id).Scan(&currency.Code, &currency.MinorUnits, &ownerID)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, wallet.ErrNotFound
+		}
+		if err != nil {
+			return nil, customerror.Wrap(err, "walletRepo.Transfer", walletRepo.Host+":"+walletRepo.Port)
+		}
+		currencies[id] = currency
+		owners[id] = ownerID
+	}
+	credit := amount
+	var rate *wallet.ExchangeRate
+	if currencies[fromID].Code != currencies[toID].Code {
+		rate, err = findRate(ctx, tx, currencies[fromID].Code, currencies[toID].Code)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, wallet.ErrExchangeRateNotFound
+		}
+		if err != nil {
+			return nil, customerror.Wrap(err, "walletRepo.Transfer", walletRepo.Host+":"+walletRepo.Port)
+		}
+		value, err := wallet.ParseRate(rate.Rate)
+		if err != nil {
+			return nil, customerror.Wrap(err, "walletRepo.Transfer", walletRepo.Host+":"+walletRepo.Port)
+		}
+		credit, err = wallet.Convert(amount, value, currencies[fromID], currencies[toID])
+		if err != nil {
+			return nil, err
+		}
+		span.SetAttributes(tracing.ExchangeRateIDKey.String(rate.ID.String()))
+	}
+
+	transferID := uuid.New()
+	transfer := wallet.Transfer{
+		ID:           transferID,
+		FromCurrency: currencies[fromID],
+		ToCurrency:   currencies[toID],
+		ToOwnerID:    owners[toID],
+		Debit: wallet.Transaction{
+			ID:            uuid.New(),
+			WalletID:      fromID,
+			OperationType: wallet.OperationTransferOut,
+			Amount:        -amount,
+			TransferID:    &transferID,
+		},
+		Credit: wallet.Transaction{
+			ID:            uuid.New(),
+			WalletID:      toID,
+			OperationType: wallet.OperationTransferIn,
+			Amount:        credit,
+			TransferID:    &transferID,
+		},
+	}
+	if rate != nil {
+		for _, transaction := range []*wallet.Transaction{&transfer.Debit, &transfer.Credit} {
+			transaction.RateID = &rate.ID
+			transaction.ExchangeRate = &rate.Rate
+		}
+	}
+	updateQuery := "UPDATE wallet SET amount = amount + $1 WHERE id = $2 RETURNING amount"
+	for _, transaction := range []*wallet.Transaction{&transfer.Debit, &transfer.Credit} {
+		err = tx.QueryRow(ctx, updateQuery, transaction.Amount, transaction.WalletID).Scan(&transaction.Balance)
+		if err != nil {
+			switch pgErrorCode(err) {
+			case checkViolation:
+				return nil, customerror.ErrWrongAmount
+			case numericValueOutOfRange:
+				return nil, customerror.ErrAmountOutOfRange
 			}
+			return nil, customerror.Wrap(err, "walletRepo.Transfer", walletRepo.Host+":"+walletRepo.Port)
+		}
+		err = insertTransaction(ctx, tx, transaction)
+		if err != nil {
+			return nil, customerror.Wrap(err, "walletRepo.Transfer", walletRepo.Host+":"+walletRepo.Port)
 		}
-		return customerror.NewError("walletRepo.UpdateWallet", walletRepo.Host+":"+walletRepo.Port, err.Error())
 	}
-	if command.RowsAffected() == 0 {
-		return pgx.ErrNoRows
+	err = tx.Commit(ctx)
+	if err != nil {
+		return nil, customerror.Wrap(err, "walletRepo.Transfer", walletRepo.Host+":"+walletRepo.Port)
 	}
-	return nil
+	return &transfer, nil
+}
+
+func (walletRepo *WalletRepository) ListTransactions(ctx context.Context, filter wallet.TransactionFilter) (_ []wallet.Transaction, err error) {
+	ctx, span := walletRepo.Tracer.Start(ctx, "walletRepo.ListTransactions", trace.WithAttributes(tracing.WalletIDKey.String(filter.WalletID.String())))
+	defer func() { tracing.End(span, err) }()
+
+	conditions := []string{"wallet_id = $1"}
+	args := []interface{}{filter.WalletID}
+	if filter.OperationType != "" {
+		args = append(args, filter.OperationType)
+		conditions = append(conditions, fmt.Sprintf("operation_type = $%d", len(args)))
+	}
+	if !filter.From.IsZero() {
+		args = append(args, filter.From)
+		conditions = append(conditions, fmt.Sprintf("created_at >= $%d", len(args)))
+	}
+	if !filter.To.IsZero() {
+		args = append(args, filter.To)
+		conditions = append(conditions, fmt.Sprintf("created_at < $%d", len(args)))
+	}
+	comparison, direction := "<", "DESC"
+	if filter.Ascending {
+		comparison, direction = ">", "ASC"
+	}
+	if filter.Cursor != nil {
+		args = append(args, filter.Cursor.CreatedAt, filter.Cursor.ID)
+		conditions = append(conditions, fmt.Sprintf("(created_at, id) %s ($%d, $%d)", comparison, len(args)-1, len(args)))
+	}
+	args = append(args, filter.Limit)
+	selectQuery := fmt.Sprintf(
+		"SELECT id, wallet_id, operation_type, amount, balance, transfer_id, rate_id, exchange_rate::text, hold_id, held_amount, created_at FROM wallet_transactions WHERE %s ORDER BY created_at %s, id %s LIMIT $%d",
+		strings.Join(conditions, " AND "), direction, direction, len(args),
+	)
+
+	rows, err := walletRepo.Pool.Query(ctx, selectQuery, args...)
+	if err != nil {
+		return nil, customerror.Wrap(err, "walletRepo.ListTransactions", walletRepo.Host+":"+walletRepo.Port)
+	}
+	defer rows.Close()
+
+	transactions := []wallet.Transaction{}
+	defer func() { span.SetAttributes(tracing.RowCountKey.Int(len(transactions))) }()
+	for rows.Next() {
+		var transaction wallet.Transaction
+		err = rows.Scan(
+			&transaction.ID, &transaction.WalletID, &transaction.OperationType, &transaction.Amount,
+			&transaction.Balance, &transaction.TransferID, &transaction.RateID, &transaction.ExchangeRate, &transaction.HoldID, &transaction.HeldAmount,
+			&transaction.CreatedAt,
+		)
+		if err != nil {
+			return nil, customerror.Wrap(err, "walletRepo.ListTransactions", walletRepo.Host+":"+walletRepo.Port)
+		}
+		transactions = append(transactions, transaction)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, customerror.Wrap(err, "walletRepo.ListTransactions", walletRepo.Host+":"+walletRepo.Port)
+	}
+	return transactions, nil
 }
 
 func (walletRepo *WalletRepository) ClosePull() {
 	walletRepo.Pool.Close()
 }
+
+func pgErrorCode(err error) string {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		return pgErr.Code
+	}
+	return ""
+}
+
+func insertTransaction(ctx context.Context, tx pgx.Tx, transaction *wallet.Transaction) error {
+	insertQuery := `INSERT INTO wallet_transactions (id, wallet_id, operation_type, amount, balance, transfer_id, rate_id, exchange_rate, hold_id, held_amount)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8::numeric, $9, $10) RETURNING created_at`
+	return tx.QueryRow(ctx, insertQuery,
+		transaction.ID, transaction.WalletID, transaction.OperationType, transaction.Amount,
+		transaction.Balance, transaction.TransferID, transaction.RateID, transaction.ExchangeRate,
+		transaction.HoldID, transaction.HeldAmount,
+	).Scan(&transaction.CreatedAt)
+}
+
+// findRate returns the rate of the pair in effect when tx started: among the
+// rates whose window covers that time, the one that took effect last, and of
+// those the one loaded last.
+func findRate(ctx context.Context, tx pgx.Tx, base string, quote string) (*wallet.ExchangeRate, error) {
+	selectQuery := `SELECT ` + rateColumns + ` FROM rates
+	WHERE base_currency = $1 AND quote_currency = $2 AND valid_from <= now() AND (valid_to IS NULL OR valid_to > now())
+	ORDER BY valid_from DESC, created_at DESC LIMIT 1`
+	return scanRate(tx.QueryRow(ctx, selectQuery, base, quote))
+}
@@ -2,16 +2,25 @@ package repos_test
 
 import (
 	"backend/internal/repos"
+	"backend/internal/tracing"
+	"backend/pkg/config"
 	"backend/pkg/customerror"
 	"backend/pkg/wallet"
 	"context"
 	"errors"
+	"log/slog"
 	"testing"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/stretchr/testify/mock"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -30,10 +39,60 @@ func (m *MockPool) QueryRow(ctx context.Context, sql string, args ...interface{}
 	return mockArgs.Get(0).(pgx.Row)
 }
 
+func (m *MockPool) Query(ctx context.Context, sql string, args ...interface{}) (pgx.Rows, error) {
+	mockArgs := m.Called(ctx, sql, args)
+	return mockArgs.Get(0).(pgx.Rows), mockArgs.Error(1)
+}
+
+func (m *MockPool) Begin(ctx context.Context) (pgx.Tx, error) {
+	mockArgs := m.Called(ctx)
+	return mockArgs.Get(0).(pgx.Tx), mockArgs.Error(1)
+}
+
+func (m *MockPool) Ping(ctx context.Context) error {
+	mockArgs := m.Called(ctx)
+	return mockArgs.Error(0)
+}
+
+func (m *MockPool) Stat() *pgxpool.Stat {
+	mockArgs := m.Called()
+	return mockArgs.Get(0).(*pgxpool.Stat)
+}
+
 func (m *MockPool) Close() {
 	m.Called()
 }
 
+type MockTx struct {
+	pgx.Tx
+	mock.Mock
+}
+
+func (m *MockTx) Exec(ctx context.Context, sql string, args ...interface{}) (pgconn.CommandTag, error) {
+	mockArgs := m.Called(ctx, sql, args)
+	return mockArgs.Get(0).(pgconn.CommandTag), mockArgs.Error(1)
+}
+
+func (m *MockTx) QueryRow(ctx context.Context, sql string, args ...interface{}) pgx.Row {
+	mockArgs := m.Called(ctx, sql, args)
+	return mockArgs.Get(0).(pgx.Row)
+}
+
+func (m *MockTx) Query(ctx context.Context, sql string, args ...interface{}) (pgx.Rows, error) {
+	mockArgs := m.Called(ctx, sql, args)
+	return mockArgs.Get(0).(pgx.Rows), mockArgs.Error(1)
+}
+
+func (m *MockTx) Commit(ctx context.Context) error {
+	mockArgs := m.Called(ctx)
+	return mockArgs.Error(0)
+}
+
+func (m *MockTx) Rollback(ctx context.Context) error {
+	mockArgs := m.Called(ctx)
+	return mockArgs.Error(0)
+}
+
 type MockRow struct {
 	mock.Mock
 }
@@ -43,53 +102,134 @@ func (m *MockRow) Scan(dest ...any) error {
 	return args.Error(0)
 }
 
-type CreateTablesTest struct {
-	Name    string
-	Mock    func(*MockPool)
-	WantErr bool
+type MockRows struct {
+	pgx.Rows
+	mock.Mock
+}
+
+func (m *MockRows) Next() bool {
+	args := m.Called()
+	return args.Bool(0)
+}
+
+func (m *MockRows) Scan(dest ...any) error {
+	args := m.Called(dest)
+	return args.Error(0)
+}
+
+func (m *MockRows) Err() error {
+	args := m.Called()
+	return args.Error(0)
+}
+
+func (m *MockRows) Close() {
+	m.Called()
+}
+
+type CreateWalletTest struct {
+	Name          string
+	WalletId      uuid.UUID
+	Amount        int64
+	WaitingWallet *wallet.Wallet
+	Mock          func(*MockPool, *MockRow)
+	WaitingError  error
 }
 
-func TestWalletRepository_CreateTables(t *testing.T) {
-	createTableTests := []CreateTablesTest{
+func TestWalletRepository_CreateWallet(t *testing.T) {
+	testUUID := uuid.New()
+	testWallet := &wallet.Wallet{
+		ID:       testUUID,
+		Amount:   500,
+		Currency: wallet.Currency{Code: "JPY", MinorUnits: 0},
+	}
+	createWalletTests := []CreateWalletTest{
 		{
-			Name: "Success Test",
-			Mock: func(m *MockPool) {
-				m.On("Exec", mock.Anything, mock.Anything, mock.Anything).Return(pgconn.CommandTag{}, nil).Twice()
+			Name:          "Success Test",
+			WalletId:      testUUID,
+			Amount:        500,
+			WaitingWallet: testWallet,
+			WaitingError:  nil,
+			Mock: func(p *MockPool, r *MockRow) {
+				p.On("QueryRow", mock.Anything, mock.Anything, []interface{}{testUUID, int64(500), "JPY", (*string)(nil)}).Return(r)
+				r.On("Scan", mock.Anything).Run(func(args mock.Arguments) {
+					mockArgs := args.Get(0).([]interface{})
+					idPtr := mockArgs[0].(*uuid.UUID)
+					*idPtr = testWallet.ID
+					amountPtr := mockArgs[1].(*int64)
+					*amountPtr = testWallet.Amount
+					*mockArgs[3].(*string) = testWallet.Currency.Code
+				}).Return(nil)
+			},
+		},
+		{
+			Name:          "Duplicate Test",
+			WalletId:      testUUID,
+			Amount:        500,
+			WaitingWallet: nil,
+			WaitingError:  wallet.ErrAlreadyExists,
+			Mock: func(p *MockPool, r *MockRow) {
+				p.On("QueryRow", mock.Anything, mock.Anything, mock.Anything).Return(r)
+				r.On("Scan", mock.Anything).Return(&pgconn.PgError{Code: "23505"})
+			},
+		},
+		{
+			Name:          "Wrong Amount Test",
+			WalletId:      testUUID,
+			Amount:        -1,
+			WaitingWallet: nil,
+			WaitingError:  customerror.ErrInvalidAmount,
+			Mock: func(p *MockPool, r *MockRow) {
+				p.On("QueryRow", mock.Anything, mock.Anything, mock.Anything).Return(r)
+				r.On("Scan", mock.Anything).Return(&pgconn.PgError{Code: "23514"})
 			},
-			WantErr: false,
 		},
 		{
-			Name: "Error creating table",
-			Mock: func(m *MockPool) {
-				m.On("Exec", mock.Anything, mock.Anything, mock.Anything).Return(pgconn.CommandTag{}, errors.New("error creating table")).Once()
+			Name:          "Unknown Currency Test",
+			WalletId:      testUUID,
+			Amount:        500,
+			WaitingWallet: nil,
+			WaitingError:  wallet.ErrUnknownCurrency,
+			Mock: func(p *MockPool, r *MockRow) {
+				p.On("QueryRow", mock.Anything, mock.Anything, mock.Anything).Return(r)
+				r.On("Scan", mock.Anything).Return(&pgconn.PgError{Code: "23503"})
 			},
-			WantErr: true,
 		},
 		{
-			Name: "Error creating index",
-			Mock: func(m *MockPool) {
-				m.On("Exec", mock.Anything, mock.Anything, mock.Anything).Return(pgconn.CommandTag{}, errors.New("error creating index")).Once()
+			Name:          "Other Error Test",
+			WalletId:      testUUID,
+			Amount:        500,
+			WaitingWallet: nil,
+			WaitingError:  customerror.NewError("walletRepo.CreateWallet", "127.0.0.1:8080", "Other error"),
+			Mock: func(p *MockPool, r *MockRow) {
+				p.On("QueryRow", mock.Anything, mock.Anything, mock.Anything).Return(r)
+				r.On("Scan", mock.Anything).Return(errors.New("Other error"))
 			},
-			WantErr: true,
 		},
 	}
-
-	for _, test := range createTableTests {
+	for _, test := range createWalletTests {
 		t.Run(test.Name, func(t *testing.T) {
 			mockPool := new(MockPool)
-			test.Mock(mockPool)
+			mockRow := new(MockRow)
+			test.Mock(mockPool, mockRow)
 			repo := &repos.WalletRepository{
-				Pool: mockPool,
-				Host: "localhost",
-				Port: "80",
+				Pool:   mockPool,
+				Host:   "127.0.0.1",
+				Port:   "8080",
+				Logger: slog.New(slog.DiscardHandler),
+				Tracer: noop.NewTracerProvider().Tracer(""),
 			}
-			err := repo.CreateTables(context.Background())
-			if test.WantErr {
-				assert.Error(t, err)
+			createdWallet, err := repo.CreateWallet(context.Background(), test.WalletId, test.Amount, "JPY", nil)
+			if test.WaitingError != nil {
+				assert.EqualError(t, err, test.WaitingError.Error())
+				assert.Equal(t, test.WaitingWallet, createdWallet)
 			} else {
 				assert.NoError(t, err)
+				assert.Equal(t, test.WaitingWallet.ID, createdWallet.ID)
+				assert.Equal(t, test.WaitingWallet.Amount, createdWallet.Amount)
+				assert.Equal(t, test.WaitingWallet.Currency.Code, createdWallet.Currency.Code)
 			}
 			mockPool.AssertExpectations(t)
+			mockRow.AssertExpectations(t)
 		})
 	}
 }
@@ -115,7 +255,7 @@ func TestWalletRepository_GetWallet(t *testing.T) {
 			WalletId:      testUUID,
 			WaitingWallet: testWallet,
 			Mock: func(p *MockPool, r *MockRow) {
-				p.On("QueryRow", mock.Anything, "SELECT id, amount FROM wallet WHERE id = $1", []interface{}{testUUID}).Return(r)
+				p.On("QueryRow", mock.Anything, mock.Anything, []interface{}{testUUID}).Return(r)
 				r.On("Scan", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
 					mockArgs := args.Get(0).([]interface{})
 					idPtr := mockArgs[0].(*uuid.UUID)
@@ -127,7 +267,7 @@ func TestWalletRepository_GetWallet(t *testing.T) {
 		},
 		{
 			Name:          "Not Found Test",
-			WaitingError:  pgx.ErrNoRows,
+			WaitingError:  wallet.ErrNotFound,
 			WalletId:      testUUID,
 			WaitingWallet: nil,
 			Mock: func(p *MockPool, r *MockRow) {
@@ -152,9 +292,11 @@ func TestWalletRepository_GetWallet(t *testing.T) {
 			mockRow := new(MockRow)
 			test.Mock(mockPool, mockRow)
 			repo := &repos.WalletRepository{
-				Pool: mockPool,
-				Host: "127.0.0.1",
-				Port: "8080",
+				Pool:   mockPool,
+				Host:   "127.0.0.1",
+				Port:   "8080",
+				Logger: slog.New(slog.DiscardHandler),
+				Tracer: noop.NewTracerProvider().Tracer(""),
 			}
 			gettedWallet, err := repo.GetWallet(context.Background(), test.WalletId)
 			if err != nil {
@@ -171,69 +313,272 @@ func TestWalletRepository_GetWallet(t *testing.T) {
 	}
 }
 
-/*
-		func (walletRepo *WalletRepository) UpdateWallet(ctx context.Context, id uuid.UUID, delta int64) error {
-		updateQuery := "UPDATE wallet set amount = amount + $1 WHERE id = $2"
-		command, err := walletRepo.Pool.Exec(ctx, updateQuery, delta, id)
-		if err != nil {
-			if pgErr, ok := err.(*pgconn.PgError); ok {
-				if pgErr.Code == "23514" {
-					return customerror.ErrWrongAmount
-				}
+type GetCurrencyTest struct {
+	Name            string
+	Mock            func(*MockPool, *MockRow)
+	WaitingCurrency *wallet.Currency
+	WaitingError    error
+}
+
+func TestWalletRepository_GetCurrency(t *testing.T) {
+	selectQuery := "SELECT code, minor_units FROM currencies WHERE code = $1"
+	tests := []GetCurrencyTest{
+		{
+			Name: "Success Test",
+			Mock: func(p *MockPool, r *MockRow) {
+				p.On("QueryRow", mock.Anything, selectQuery, []interface{}{"JPY"}).Return(r)
+				r.On("Scan", mock.Anything).Run(func(args mock.Arguments) {
+					dest := args.Get(0).([]interface{})
+					*dest[0].(*string) = "JPY"
+					*dest[1].(*int) = 0
+				}).Return(nil)
+			},
+			WaitingCurrency: &wallet.Currency{Code: "JPY", MinorUnits: 0},
+		},
+		{
+			Name: "Unknown Currency Test",
+			Mock: func(p *MockPool, r *MockRow) {
+				p.On("QueryRow", mock.Anything, selectQuery, mock.Anything).Return(r)
+				r.On("Scan", mock.Anything).Return(pgx.ErrNoRows)
+			},
+			WaitingError: wallet.ErrUnknownCurrency,
+		},
+		{
+			Name: "Other Error Test",
+			Mock: func(p *MockPool, r *MockRow) {
+				p.On("QueryRow", mock.Anything, selectQuery, mock.Anything).Return(r)
+				r.On("Scan", mock.Anything).Return(errors.New("error"))
+			},
+			WaitingError: customerror.NewError("walletRepo.GetCurrency", "127.0.0.1:8080", "error"),
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			mockPool := new(MockPool)
+			mockRow := new(MockRow)
+			test.Mock(mockPool, mockRow)
+			repo := &repos.WalletRepository{
+				Pool:   mockPool,
+				Host:   "127.0.0.1",
+				Port:   "8080",
+				Logger: slog.New(slog.DiscardHandler),
+				Tracer: noop.NewTracerProvider().Tracer(""),
+			}
+			currency, err := repo.GetCurrency(context.Background(), "JPY")
+			if test.WaitingError != nil {
+				assert.EqualError(t, err, test.WaitingError.Error())
+				assert.Nil(t, currency)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, test.WaitingCurrency, currency)
 			}
-			return customerror.NewError("walletRepo.UpdateWallet", walletRepo.Host+":"+walletRepo.Port, err.Error())
-		}
-		if command.RowsAffected() == 0 {
-			return pgx.ErrNoRows
-		}
-		return nil
+			mockPool.AssertExpectations(t)
+			mockRow.AssertExpectations(t)
+		})
 	}
-*/
+}
+
 type UpdateWalletTest struct {
-	Name         string
-	WalletId     uuid.UUID
-	Delta        int64
-	Mock         func(*MockPool)
-	WaitingError error
+	Name               string
+	WalletId           uuid.UUID
+	OperationType      string
+	Delta              int64
+	IdempotencyKey     *wallet.IdempotencyKey
+	Mock               func(*MockPool, *MockTx, *MockRow)
+	WaitingTransaction *wallet.Transaction
+	WaitingError       error
 }
 
 func TestWalletRepository_UpdateWallet(t *testing.T) {
 	testUUID := uuid.New()
 	testDelta := int64(100)
+	testTime := time.Now()
+	testKey := wallet.NewIdempotencyKey("client-1", "key", testUUID, wallet.OperationDeposit, testDelta)
+	replayedID := uuid.New()
+	claimQuery := "INSERT INTO idempotency_keys (client_id, key, request_hash) VALUES ($1, $2, $3) ON CONFLICT (client_id, key) DO NOTHING"
 	updateWalletTests := []UpdateWalletTest{
 		{
-			Name:         "Success Test",
-			WalletId:     testUUID,
+			Name:          "Success Test",
+			WalletId:      testUUID,
+			OperationType: wallet.OperationDeposit,
+			Delta:         testDelta,
+			WaitingTransaction: &wallet.Transaction{
+				WalletID:      testUUID,
+				OperationType: wallet.OperationDeposit,
+				Amount:        testDelta,
+				Balance:       1100,
+				CreatedAt:     testTime,
+			},
+			WaitingError: nil,
+			Mock: func(p *MockPool, tx *MockTx, r *MockRow) {
+				p.On("Begin", mock.Anything).Return(tx, nil)
+				tx.On("QueryRow", mock.Anything, "UPDATE wallet SET amount = amount + $1 WHERE id = $2 RETURNING amount", []interface{}{testDelta, testUUID}).Return(r).Once()
+				r.On("Scan", mock.Anything).Run(func(args mock.Arguments) {
+					*args.Get(0).([]interface{})[0].(*int64) = 1100
+				}).Return(nil).Once()
+				tx.On("QueryRow", mock.Anything, `INSERT INTO wallet_transactions (id, wallet_id, operation_type, amount, balance, transfer_id, rate_id, exchange_rate, hold_id, held_amount)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8::numeric, $9, $10) RETURNING created_at`, mock.Anything).Return(r).Once()
+				r.On("Scan", mock.Anything).Run(func(args mock.Arguments) {
+					*args.Get(0).([]interface{})[0].(*time.Time) = testTime
+				}).Return(nil).Once()
+				tx.On("Commit", mock.Anything).Return(nil)
+				tx.On("Rollback", mock.Anything).Return(pgx.ErrTxClosed)
+			},
+		},
+		{
+			Name:           "Idempotent First Request Test",
+			WalletId:       testUUID,
+			OperationType:  wallet.OperationDeposit,
+			Delta:          testDelta,
+			IdempotencyKey: testKey,
+			WaitingTransaction: &wallet.Transaction{
+				WalletID:      testUUID,
+				OperationType: wallet.OperationDeposit,
+				Amount:        testDelta,
+				Balance:       1100,
+				CreatedAt:     testTime,
+			},
+			WaitingError: nil,
+			Mock: func(p *MockPool, tx *MockTx, r *MockRow) {
+				p.On("Begin", mock.Anything).Return(tx, nil)
+				tx.On("Exec", mock.Anything, claimQuery, []interface{}{"client-1", "key", testKey.RequestHash}).Return(pgconn.NewCommandTag("INSERT 0 1"), nil).Once()
+				tx.On("QueryRow", mock.Anything, "UPDATE wallet SET amount = amount + $1 WHERE id = $2 RETURNING amount", []interface{}{testDelta, testUUID}).Return(r).Once()
+				r.On("Scan", mock.Anything).Run(func(args mock.Arguments) {
+					*args.Get(0).([]interface{})[0].(*int64) = 1100
+				}).Return(nil).Once()
+				tx.On("QueryRow", mock.Anything, mock.Anything, mock.Anything).Return(r).Once()
+				r.On("Scan", mock.Anything).Run(func(args mock.Arguments) {
+					*args.Get(0).([]interface{})[0].(*time.Time) = testTime
+				}).Return(nil).Once()
+				tx.On("Exec", mock.Anything, "UPDATE idempotency_keys SET transaction_id = $1 WHERE client_id = $2 AND key = $3", mock.Anything).Return(pgconn.NewCommandTag("UPDATE 1"), nil).Once()
+				tx.On("Commit", mock.Anything).Return(nil)
+				tx.On("Rollback", mock.Anything).Return(pgx.ErrTxClosed)
+			},
+		},
+		{
+			Name:           "Idempotent Replay Test",
+			WalletId:       testUUID,
+			OperationType:  wallet.OperationDeposit,
+			Delta:          testDelta,
+			IdempotencyKey: testKey,
+			WaitingTransaction: &wallet.Transaction{
+				ID:            replayedID,
+				WalletID:      testUUID,
+				OperationType: wallet.OperationDeposit,
+				Amount:        testDelta,
+				Balance:       1100,
+				CreatedAt:     testTime,
+				Replayed:      true,
+			},
 			WaitingError: nil,
-			Delta:        testDelta,
-			Mock: func(p *MockPool) {
-				p.On("Exec", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(pgconn.NewCommandTag("UPDATE 1"), nil)
+			Mock: func(p *MockPool, tx *MockTx, r *MockRow) {
+				p.On("Begin", mock.Anything).Return(tx, nil)
+				tx.On("Exec", mock.Anything, claimQuery, mock.Anything).Return(pgconn.NewCommandTag("INSERT 0 0"), nil).Once()
+				tx.On("QueryRow", mock.Anything, mock.Anything, []interface{}{"client-1", "key"}).Return(r).Once()
+				r.On("Scan", mock.Anything).Run(func(args mock.Arguments) {
+					dest := args.Get(0).([]interface{})
+					*dest[0].(*string) = testKey.RequestHash
+					*dest[1].(*uuid.UUID) = replayedID
+					*dest[2].(*uuid.UUID) = testUUID
+					*dest[3].(*string) = wallet.OperationDeposit
+					*dest[4].(*int64) = testDelta
+					*dest[5].(*int64) = 1100
+					*dest[11].(*time.Time) = testTime
+				}).Return(nil).Once()
+				tx.On("Rollback", mock.Anything).Return(nil)
+			},
+		},
+		{
+			Name:           "Idempotency Key Reused Test",
+			WalletId:       testUUID,
+			OperationType:  wallet.OperationDeposit,
+			Delta:          testDelta,
+			IdempotencyKey: testKey,
+			WaitingError:   customerror.ErrIdempotencyKeyReused,
+			Mock: func(p *MockPool, tx *MockTx, r *MockRow) {
+				p.On("Begin", mock.Anything).Return(tx, nil)
+				tx.On("Exec", mock.Anything, claimQuery, mock.Anything).Return(pgconn.NewCommandTag("INSERT 0 0"), nil).Once()
+				tx.On("QueryRow", mock.Anything, mock.Anything, []interface{}{"client-1", "key"}).Return(r).Once()
+				r.On("Scan", mock.Anything).Run(func(args mock.Arguments) {
+					*args.Get(0).([]interface{})[0].(*string) = "other"
+				}).Return(nil).Once()
+				tx.On("Rollback", mock.Anything).Return(nil)
+			},
+		},
+		{
+			Name:          "Begin Error Test",
+			WalletId:      testUUID,
+			OperationType: wallet.OperationDeposit,
+			Delta:         testDelta,
+			WaitingError:  customerror.NewError("walletRepo.UpdateWallet", "127.0.0.1:8080", "error"),
+			Mock: func(p *MockPool, tx *MockTx, r *MockRow) {
+				p.On("Begin", mock.Anything).Return(tx, errors.New("error"))
 			},
 		},
 		{
-			Name:         "Not Found Test",
-			WalletId:     testUUID,
-			Delta:        testDelta,
-			WaitingError: pgx.ErrNoRows,
-			Mock: func(p *MockPool) {
-				p.On("Exec", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(pgconn.NewCommandTag("UPDATE 0"), nil)
+			Name:          "Not Found Test",
+			WalletId:      testUUID,
+			OperationType: wallet.OperationDeposit,
+			Delta:         testDelta,
+			WaitingError:  wallet.ErrNotFound,
+			Mock: func(p *MockPool, tx *MockTx, r *MockRow) {
+				p.On("Begin", mock.Anything).Return(tx, nil)
+				tx.On("QueryRow", mock.Anything, mock.Anything, mock.Anything).Return(r)
+				r.On("Scan", mock.Anything).Return(pgx.ErrNoRows)
+				tx.On("Rollback", mock.Anything).Return(nil)
 			},
 		},
 		{
-			Name:     "Wrong Amount Test",
-			WalletId: testUUID,
-			Delta:    testDelta,
-			Mock: func(p *MockPool) {
-				p.On("Exec", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(pgconn.CommandTag{}, &pgconn.PgError{Code: "23514"})
+			Name:          "Wrong Amount Test",
+			WalletId:      testUUID,
+			OperationType: wallet.OperationWithdraw,
+			Delta:         -testDelta,
+			Mock: func(p *MockPool, tx *MockTx, r *MockRow) {
+				p.On("Begin", mock.Anything).Return(tx, nil)
+				tx.On("QueryRow", mock.Anything, mock.Anything, mock.Anything).Return(r)
+				r.On("Scan", mock.Anything).Return(&pgconn.PgError{Code: "23514"})
+				tx.On("Rollback", mock.Anything).Return(nil)
 			},
 			WaitingError: customerror.ErrWrongAmount,
 		},
 		{
-			Name:     "other error",
-			WalletId: testUUID,
-			Delta:    testDelta,
-			Mock: func(p *MockPool) {
-				p.On("Exec", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(pgconn.CommandTag{}, errors.New("error"))
+			Name:          "Balance Out Of Range Test",
+			WalletId:      testUUID,
+			OperationType: wallet.OperationDeposit,
+			Delta:         testDelta,
+			Mock: func(p *MockPool, tx *MockTx, r *MockRow) {
+				p.On("Begin", mock.Anything).Return(tx, nil)
+				tx.On("QueryRow", mock.Anything, mock.Anything, mock.Anything).Return(r)
+				r.On("Scan", mock.Anything).Return(&pgconn.PgError{Code: "22003"})
+				tx.On("Rollback", mock.Anything).Return(nil)
+			},
+			WaitingError: customerror.ErrAmountOutOfRange,
+		},
+		{
+			Name:          "Ledger Error Test",
+			WalletId:      testUUID,
+			OperationType: wallet.OperationDeposit,
+			Delta:         testDelta,
+			Mock: func(p *MockPool, tx *MockTx, r *MockRow) {
+				p.On("Begin", mock.Anything).Return(tx, nil)
+				tx.On("QueryRow", mock.Anything, mock.Anything, mock.Anything).Return(r)
+				r.On("Scan", mock.Anything).Return(nil).Once()
+				r.On("Scan", mock.Anything).Return(errors.New("error")).Once()
+				tx.On("Rollback", mock.Anything).Return(nil)
+			},
+			WaitingError: customerror.NewError("walletRepo.UpdateWallet", "127.0.0.1:8080", "error"),
+		},
+		{
+			Name:          "Commit Error Test",
+			WalletId:      testUUID,
+			OperationType: wallet.OperationDeposit,
+			Delta:         testDelta,
+			Mock: func(p *MockPool, tx *MockTx, r *MockRow) {
+				p.On("Begin", mock.Anything).Return(tx, nil)
+				tx.On("QueryRow", mock.Anything, mock.Anything, mock.Anything).Return(r)
+				r.On("Scan", mock.Anything).Return(nil)
+				tx.On("Commit", mock.Anything).Return(errors.New("error"))
+				tx.On("Rollback", mock.Anything).Return(nil)
 			},
 			WaitingError: customerror.NewError("walletRepo.UpdateWallet", "127.0.0.1:8080", "error"),
 		},
@@ -241,21 +586,413 @@ func TestWalletRepository_UpdateWallet(t *testing.T) {
 	for _, test := range updateWalletTests {
 		t.Run(test.Name, func(t *testing.T) {
 			mockPool := new(MockPool)
-			test.Mock(mockPool)
+			mockTx := new(MockTx)
+			mockRow := new(MockRow)
+			test.Mock(mockPool, mockTx, mockRow)
+
+			repo := &repos.WalletRepository{
+				Pool:   mockPool,
+				Host:   "127.0.0.1",
+				Port:   "8080",
+				Logger: slog.New(slog.DiscardHandler),
+				Tracer: noop.NewTracerProvider().Tracer(""),
+			}
+
+			transaction, err := repo.UpdateWallet(context.Background(), test.WalletId, test.OperationType, test.Delta, test.IdempotencyKey)
+			if test.WaitingError != nil {
+				assert.EqualError(t, err, test.WaitingError.Error())
+				assert.Nil(t, transaction)
+			} else {
+				assert.NoError(t, err)
+				if test.WaitingTransaction.ID != uuid.Nil {
+					assert.Equal(t, test.WaitingTransaction.ID, transaction.ID)
+				} else {
+					assert.NotEqual(t, uuid.Nil, transaction.ID)
+				}
+				assert.Equal(t, test.WaitingTransaction.Replayed, transaction.Replayed)
+				assert.Equal(t, test.WaitingTransaction.WalletID, transaction.WalletID)
+				assert.Equal(t, test.WaitingTransaction.OperationType, transaction.OperationType)
+				assert.Equal(t, test.WaitingTransaction.Amount, transaction.Amount)
+				assert.Equal(t, test.WaitingTransaction.Balance, transaction.Balance)
+				assert.Equal(t, test.WaitingTransaction.CreatedAt, transaction.CreatedAt)
+			}
+			mockPool.AssertExpectations(t)
+			mockTx.AssertExpectations(t)
+			mockRow.AssertExpectations(t)
+		})
+	}
+}
+
+type ListTransactionsTest struct {
+	Name                string
+	Filter              wallet.TransactionFilter
+	Mock                func(*MockPool, *MockRows)
+	WaitingTransactions []wallet.Transaction
+	WaitingError        error
+}
+
+func TestWalletRepository_ListTransactions(t *testing.T) {
+	testUUID := uuid.New()
+	testTransaction := wallet.Transaction{
+		ID:            uuid.New(),
+		WalletID:      testUUID,
+		OperationType: wallet.OperationDeposit,
+		Amount:        100,
+		Balance:       100,
+		CreatedAt:     time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC),
+	}
+	testFrom := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	testCursor := &wallet.TransactionCursor{CreatedAt: testFrom, ID: uuid.New()}
+	listTransactionsTests := []ListTransactionsTest{
+		{
+			Name:   "Success Test",
+			Filter: wallet.TransactionFilter{WalletID: testUUID, Limit: 10},
+			Mock: func(p *MockPool, r *MockRows) {
+				p.On("Query", mock.Anything, "SELECT id, wallet_id, operation_type, amount, balance, transfer_id, rate_id, exchange_rate::text, hold_id, held_amount, created_at FROM wallet_transactions WHERE wallet_id = $1 ORDER BY created_at DESC, id DESC LIMIT $2", []interface{}{testUUID, 10}).Return(r, nil)
+				r.On("Next").Return(true).Once()
+				r.On("Scan", mock.Anything).Run(func(args mock.Arguments) {
+					dest := args.Get(0).([]interface{})
+					*dest[0].(*uuid.UUID) = testTransaction.ID
+					*dest[1].(*uuid.UUID) = testTransaction.WalletID
+					*dest[2].(*string) = testTransaction.OperationType
+					*dest[3].(*int64) = testTransaction.Amount
+					*dest[4].(*int64) = testTransaction.Balance
+					*dest[10].(*time.Time) = testTransaction.CreatedAt
+				}).Return(nil)
+				r.On("Next").Return(false).Once()
+				r.On("Err").Return(nil)
+				r.On("Close")
+			},
+			WaitingTransactions: []wallet.Transaction{testTransaction},
+		},
+		{
+			Name: "Filters And Cursor Test",
+			Filter: wallet.TransactionFilter{
+				WalletID:      testUUID,
+				OperationType: wallet.OperationWithdraw,
+				From:          testFrom,
+				Cursor:        testCursor,
+				Ascending:     true,
+				Limit:         5,
+			},
+			Mock: func(p *MockPool, r *MockRows) {
+				p.On("Query", mock.Anything, "SELECT id, wallet_id, operation_type, amount, balance, transfer_id, rate_id, exchange_rate::text, hold_id, held_amount, created_at FROM wallet_transactions WHERE wallet_id = $1 AND operation_type = $2 AND created_at >= $3 AND (created_at, id) > ($4, $5) ORDER BY created_at ASC, id ASC LIMIT $6", []interface{}{testUUID, wallet.OperationWithdraw, testFrom, testCursor.CreatedAt, testCursor.ID, 5}).Return(r, nil)
+				r.On("Next").Return(false)
+				r.On("Err").Return(nil)
+				r.On("Close")
+			},
+			WaitingTransactions: []wallet.Transaction{},
+		},
+		{
+			Name:   "Query Error Test",
+			Filter: wallet.TransactionFilter{WalletID: testUUID, Limit: 10},
+			Mock: func(p *MockPool, r *MockRows) {
+				p.On("Query", mock.Anything, mock.Anything, mock.Anything).Return(r, errors.New("error"))
+			},
+			WaitingError: customerror.NewError("walletRepo.ListTransactions", "127.0.0.1:8080", "error"),
+		},
+		{
+			Name:   "Scan Error Test",
+			Filter: wallet.TransactionFilter{WalletID: testUUID, Limit: 10},
+			Mock: func(p *MockPool, r *MockRows) {
+				p.On("Query", mock.Anything, mock.Anything, mock.Anything).Return(r, nil)
+				r.On("Next").Return(true)
+				r.On("Scan", mock.Anything).Return(errors.New("error"))
+				r.On("Close")
+			},
+			WaitingError: customerror.NewError("walletRepo.ListTransactions", "127.0.0.1:8080", "error"),
+		},
+		{
+			Name:   "Rows Error Test",
+			Filter: wallet.TransactionFilter{WalletID: testUUID, Limit: 10},
+			Mock: func(p *MockPool, r *MockRows) {
+				p.On("Query", mock.Anything, mock.Anything, mock.Anything).Return(r, nil)
+				r.On("Next").Return(false)
+				r.On("Err").Return(errors.New("error"))
+				r.On("Close")
+			},
+			WaitingError: customerror.NewError("walletRepo.ListTransactions", "127.0.0.1:8080", "error"),
+		},
+	}
+	for _, test := range listTransactionsTests {
+		t.Run(test.Name, func(t *testing.T) {
+			mockPool := new(MockPool)
+			mockRows := new(MockRows)
+			test.Mock(mockPool, mockRows)
+
+			repo := &repos.WalletRepository{
+				Pool:   mockPool,
+				Host:   "127.0.0.1",
+				Port:   "8080",
+				Logger: slog.New(slog.DiscardHandler),
+				Tracer: noop.NewTracerProvider().Tracer(""),
+			}
+
+			transactions, err := repo.ListTransactions(context.Background(), test.Filter)
+			if test.WaitingError != nil {
+				assert.EqualError(t, err, test.WaitingError.Error())
+				assert.Nil(t, transactions)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, test.WaitingTransactions, transactions)
+			}
+			mockPool.AssertExpectations(t)
+			mockRows.AssertExpectations(t)
+		})
+	}
+}
+
+type TransferTest struct {
+	Name          string
+	FromId        uuid.UUID
+	ToId          uuid.UUID
+	Amount        int64
+	Mock          func(*MockPool, *MockTx, *MockRow)
+	WaitingCredit int64
+	WaitingRate   *string
+	// WaitingCurrencies are the source and destination currencies.
+	WaitingCurrencies []wallet.Currency
+	WaitingError      error
+}
+
+func TestWalletRepository_Transfer(t *testing.T) {
+	lowID := uuid.MustParse("00000000-0000-0000-0000-000000000001")
+	highID := uuid.MustParse("ffffffff-ffff-ffff-ffff-ffffffffffff")
+	lockQuery := "SELECT w.currency, c.minor_units, w.owner_id FROM wallet w JOIN currencies c ON c.code = w.currency WHERE w.id = $1 FOR UPDATE OF w"
+	updateQuery := "UPDATE wallet SET amount = amount + $1 WHERE id = $2 RETURNING amount"
+	insertQuery := `INSERT INTO wallet_transactions (id, wallet_id, operation_type, amount, balance, transfer_id, rate_id, exchange_rate, hold_id, held_amount)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8::numeric, $9, $10) RETURNING created_at`
+	rateQuery := `SELECT id, base_currency, quote_currency, rate::text, valid_from, valid_to, source, created_at FROM rates
+	WHERE base_currency = $1 AND quote_currency = $2 AND valid_from <= now() AND (valid_to IS NULL OR valid_to > now())
+	ORDER BY valid_from DESC, created_at DESC LIMIT 1`
+	testRate := "151.2345"
+	currencyRow := func(code string, minorUnits int) *MockRow {
+		row := new(MockRow)
+		row.On("Scan", mock.Anything).Run(func(args mock.Arguments) {
+			dest := args.Get(0).([]interface{})
+			*dest[0].(*string) = code
+			*dest[1].(*int) = minorUnits
+		}).Return(nil)
+		return row
+	}
+	rateRow := func(rate string) *MockRow {
+		row := new(MockRow)
+		row.On("Scan", mock.Anything).Run(func(args mock.Arguments) {
+			dest := args.Get(0).([]interface{})
+			*dest[0].(*uuid.UUID) = uuid.New()
+			*dest[3].(*string) = rate
+		}).Return(nil)
+		return row
+	}
+	transferTests := []TransferTest{
+		{
+			Name:   "Success Test",
+			FromId: highID,
+			ToId:   lowID,
+			Amount: 100,
+			Mock: func(p *MockPool, tx *MockTx, r *MockRow) {
+				p.On("Begin", mock.Anything).Return(tx, nil)
+				lowLock := tx.On("QueryRow", mock.Anything, lockQuery, []interface{}{lowID}).Return(r).Once()
+				tx.On("QueryRow", mock.Anything, lockQuery, []interface{}{highID}).Return(r).Once().NotBefore(lowLock)
+				tx.On("QueryRow", mock.Anything, updateQuery, []interface{}{int64(-100), highID}).Return(r).Once()
+				tx.On("QueryRow", mock.Anything, updateQuery, []interface{}{int64(100), lowID}).Return(r).Once()
+				tx.On("QueryRow", mock.Anything, insertQuery, mock.Anything).Return(r).Twice()
+				r.On("Scan", mock.Anything).Return(nil)
+				tx.On("Commit", mock.Anything).Return(nil)
+				tx.On("Rollback", mock.Anything).Return(pgx.ErrTxClosed)
+			},
+			WaitingCredit:     100,
+			WaitingCurrencies: []wallet.Currency{{}, {}},
+			WaitingError:      nil,
+		},
+		{
+			Name:   "Cross Currency Test",
+			FromId: lowID,
+			ToId:   highID,
+			Amount: 1000,
+			Mock: func(p *MockPool, tx *MockTx, r *MockRow) {
+				p.On("Begin", mock.Anything).Return(tx, nil)
+				tx.On("QueryRow", mock.Anything, lockQuery, []interface{}{lowID}).Return(currencyRow("USD", 2)).Once()
+				tx.On("QueryRow", mock.Anything, lockQuery, []interface{}{highID}).Return(currencyRow("JPY", 0)).Once()
+				tx.On("QueryRow", mock.Anything, rateQuery, []interface{}{"USD", "JPY"}).Return(rateRow(testRate)).Once()
+				// 10.00 USD at 151.2345 is 1512.345 JPY, rounded down.
+				tx.On("QueryRow", mock.Anything, updateQuery, []interface{}{int64(-1000), lowID}).Return(r).Once()
+				tx.On("QueryRow", mock.Anything, updateQuery, []interface{}{int64(1512), highID}).Return(r).Once()
+				tx.On("QueryRow", mock.Anything, insertQuery, mock.Anything).Return(r).Twice()
+				r.On("Scan", mock.Anything).Return(nil)
+				tx.On("Commit", mock.Anything).Return(nil)
+				tx.On("Rollback", mock.Anything).Return(pgx.ErrTxClosed)
+			},
+			WaitingCredit:     1512,
+			WaitingRate:       &testRate,
+			WaitingCurrencies: []wallet.Currency{{Code: "USD", MinorUnits: 2}, {Code: "JPY", MinorUnits: 0}},
+			WaitingError:      nil,
+		},
+		{
+			Name:   "Conversion Rounds To Zero Test",
+			FromId: lowID,
+			ToId:   highID,
+			Amount: 1,
+			Mock: func(p *MockPool, tx *MockTx, r *MockRow) {
+				p.On("Begin", mock.Anything).Return(tx, nil)
+				tx.On("QueryRow", mock.Anything, lockQuery, []interface{}{lowID}).Return(currencyRow("JPY", 0)).Once()
+				tx.On("QueryRow", mock.Anything, lockQuery, []interface{}{highID}).Return(currencyRow("USD", 2)).Once()
+				tx.On("QueryRow", mock.Anything, rateQuery, []interface{}{"JPY", "USD"}).Return(rateRow("0.0066")).Once()
+				tx.On("Rollback", mock.Anything).Return(nil)
+			},
+			WaitingError: customerror.ErrInvalidAmount,
+		},
+		{
+			Name:   "Not Found Test",
+			FromId: lowID,
+			ToId:   highID,
+			Amount: 100,
+			Mock: func(p *MockPool, tx *MockTx, r *MockRow) {
+				p.On("Begin", mock.Anything).Return(tx, nil)
+				tx.On("QueryRow", mock.Anything, lockQuery, []interface{}{lowID}).Return(r).Once()
+				r.On("Scan", mock.Anything).Return(pgx.ErrNoRows)
+				tx.On("Rollback", mock.Anything).Return(nil)
+			},
+			WaitingError: wallet.ErrNotFound,
+		},
+		{
+			Name:   "Insufficient Funds Test",
+			FromId: lowID,
+			ToId:   highID,
+			Amount: 100,
+			Mock: func(p *MockPool, tx *MockTx, r *MockRow) {
+				p.On("Begin", mock.Anything).Return(tx, nil)
+				tx.On("QueryRow", mock.Anything, lockQuery, mock.Anything).Return(r).Twice()
+				r.On("Scan", mock.Anything).Return(nil).Twice()
+				tx.On("QueryRow", mock.Anything, updateQuery, []interface{}{int64(-100), lowID}).Return(r).Once()
+				r.On("Scan", mock.Anything).Return(&pgconn.PgError{Code: "23514"}).Once()
+				tx.On("Rollback", mock.Anything).Return(nil)
+			},
+			WaitingError: customerror.ErrWrongAmount,
+		},
+		{
+			Name:   "Balance Out Of Range Test",
+			FromId: lowID,
+			ToId:   highID,
+			Amount: 100,
+			Mock: func(p *MockPool, tx *MockTx, r *MockRow) {
+				p.On("Begin", mock.Anything).Return(tx, nil)
+				tx.On("QueryRow", mock.Anything, lockQuery, mock.Anything).Return(r).Twice()
+				tx.On("QueryRow", mock.Anything, updateQuery, []interface{}{int64(-100), lowID}).Return(r).Once()
+				tx.On("QueryRow", mock.Anything, insertQuery, mock.Anything).Return(r).Once()
+				r.On("Scan", mock.Anything).Return(nil).Times(4)
+				tx.On("QueryRow", mock.Anything, updateQuery, []interface{}{int64(100), highID}).Return(r).Once()
+				r.On("Scan", mock.Anything).Return(&pgconn.PgError{Code: "22003"}).Once()
+				tx.On("Rollback", mock.Anything).Return(nil)
+			},
+			WaitingError: customerror.ErrAmountOutOfRange,
+		},
+		{
+			Name:   "Exchange Rate Not Found Test",
+			FromId: lowID,
+			ToId:   highID,
+			Amount: 100,
+			Mock: func(p *MockPool, tx *MockTx, r *MockRow) {
+				p.On("Begin", mock.Anything).Return(tx, nil)
+				tx.On("QueryRow", mock.Anything, lockQuery, []interface{}{lowID}).Return(currencyRow("USD", 2)).Once()
+				tx.On("QueryRow", mock.Anything, lockQuery, []interface{}{highID}).Return(currencyRow("JPY", 0)).Once()
+				tx.On("QueryRow", mock.Anything, rateQuery, []interface{}{"USD", "JPY"}).Return(r).Once()
+				r.On("Scan", mock.Anything).Return(pgx.ErrNoRows)
+				tx.On("Rollback", mock.Anything).Return(nil)
+			},
+			WaitingError: wallet.ErrExchangeRateNotFound,
+		},
+		{
+			Name:   "Begin Error Test",
+			FromId: lowID,
+			ToId:   highID,
+			Amount: 100,
+			Mock: func(p *MockPool, tx *MockTx, r *MockRow) {
+				p.On("Begin", mock.Anything).Return(tx, errors.New("error"))
+			},
+			WaitingError: customerror.NewError("walletRepo.Transfer", "127.0.0.1:8080", "error"),
+		},
+		{
+			Name:   "Commit Error Test",
+			FromId: lowID,
+			ToId:   highID,
+			Amount: 100,
+			Mock: func(p *MockPool, tx *MockTx, r *MockRow) {
+				p.On("Begin", mock.Anything).Return(tx, nil)
+				tx.On("QueryRow", mock.Anything, mock.Anything, mock.Anything).Return(r)
+				r.On("Scan", mock.Anything).Return(nil)
+				tx.On("Commit", mock.Anything).Return(errors.New("error"))
+				tx.On("Rollback", mock.Anything).Return(nil)
+			},
+			WaitingError: customerror.NewError("walletRepo.Transfer", "127.0.0.1:8080", "error"),
+		},
+	}
+	for _, test := range transferTests {
+		t.Run(test.Name, func(t *testing.T) {
+			mockPool := new(MockPool)
+			mockTx := new(MockTx)
+			mockRow := new(MockRow)
+			test.Mock(mockPool, mockTx, mockRow)
 
 			repo := &repos.WalletRepository{
-				Pool: mockPool,
-				Host: "127.0.0.1",
-				Port: "8080",
+				Pool:   mockPool,
+				Host:   "127.0.0.1",
+				Port:   "8080",
+				Logger: slog.New(slog.DiscardHandler),
+				Tracer: noop.NewTracerProvider().Tracer(""),
 			}
 
-			err := repo.UpdateWallet(context.Background(), test.WalletId, test.Delta)
+			transfer, err := repo.Transfer(context.Background(), test.FromId, test.ToId, test.Amount)
 			if test.WaitingError != nil {
 				assert.EqualError(t, err, test.WaitingError.Error())
+				assert.Nil(t, transfer)
 			} else {
 				assert.NoError(t, err)
+				assert.Equal(t, test.FromId, transfer.Debit.WalletID)
+				assert.Equal(t, wallet.OperationTransferOut, transfer.Debit.OperationType)
+				assert.Equal(t, -test.Amount, transfer.Debit.Amount)
+				assert.Equal(t, test.ToId, transfer.Credit.WalletID)
+				assert.Equal(t, wallet.OperationTransferIn, transfer.Credit.OperationType)
+				assert.Equal(t, test.WaitingCredit, transfer.Credit.Amount)
+				assert.Equal(t, transfer.ID, *transfer.Debit.TransferID)
+				assert.Equal(t, transfer.ID, *transfer.Credit.TransferID)
+				assert.Equal(t, test.WaitingRate, transfer.Debit.ExchangeRate)
+				assert.Equal(t, test.WaitingRate, transfer.Credit.ExchangeRate)
+				assert.Equal(t, transfer.Debit.RateID, transfer.Credit.RateID)
+				assert.Equal(t, test.WaitingCurrencies, []wallet.Currency{transfer.FromCurrency, transfer.ToCurrency})
 			}
 			mockPool.AssertExpectations(t)
+			mockTx.AssertExpectations(t)
+			mockRow.AssertExpectations(t)
 		})
 	}
 }
+
+func TestWalletRepository_Tracing(t *testing.T) {
+	testUUID := uuid.New()
+	spanRecorder := tracetest.NewSpanRecorder()
+	tracerProvider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(spanRecorder))
+
+	mockPool := new(MockPool)
+	mockRows := new(MockRows)
+	mockPool.On("Query", mock.MatchedBy(func(ctx context.Context) bool {
+		return trace.SpanContextFromContext(ctx).IsValid()
+	}), mock.Anything, mock.Anything).Return(mockRows, nil)
+	mockRows.On("Next").Return(true).Twice()
+	mockRows.On("Scan", mock.Anything).Return(nil).Twice()
+	mockRows.On("Next").Return(false).Once()
+	mockRows.On("Err").Return(nil)
+	mockRows.On("Close")
+
+	repo := repos.NewWalletRepository(mockPool, &config.Config{WebHost: "127.0.0.1", WebPort: "8080"}, slog.New(slog.DiscardHandler), tracerProvider)
+	transactions, err := repo.ListTransactions(context.Background(), wallet.TransactionFilter{WalletID: testUUID, Limit: 10})
+	assert.NoError(t, err)
+	assert.Len(t, transactions, 2)
+
+	spans := spanRecorder.Ended()
+	assert.Len(t, spans, 1)
+	assert.Equal(t, "walletRepo.ListTransactions", spans[0].Name())
+	assert.Contains(t, spans[0].Attributes(), tracing.WalletIDKey.String(testUUID.String()))
+	assert.Contains(t, spans[0].Attributes(), tracing.RowCountKey.Int(2))
+	mockPool.AssertExpectations(t)
+}
@@ -0,0 +1,56 @@
+package requestid
+
+import (
+	"backend/internal/logging"
+	"context"
+	"log/slog"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+const Header = "X-Request-ID"
+
+// MaxLength bounds client supplied ids so they cannot bloat logs.
+const MaxLength = 128
+
+type contextKey struct{}
+
+func NewContext(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, contextKey{}, id)
+}
+
+// FromContext returns the request id stored in ctx, or "" outside a request.
+func FromContext(ctx context.Context) string {
+	id, _ := ctx.Value(contextKey{}).(string)
+	return id
+}
+
+// Middleware accepts the client's X-Request-ID or generates one, stores it in
+// the request context and its log attributes, and echoes it in the response.
+func Middleware() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		id := ctx.GetHeader(Header)
+		if !valid(id) {
+			id = uuid.NewString()
+		}
+		requestCtx := NewContext(ctx.Request.Context(), id)
+		requestCtx = logging.WithAttrs(requestCtx, slog.String("request_id", id))
+		ctx.Request = ctx.Request.WithContext(requestCtx)
+		ctx.Header(Header, id)
+		ctx.Next()
+	}
+}
+
+// valid accepts non-empty printable ASCII without spaces.
+func valid(id string) bool {
+	if id == "" || len(id) > MaxLength {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] < '!' || id[i] > '~' {
+			return false
+		}
+	}
+	return true
+}
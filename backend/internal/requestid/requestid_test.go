@@ -0,0 +1,68 @@
+package requestid_test
+
+import (
+	"backend/internal/requestid"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+type MiddlewareTest struct {
+	Name      string
+	Header    string
+	Generated bool
+}
+
+func TestMiddleware(t *testing.T) {
+	tests := []MiddlewareTest{
+		{
+			Name:   "Client ID Test",
+			Header: "client-req-42",
+		},
+		{
+			Name:      "Missing ID Test",
+			Generated: true,
+		},
+		{
+			Name:      "Too Long ID Test",
+			Header:    strings.Repeat("a", requestid.MaxLength+1),
+			Generated: true,
+		},
+		{
+			Name:      "Invalid Characters Test",
+			Header:    "two words",
+			Generated: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			var seen string
+			router := gin.New()
+			router.Use(requestid.Middleware())
+			router.GET("/", func(ctx *gin.Context) {
+				seen = requestid.FromContext(ctx.Request.Context())
+			})
+
+			req, _ := http.NewRequest(http.MethodGet, "/", nil)
+			if test.Header != "" {
+				req.Header.Set(requestid.Header, test.Header)
+			}
+			resp := httptest.NewRecorder()
+			router.ServeHTTP(resp, req)
+
+			assert.Equal(t, seen, resp.Header().Get(requestid.Header))
+			if test.Generated {
+				_, err := uuid.Parse(seen)
+				assert.NoError(t, err)
+			} else {
+				assert.Equal(t, test.Header, seen)
+			}
+		})
+	}
+}
@@ -0,0 +1,186 @@
+package services
+
+import (
+	"backend/internal/repos"
+	"backend/internal/tracing"
+	"backend/pkg/auth"
+	"backend/pkg/customerror"
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/trace"
+)
+
+type APIKeyServiceI interface {
+	Authenticate(ctx context.Context, secret string) (*auth.Principal, error)
+	Issue(ctx context.Context, name string, scopes []string, ttl time.Duration) (string, *auth.APIKey, error)
+	Rotate(ctx context.Context, id uuid.UUID, grace time.Duration) (string, *auth.APIKey, error)
+	Revoke(ctx context.Context, id uuid.UUID) error
+	List(ctx context.Context) ([]auth.APIKey, error)
+}
+
+type APIKeyService struct {
+	Repo    repos.APIKeyRepositoryI
+	Timeout time.Duration
+	Logger  *slog.Logger
+	Tracer  trace.Tracer
+}
+
+func NewAPIKeyService(repo repos.APIKeyRepositoryI, timeout time.Duration, logger *slog.Logger, tracerProvider trace.TracerProvider) APIKeyServiceI {
+	return &APIKeyService{
+		Repo:    repo,
+		Timeout: timeout,
+		Logger:  logger,
+		Tracer:  tracerProvider.Tracer("backend/internal/services"),
+	}
+}
+
+// Authenticate resolves secret to the caller it was issued to. Unknown,
+// expired and revoked keys are all reported as ErrUnauthenticated so the
+// response does not tell an attacker which one it was.
+func (APIKeyService *APIKeyService) Authenticate(ctx context.Context, secret string) (_ *auth.Principal, err error) {
+	ctx, span := APIKeyService.Tracer.Start(ctx, "APIKeyService.Authenticate")
+	defer func() { tracing.End(span, err) }()
+
+	if !strings.HasPrefix(secret, auth.APIKeyPrefix) {
+		return nil, auth.ErrUnauthenticated
+	}
+	ctx, cancel := context.WithTimeout(ctx, APIKeyService.Timeout)
+	defer cancel()
+
+	key, err := APIKeyService.Repo.GetAPIKeyByHash(ctx, auth.HashAPIKey(secret))
+	if errors.Is(err, auth.ErrAPIKeyNotFound) {
+		return nil, auth.ErrUnauthenticated
+	}
+	if err != nil {
+		return nil, customerror.AppendModule(err, "Authenticate")
+	}
+	span.SetAttributes(tracing.APIKeyIDKey.String(key.ID.String()))
+	if !key.Active(time.Now()) {
+		return nil, auth.ErrUnauthenticated
+	}
+	return key.Principal(), nil
+}
+
+// Issue creates a key with scopes. A zero ttl issues a key that never expires.
+// The returned secret is the only copy; it cannot be recovered later.
+func (APIKeyService *APIKeyService) Issue(ctx context.Context, name string, scopes []string, ttl time.Duration) (_ string, _ *auth.APIKey, err error) {
+	ctx, span := APIKeyService.Tracer.Start(ctx, "APIKeyService.Issue")
+	defer func() { tracing.End(span, err) }()
+
+	if name == "" || len(scopes) == 0 {
+		return "", nil, auth.ErrInvalidScope
+	}
+	for _, scope := range scopes {
+		if !auth.ValidScope(scope) {
+			return "", nil, auth.ErrInvalidScope
+		}
+	}
+	secret, key, err := newAPIKey(name, scopes, ttl)
+	if err != nil {
+		return "", nil, customerror.AppendModule(err, "Issue")
+	}
+	ctx, cancel := context.WithTimeout(ctx, APIKeyService.Timeout)
+	defer cancel()
+
+	err = APIKeyService.Repo.CreateAPIKey(ctx, key)
+	if err != nil {
+		return "", nil, customerror.AppendModule(err, "Issue")
+	}
+	APIKeyService.Logger.InfoContext(ctx, "api key issued",
+		slog.String("api_key_id", key.ID.String()),
+		slog.String("name", key.Name),
+		slog.Any("scopes", key.Scopes),
+	)
+	return secret, key, nil
+}
+
+// Rotate issues a replacement with the same name and scopes. The old key keeps
+// working for grace, so clients can be redeployed with the new one first.
+func (APIKeyService *APIKeyService) Rotate(ctx context.Context, id uuid.UUID, grace time.Duration) (_ string, _ *auth.APIKey, err error) {
+	ctx, span := APIKeyService.Tracer.Start(ctx, "APIKeyService.Rotate", trace.WithAttributes(tracing.APIKeyIDKey.String(id.String())))
+	defer func() { tracing.End(span, err) }()
+
+	ctx, cancel := context.WithTimeout(ctx, APIKeyService.Timeout)
+	defer cancel()
+
+	old, err := APIKeyService.Repo.GetAPIKey(ctx, id)
+	if errors.Is(err, auth.ErrAPIKeyNotFound) {
+		return "", nil, err
+	}
+	if err != nil {
+		return "", nil, customerror.AppendModule(err, "Rotate")
+	}
+	secret, key, err := newAPIKey(old.Name, old.Scopes, 0)
+	if err != nil {
+		return "", nil, customerror.AppendModule(err, "Rotate")
+	}
+	key.ExpiresAt = old.ExpiresAt
+	err = APIKeyService.Repo.RotateAPIKey(ctx, id, key, time.Now().Add(grace))
+	if errors.Is(err, auth.ErrAPIKeyNotFound) {
+		return "", nil, err
+	}
+	if err != nil {
+		return "", nil, customerror.AppendModule(err, "Rotate")
+	}
+	APIKeyService.Logger.InfoContext(ctx, "api key rotated",
+		slog.String("api_key_id", id.String()),
+		slog.String("replacement_id", key.ID.String()),
+		slog.Duration("grace", grace),
+	)
+	return secret, key, nil
+}
+
+func (APIKeyService *APIKeyService) Revoke(ctx context.Context, id uuid.UUID) (err error) {
+	ctx, span := APIKeyService.Tracer.Start(ctx, "APIKeyService.Revoke", trace.WithAttributes(tracing.APIKeyIDKey.String(id.String())))
+	defer func() { tracing.End(span, err) }()
+
+	ctx, cancel := context.WithTimeout(ctx, APIKeyService.Timeout)
+	defer cancel()
+
+	err = APIKeyService.Repo.RevokeAPIKey(ctx, id)
+	if err == nil {
+		APIKeyService.Logger.InfoContext(ctx, "api key revoked", slog.String("api_key_id", id.String()))
+		return nil
+	}
+	if errors.Is(err, auth.ErrAPIKeyNotFound) {
+		return err
+	}
+	return customerror.AppendModule(err, "Revoke")
+}
+
+func (APIKeyService *APIKeyService) List(ctx context.Context) (_ []auth.APIKey, err error) {
+	ctx, span := APIKeyService.Tracer.Start(ctx, "APIKeyService.List")
+	defer func() { tracing.End(span, err) }()
+
+	ctx, cancel := context.WithTimeout(ctx, APIKeyService.Timeout)
+	defer cancel()
+
+	keys, err := APIKeyService.Repo.ListAPIKeys(ctx)
+	if err != nil {
+		return nil, customerror.AppendModule(err, "List")
+	}
+	return keys, nil
+}
+
+func newAPIKey(name string, scopes []string, ttl time.Duration) (string, *auth.APIKey, error) {
+	secret, err := auth.GenerateAPIKey()
+	if err != nil {
+		return "", nil, err
+	}
+	key := &auth.APIKey{
+		ID:     uuid.New(),
+		Name:   name,
+		Hash:   auth.HashAPIKey(secret),
+		Scopes: scopes,
+	}
+	if ttl > 0 {
+		expiresAt := time.Now().Add(ttl)
+		key.ExpiresAt = &expiresAt
+	}
+	return secret, key, nil
+}
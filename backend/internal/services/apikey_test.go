@@ -0,0 +1,196 @@
+package services_test
+
+import (
+	"backend/internal/services"
+	"backend/pkg/auth"
+	"context"
+	"errors"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+type MockAPIKeyRepository struct {
+	mock.Mock
+}
+
+func (m *MockAPIKeyRepository) CreateAPIKey(ctx context.Context, key *auth.APIKey) error {
+	args := m.Called(ctx, key)
+	return args.Error(0)
+}
+
+func (m *MockAPIKeyRepository) GetAPIKey(ctx context.Context, id uuid.UUID) (*auth.APIKey, error) {
+	args := m.Called(ctx, id)
+	return args.Get(0).(*auth.APIKey), args.Error(1)
+}
+
+func (m *MockAPIKeyRepository) GetAPIKeyByHash(ctx context.Context, hash string) (*auth.APIKey, error) {
+	args := m.Called(ctx, hash)
+	return args.Get(0).(*auth.APIKey), args.Error(1)
+}
+
+func (m *MockAPIKeyRepository) ListAPIKeys(ctx context.Context) ([]auth.APIKey, error) {
+	args := m.Called(ctx)
+	return args.Get(0).([]auth.APIKey), args.Error(1)
+}
+
+func (m *MockAPIKeyRepository) RevokeAPIKey(ctx context.Context, id uuid.UUID) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
+
+func (m *MockAPIKeyRepository) RotateAPIKey(ctx context.Context, id uuid.UUID, replacement *auth.APIKey, expiresAt time.Time) error {
+	args := m.Called(ctx, id, replacement, expiresAt)
+	return args.Error(0)
+}
+
+type AuthenticateTest struct {
+	Name             string
+	Secret           string
+	Mock             func(*MockAPIKeyRepository)
+	WaitingPrincipal *auth.Principal
+	WaitingError     error
+}
+
+func TestAPIKeyService_Authenticate(t *testing.T) {
+	secret := "wk_secret"
+	testID := uuid.New()
+	past := time.Now().Add(-time.Minute)
+	activeKey := &auth.APIKey{ID: testID, Name: "billing", Scopes: []string{auth.ScopeWalletRead}}
+
+	tests := []AuthenticateTest{
+		{
+			Name:   "Success Test",
+			Secret: secret,
+			Mock: func(r *MockAPIKeyRepository) {
+				r.On("GetAPIKeyByHash", mock.Anything, auth.HashAPIKey(secret)).Return(activeKey, nil)
+			},
+			WaitingPrincipal: &auth.Principal{ID: testID.String(), Name: "billing", Scopes: []string{auth.ScopeWalletRead}},
+		},
+		{
+			Name:         "Missing Key Test",
+			Secret:       "",
+			Mock:         func(r *MockAPIKeyRepository) {},
+			WaitingError: auth.ErrUnauthenticated,
+		},
+		{
+			Name:   "Unknown Key Test",
+			Secret: secret,
+			Mock: func(r *MockAPIKeyRepository) {
+				r.On("GetAPIKeyByHash", mock.Anything, mock.Anything).Return((*auth.APIKey)(nil), auth.ErrAPIKeyNotFound)
+			},
+			WaitingError: auth.ErrUnauthenticated,
+		},
+		{
+			Name:   "Expired Key Test",
+			Secret: secret,
+			Mock: func(r *MockAPIKeyRepository) {
+				r.On("GetAPIKeyByHash", mock.Anything, mock.Anything).Return(&auth.APIKey{ID: testID, ExpiresAt: &past}, nil)
+			},
+			WaitingError: auth.ErrUnauthenticated,
+		},
+		{
+			Name:   "Revoked Key Test",
+			Secret: secret,
+			Mock: func(r *MockAPIKeyRepository) {
+				r.On("GetAPIKeyByHash", mock.Anything, mock.Anything).Return(&auth.APIKey{ID: testID, RevokedAt: &past}, nil)
+			},
+			WaitingError: auth.ErrUnauthenticated,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			repo := new(MockAPIKeyRepository)
+			test.Mock(repo)
+			service := services.NewAPIKeyService(repo, time.Second, slog.New(slog.DiscardHandler), noop.NewTracerProvider())
+
+			principal, err := service.Authenticate(context.Background(), test.Secret)
+			if test.WaitingError != nil {
+				assert.ErrorIs(t, err, test.WaitingError)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, test.WaitingPrincipal, principal)
+			repo.AssertExpectations(t)
+		})
+	}
+}
+
+type IssueTest struct {
+	Name         string
+	KeyName      string
+	Scopes       []string
+	TTL          time.Duration
+	Mock         func(*MockAPIKeyRepository)
+	WaitingError error
+}
+
+func TestAPIKeyService_Issue(t *testing.T) {
+	dbErr := errors.New("db error")
+	tests := []IssueTest{
+		{
+			Name:    "Success Test",
+			KeyName: "billing",
+			Scopes:  []string{auth.ScopeWalletRead, auth.ScopeWalletWrite},
+			TTL:     time.Hour,
+			Mock: func(r *MockAPIKeyRepository) {
+				r.On("CreateAPIKey", mock.Anything, mock.MatchedBy(func(key *auth.APIKey) bool {
+					return key.Name == "billing" && len(key.Hash) == 64 && key.ExpiresAt != nil
+				})).Return(nil)
+			},
+		},
+		{
+			Name:         "Unknown Scope Test",
+			KeyName:      "billing",
+			Scopes:       []string{"wallet:admin"},
+			Mock:         func(r *MockAPIKeyRepository) {},
+			WaitingError: auth.ErrInvalidScope,
+		},
+		{
+			Name:         "Repository Error Test",
+			KeyName:      "billing",
+			Scopes:       []string{auth.ScopeWalletRead},
+			Mock:         func(r *MockAPIKeyRepository) { r.On("CreateAPIKey", mock.Anything, mock.Anything).Return(dbErr) },
+			WaitingError: dbErr,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			repo := new(MockAPIKeyRepository)
+			test.Mock(repo)
+			service := services.NewAPIKeyService(repo, time.Second, slog.New(slog.DiscardHandler), noop.NewTracerProvider())
+
+			secret, key, err := service.Issue(context.Background(), test.KeyName, test.Scopes, test.TTL)
+			if test.WaitingError != nil {
+				assert.ErrorIs(t, err, test.WaitingError)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, auth.HashAPIKey(secret), key.Hash)
+			}
+			repo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestAPIKeyService_Rotate(t *testing.T) {
+	testID := uuid.New()
+	old := &auth.APIKey{ID: testID, Name: "billing", Scopes: []string{auth.ScopeWalletWrite}}
+	repo := new(MockAPIKeyRepository)
+	repo.On("GetAPIKey", mock.Anything, testID).Return(old, nil)
+	repo.On("RotateAPIKey", mock.Anything, testID, mock.MatchedBy(func(key *auth.APIKey) bool {
+		return key.ID != testID && key.Name == old.Name && assert.ObjectsAreEqual(old.Scopes, key.Scopes)
+	}), mock.MatchedBy(func(expiresAt time.Time) bool {
+		return time.Until(expiresAt) > 59*time.Minute
+	})).Return(nil)
+	service := services.NewAPIKeyService(repo, time.Second, slog.New(slog.DiscardHandler), noop.NewTracerProvider())
+
+	secret, key, err := service.Rotate(context.Background(), testID, time.Hour)
+	assert.NoError(t, err)
+	assert.Equal(t, auth.HashAPIKey(secret), key.Hash)
+	repo.AssertExpectations(t)
+}
@@ -0,0 +1,167 @@
+package services
+
+import (
+	"backend/internal/tracing"
+	"backend/pkg/auth"
+	"backend/pkg/customerror"
+	"backend/pkg/wallet"
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// CreateHold reserves amount of wallet walletID until expiresAt, or for
+// wallet.DefaultHoldDuration when it is nil.
+func (WalletService *WalletService) CreateHold(ctx context.Context, walletID uuid.UUID, amount int64, currency string, expiresAt *time.Time) (_ *wallet.Hold, err error) {
+	ctx, span := WalletService.Tracer.Start(ctx, "WalletService.CreateHold", trace.WithAttributes(tracing.WalletIDKey.String(walletID.String())))
+	defer func() { tracing.End(span, err) }()
+
+	if amount <= 0 {
+		return nil, customerror.ErrInvalidAmount
+	}
+	now := time.Now()
+	expiry := now.Add(wallet.DefaultHoldDuration)
+	if expiresAt != nil {
+		expiry = *expiresAt
+	}
+	if !expiry.After(now) || expiry.After(now.Add(wallet.MaxHoldDuration)) {
+		return nil, wallet.ErrInvalidHoldExpiry
+	}
+	ctx, cancel := context.WithTimeout(ctx, WalletService.Timeout)
+	defer cancel()
+
+	err = WalletService.checkWallet(ctx, walletID, currency)
+	if errors.Is(err, wallet.ErrNotFound) || errors.Is(err, wallet.ErrForbidden) || errors.Is(err, wallet.ErrCurrencyMismatch) {
+		return nil, err
+	}
+	if err != nil {
+		return nil, customerror.AppendModule(err, "CreateHold")
+	}
+	hold, err := WalletService.Repo.CreateHold(ctx, uuid.New(), walletID, amount, expiry.UTC())
+	if err == nil {
+		WalletService.Metrics.ObserveOperation(wallet.OperationHold, amount)
+		WalletService.Logger.InfoContext(ctx, "hold created",
+			slog.String("hold_id", hold.ID.String()),
+			slog.Int64("amount", amount),
+			slog.Time("expires_at", hold.ExpiresAt),
+		)
+		return hold, nil
+	}
+	if errors.Is(err, customerror.ErrWrongAmount) {
+		WalletService.Metrics.IncInsufficientFunds(wallet.OperationHold)
+		WalletService.Logger.InfoContext(ctx, "insufficient funds",
+			slog.String("operation_type", wallet.OperationHold),
+			slog.Int64("amount", amount),
+		)
+		return nil, err
+	}
+	if errors.Is(err, wallet.ErrNotFound) || errors.Is(err, customerror.ErrAmountOutOfRange) {
+		return nil, err
+	}
+	return nil, customerror.AppendModule(err, "CreateHold")
+}
+
+func (WalletService *WalletService) GetHold(ctx context.Context, id uuid.UUID) (_ *wallet.Hold, err error) {
+	ctx, span := WalletService.Tracer.Start(ctx, "WalletService.GetHold", trace.WithAttributes(tracing.HoldIDKey.String(id.String())))
+	defer func() { tracing.End(span, err) }()
+
+	ctx, cancel := context.WithTimeout(ctx, WalletService.Timeout)
+	defer cancel()
+
+	hold, err := WalletService.Repo.GetHold(ctx, id)
+	if errors.Is(err, wallet.ErrHoldNotFound) {
+		return nil, err
+	}
+	if err != nil {
+		return nil, customerror.AppendModule(err, "GetHold")
+	}
+	err = WalletService.checkWallet(ctx, hold.WalletID, "")
+	if errors.Is(err, wallet.ErrForbidden) {
+		return nil, err
+	}
+	if err != nil {
+		return nil, customerror.AppendModule(err, "GetHold")
+	}
+	return hold, nil
+}
+
+// checkHold makes sure a restricted caller owns the wallet of hold id before
+// it is captured or released. Other callers skip the lookup.
+func (WalletService *WalletService) checkHold(ctx context.Context, id uuid.UUID) error {
+	principal := auth.FromContext(ctx)
+	if principal == nil || !principal.Restricted() {
+		return nil
+	}
+	hold, err := WalletService.Repo.GetHold(ctx, id)
+	if err != nil {
+		return err
+	}
+	return WalletService.checkWallet(ctx, hold.WalletID, "")
+}
+
+// CaptureHold spends amount of hold id, the whole hold when amount is nil.
+func (WalletService *WalletService) CaptureHold(ctx context.Context, id uuid.UUID, amount *int64) (_ *wallet.Hold, err error) {
+	ctx, span := WalletService.Tracer.Start(ctx, "WalletService.CaptureHold", trace.WithAttributes(tracing.HoldIDKey.String(id.String())))
+	defer func() { tracing.End(span, err) }()
+
+	if amount != nil && *amount <= 0 {
+		return nil, customerror.ErrInvalidAmount
+	}
+	ctx, cancel := context.WithTimeout(ctx, WalletService.Timeout)
+	defer cancel()
+
+	err = WalletService.checkHold(ctx, id)
+	if errors.Is(err, wallet.ErrHoldNotFound) || errors.Is(err, wallet.ErrForbidden) {
+		return nil, err
+	}
+	if err != nil {
+		return nil, customerror.AppendModule(err, "CaptureHold")
+	}
+	hold, err := WalletService.Repo.CaptureHold(ctx, id, amount)
+	if err == nil {
+		WalletService.Metrics.ObserveOperation(wallet.OperationCapture, hold.Captured)
+		WalletService.Logger.InfoContext(ctx, "hold captured",
+			slog.String("hold_id", hold.ID.String()),
+			slog.Int64("amount", hold.Captured),
+			slog.Int64("released", hold.Amount-hold.Captured),
+		)
+		return hold, nil
+	}
+	if errors.Is(err, wallet.ErrHoldNotFound) || errors.Is(err, wallet.ErrHoldNotActive) ||
+		errors.Is(err, wallet.ErrHoldExpired) || errors.Is(err, customerror.ErrInvalidAmount) {
+		return nil, err
+	}
+	return nil, customerror.AppendModule(err, "CaptureHold")
+}
+
+func (WalletService *WalletService) ReleaseHold(ctx context.Context, id uuid.UUID) (_ *wallet.Hold, err error) {
+	ctx, span := WalletService.Tracer.Start(ctx, "WalletService.ReleaseHold", trace.WithAttributes(tracing.HoldIDKey.String(id.String())))
+	defer func() { tracing.End(span, err) }()
+
+	ctx, cancel := context.WithTimeout(ctx, WalletService.Timeout)
+	defer cancel()
+
+	err = WalletService.checkHold(ctx, id)
+	if errors.Is(err, wallet.ErrHoldNotFound) || errors.Is(err, wallet.ErrForbidden) {
+		return nil, err
+	}
+	if err != nil {
+		return nil, customerror.AppendModule(err, "ReleaseHold")
+	}
+	hold, err := WalletService.Repo.ReleaseHold(ctx, id)
+	if err == nil {
+		WalletService.Logger.InfoContext(ctx, "hold released",
+			slog.String("hold_id", hold.ID.String()),
+			slog.Int64("amount", hold.Amount),
+		)
+		return hold, nil
+	}
+	if errors.Is(err, wallet.ErrHoldNotFound) || errors.Is(err, wallet.ErrHoldNotActive) {
+		return nil, err
+	}
+	return nil, customerror.AppendModule(err, "ReleaseHold")
+}
@@ -0,0 +1,211 @@
+package services_test
+
+import (
+	"backend/internal/metrics"
+	"backend/internal/services"
+	"backend/pkg/auth"
+	"backend/pkg/customerror"
+	"backend/pkg/wallet"
+	"context"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+type CreateHoldTest struct {
+	Name         string
+	Amount       int64
+	ExpiresAt    *time.Time
+	Mock         func(*MockRepository)
+	WaitingError error
+}
+
+func TestWalletService_CreateHold(t *testing.T) {
+	walletID := uuid.New()
+	hold := &wallet.Hold{ID: uuid.New(), WalletID: walletID, Amount: 300, Status: wallet.HoldStatusActive}
+	inAnHour := time.Now().Add(time.Hour)
+	past := time.Now().Add(-time.Minute)
+	tooLate := time.Now().Add(wallet.MaxHoldDuration + time.Hour)
+	defaultExpiry := mock.MatchedBy(func(expiresAt time.Time) bool {
+		return time.Until(expiresAt) > wallet.DefaultHoldDuration-time.Minute && time.Until(expiresAt) <= wallet.DefaultHoldDuration
+	})
+
+	tests := []CreateHoldTest{
+		{
+			Name:   "Default Expiry Test",
+			Amount: 300,
+			Mock: func(r *MockRepository) {
+				r.On("CreateHold", mock.Anything, mock.Anything, walletID, int64(300), defaultExpiry).Return(hold, nil)
+			},
+		},
+		{
+			Name:      "Given Expiry Test",
+			Amount:    300,
+			ExpiresAt: &inAnHour,
+			Mock: func(r *MockRepository) {
+				r.On("CreateHold", mock.Anything, mock.Anything, walletID, int64(300), inAnHour.UTC()).Return(hold, nil)
+			},
+		},
+		{
+			Name:         "Zero Amount Test",
+			Amount:       0,
+			Mock:         func(r *MockRepository) {},
+			WaitingError: customerror.ErrInvalidAmount,
+		},
+		{
+			Name:         "Past Expiry Test",
+			Amount:       300,
+			ExpiresAt:    &past,
+			Mock:         func(r *MockRepository) {},
+			WaitingError: wallet.ErrInvalidHoldExpiry,
+		},
+		{
+			Name:         "Expiry Too Far Test",
+			Amount:       300,
+			ExpiresAt:    &tooLate,
+			Mock:         func(r *MockRepository) {},
+			WaitingError: wallet.ErrInvalidHoldExpiry,
+		},
+		{
+			Name:   "Insufficient Funds Test",
+			Amount: 300,
+			Mock: func(r *MockRepository) {
+				r.On("CreateHold", mock.Anything, mock.Anything, walletID, int64(300), mock.Anything).Return((*wallet.Hold)(nil), customerror.ErrWrongAmount)
+			},
+			WaitingError: customerror.ErrWrongAmount,
+		},
+		{
+			Name:   "Repository Error Test",
+			Amount: 300,
+			Mock: func(r *MockRepository) {
+				r.On("CreateHold", mock.Anything, mock.Anything, walletID, int64(300), mock.Anything).
+					Return((*wallet.Hold)(nil), customerror.NewError("walletRepo.CreateHold", "127.0.0.1:8080", "error"))
+			},
+			WaitingError: customerror.NewError("CreateHold.walletRepo.CreateHold", "127.0.0.1:8080", "error"),
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			mockRepo := new(MockRepository)
+			test.Mock(mockRepo)
+			service := services.NewWalletService(mockRepo, 5*time.Second, "USD", metrics.NewNop(), slog.New(slog.DiscardHandler), noop.NewTracerProvider())
+
+			result, err := service.CreateHold(context.Background(), walletID, test.Amount, "", test.ExpiresAt)
+			if test.WaitingError != nil {
+				assert.Nil(t, result)
+				assert.EqualError(t, err, test.WaitingError.Error())
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, hold, result)
+			}
+			mockRepo.AssertExpectations(t)
+		})
+	}
+}
+
+type CaptureHoldTest struct {
+	Name         string
+	Amount       *int64
+	Mock         func(*MockRepository)
+	WaitingError error
+}
+
+func TestWalletService_CaptureHold(t *testing.T) {
+	holdID := uuid.New()
+	captured := &wallet.Hold{ID: holdID, Amount: 100, Captured: 60, Status: wallet.HoldStatusCaptured}
+	amount := func(value int64) *int64 { return &value }
+
+	tests := []CaptureHoldTest{
+		{
+			Name:   "Success Test",
+			Amount: amount(60),
+			Mock: func(r *MockRepository) {
+				r.On("CaptureHold", mock.Anything, holdID, amount(60)).Return(captured, nil)
+			},
+		},
+		{
+			Name: "Full Capture Test",
+			Mock: func(r *MockRepository) {
+				r.On("CaptureHold", mock.Anything, holdID, (*int64)(nil)).Return(captured, nil)
+			},
+		},
+		{
+			Name:         "Negative Amount Test",
+			Amount:       amount(-1),
+			Mock:         func(r *MockRepository) {},
+			WaitingError: customerror.ErrInvalidAmount,
+		},
+		{
+			Name:         "Zero Amount Test",
+			Amount:       amount(0),
+			Mock:         func(r *MockRepository) {},
+			WaitingError: customerror.ErrInvalidAmount,
+		},
+		{
+			Name: "Expired Test",
+			Mock: func(r *MockRepository) {
+				r.On("CaptureHold", mock.Anything, holdID, (*int64)(nil)).Return((*wallet.Hold)(nil), wallet.ErrHoldExpired)
+			},
+			WaitingError: wallet.ErrHoldExpired,
+		},
+		{
+			Name: "Not Active Test",
+			Mock: func(r *MockRepository) {
+				r.On("CaptureHold", mock.Anything, holdID, (*int64)(nil)).Return((*wallet.Hold)(nil), wallet.ErrHoldNotActive)
+			},
+			WaitingError: wallet.ErrHoldNotActive,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			mockRepo := new(MockRepository)
+			test.Mock(mockRepo)
+			service := services.NewWalletService(mockRepo, 5*time.Second, "USD", metrics.NewNop(), slog.New(slog.DiscardHandler), noop.NewTracerProvider())
+
+			result, err := service.CaptureHold(context.Background(), holdID, test.Amount)
+			if test.WaitingError != nil {
+				assert.ErrorIs(t, err, test.WaitingError)
+				assert.Nil(t, result)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, captured, result)
+			}
+			mockRepo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestWalletService_HoldOwnership(t *testing.T) {
+	walletID := uuid.New()
+	owner := "user-1"
+	stranger := "user-2"
+	hold := &wallet.Hold{ID: uuid.New(), WalletID: walletID, Amount: 100, Status: wallet.HoldStatusActive}
+
+	repo := new(MockRepository)
+	repo.On("GetHold", mock.Anything, hold.ID).Return(hold, nil)
+	repo.On("GetWallet", mock.Anything, walletID).Return(&wallet.Wallet{ID: walletID, OwnerID: &stranger}, nil)
+	service := services.NewWalletService(repo, time.Second, "USD", metrics.NewNop(), slog.New(slog.DiscardHandler), noop.NewTracerProvider())
+
+	ctx := auth.NewContext(context.Background(), &auth.Principal{Subject: owner})
+	_, err := service.GetHold(ctx, hold.ID)
+	assert.ErrorIs(t, err, wallet.ErrForbidden)
+	_, err = service.CaptureHold(ctx, hold.ID, nil)
+	assert.ErrorIs(t, err, wallet.ErrForbidden)
+	_, err = service.ReleaseHold(ctx, hold.ID)
+	assert.ErrorIs(t, err, wallet.ErrForbidden)
+
+	// Trusted callers release without the ownership lookup.
+	released := *hold
+	released.Status = wallet.HoldStatusReleased
+	repo.On("ReleaseHold", mock.Anything, hold.ID).Return(&released, nil).Once()
+	result, err := service.ReleaseHold(context.Background(), hold.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, wallet.HoldStatusReleased, result.Status)
+	repo.AssertNumberOfCalls(t, "GetHold", 3)
+	repo.AssertExpectations(t)
+}
@@ -0,0 +1,92 @@
+package services
+
+import (
+	"backend/internal/repos"
+	"backend/internal/tracing"
+	"backend/pkg/customerror"
+	"backend/pkg/wallet"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// MaxRatesPerImport bounds a single import, which runs in one transaction.
+const MaxRatesPerImport = 1000
+
+type RateServiceI interface {
+	Import(ctx context.Context, rates []wallet.ExchangeRate) ([]wallet.ExchangeRate, error)
+	ListCurrent(ctx context.Context) ([]wallet.ExchangeRate, error)
+}
+
+type RateService struct {
+	Repo    repos.RateRepositoryI
+	Timeout time.Duration
+	Logger  *slog.Logger
+	Tracer  trace.Tracer
+}
+
+func NewRateService(repo repos.RateRepositoryI, timeout time.Duration, logger *slog.Logger, tracerProvider trace.TracerProvider) RateServiceI {
+	return &RateService{
+		Repo:    repo,
+		Timeout: timeout,
+		Logger:  logger,
+		Tracer:  tracerProvider.Tracer("backend/internal/services"),
+	}
+}
+
+// Import validates rates and stores all of them or none. Rates without a
+// ValidFrom take effect immediately. Rates are never updated in place: a
+// newer ValidFrom supersedes older rates of the pair, and transfers keep
+// pointing at the rate they used.
+func (RateService *RateService) Import(ctx context.Context, rates []wallet.ExchangeRate) (_ []wallet.ExchangeRate, err error) {
+	ctx, span := RateService.Tracer.Start(ctx, "RateService.Import", trace.WithAttributes(tracing.RowCountKey.Int(len(rates))))
+	defer func() { tracing.End(span, err) }()
+
+	if len(rates) == 0 || len(rates) > MaxRatesPerImport {
+		return nil, fmt.Errorf("%w: an import takes 1 to %d rates", wallet.ErrInvalidExchangeRate, MaxRatesPerImport)
+	}
+	now := time.Now().UTC()
+	imported := make([]wallet.ExchangeRate, len(rates))
+	for i, rate := range rates {
+		rate.ID = uuid.New()
+		if rate.ValidFrom.IsZero() {
+			rate.ValidFrom = now
+		}
+		if err = rate.Validate(); err != nil {
+			return nil, fmt.Errorf("rate %d: %w", i+1, err)
+		}
+		imported[i] = rate
+	}
+	ctx, cancel := context.WithTimeout(ctx, RateService.Timeout)
+	defer cancel()
+
+	err = RateService.Repo.CreateRates(ctx, imported)
+	if errors.Is(err, wallet.ErrUnknownCurrency) {
+		return nil, err
+	}
+	if err != nil {
+		return nil, customerror.AppendModule(err, "Import")
+	}
+	RateService.Logger.InfoContext(ctx, "exchange rates imported", slog.Int("count", len(imported)))
+	return imported, nil
+}
+
+// ListCurrent returns the rate transfers would use right now for every pair.
+func (RateService *RateService) ListCurrent(ctx context.Context) (_ []wallet.ExchangeRate, err error) {
+	ctx, span := RateService.Tracer.Start(ctx, "RateService.ListCurrent")
+	defer func() { tracing.End(span, err) }()
+
+	ctx, cancel := context.WithTimeout(ctx, RateService.Timeout)
+	defer cancel()
+
+	rates, err := RateService.Repo.ListCurrentRates(ctx)
+	if err != nil {
+		return nil, customerror.AppendModule(err, "ListCurrent")
+	}
+	return rates, nil
+}
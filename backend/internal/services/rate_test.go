@@ -0,0 +1,120 @@
+package services_test
+
+import (
+	"backend/internal/services"
+	"backend/pkg/customerror"
+	"backend/pkg/wallet"
+	"context"
+	"errors"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+type MockRateRepository struct {
+	mock.Mock
+}
+
+func (m *MockRateRepository) CreateRates(ctx context.Context, rates []wallet.ExchangeRate) error {
+	args := m.Called(ctx, rates)
+	return args.Error(0)
+}
+
+func (m *MockRateRepository) ListCurrentRates(ctx context.Context) ([]wallet.ExchangeRate, error) {
+	args := m.Called(ctx)
+	return args.Get(0).([]wallet.ExchangeRate), args.Error(1)
+}
+
+type ImportRatesTest struct {
+	Name         string
+	Rates        []wallet.ExchangeRate
+	Mock         func(*MockRateRepository)
+	WaitingError error
+}
+
+func TestRateService_Import(t *testing.T) {
+	validFrom := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	validTo := validFrom.Add(-time.Hour)
+
+	tests := []ImportRatesTest{
+		{
+			Name: "Success Test",
+			Rates: []wallet.ExchangeRate{
+				{Base: "USD", Quote: "EUR", Rate: "0.92", ValidFrom: validFrom, Source: "ecb"},
+				{Base: "EUR", Quote: "USD", Rate: "1.087", Source: "ecb"},
+			},
+			Mock: func(r *MockRateRepository) {
+				r.On("CreateRates", mock.Anything, mock.MatchedBy(func(rates []wallet.ExchangeRate) bool {
+					return len(rates) == 2 && rates[0].ValidFrom.Equal(validFrom) && !rates[1].ValidFrom.IsZero()
+				})).Return(nil)
+			},
+		},
+		{
+			Name:         "Empty Test",
+			Rates:        []wallet.ExchangeRate{},
+			Mock:         func(r *MockRateRepository) {},
+			WaitingError: wallet.ErrInvalidExchangeRate,
+		},
+		{
+			Name:         "Invalid Rate Test",
+			Rates:        []wallet.ExchangeRate{{Base: "USD", Quote: "EUR", Rate: "-1", Source: "ecb"}},
+			Mock:         func(r *MockRateRepository) {},
+			WaitingError: wallet.ErrInvalidExchangeRate,
+		},
+		{
+			Name:         "Inverted Window Test",
+			Rates:        []wallet.ExchangeRate{{Base: "USD", Quote: "EUR", Rate: "0.92", ValidFrom: validFrom, ValidTo: &validTo, Source: "ecb"}},
+			Mock:         func(r *MockRateRepository) {},
+			WaitingError: wallet.ErrInvalidExchangeRate,
+		},
+		{
+			Name:  "Unknown Currency Test",
+			Rates: []wallet.ExchangeRate{{Base: "USD", Quote: "XXX", Rate: "2", Source: "ecb"}},
+			Mock: func(r *MockRateRepository) {
+				r.On("CreateRates", mock.Anything, mock.Anything).Return(wallet.ErrUnknownCurrency)
+			},
+			WaitingError: wallet.ErrUnknownCurrency,
+		},
+		{
+			Name:  "Repository Error Test",
+			Rates: []wallet.ExchangeRate{{Base: "USD", Quote: "EUR", Rate: "0.92", Source: "ecb"}},
+			Mock: func(r *MockRateRepository) {
+				r.On("CreateRates", mock.Anything, mock.Anything).Return(customerror.NewError("rateRepo.CreateRates", "", "error"))
+			},
+			WaitingError: customerror.ErrInternal,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			mockRepo := new(MockRateRepository)
+			test.Mock(mockRepo)
+			service := services.NewRateService(mockRepo, time.Second, slog.New(slog.DiscardHandler), noop.NewTracerProvider())
+
+			rates, err := service.Import(context.Background(), test.Rates)
+			if test.WaitingError != nil {
+				assert.ErrorIs(t, err, test.WaitingError)
+				assert.Nil(t, rates)
+			} else {
+				assert.NoError(t, err)
+				assert.Len(t, rates, len(test.Rates))
+				assert.NotEqual(t, rates[0].ID, rates[1].ID)
+			}
+			mockRepo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestRateService_ListCurrent(t *testing.T) {
+	mockRepo := new(MockRateRepository)
+	mockRepo.On("ListCurrentRates", mock.Anything).Return([]wallet.ExchangeRate(nil), errors.New("error"))
+	service := services.NewRateService(mockRepo, time.Second, slog.New(slog.DiscardHandler), noop.NewTracerProvider())
+
+	rates, err := service.ListCurrent(context.Background())
+	assert.Error(t, err)
+	assert.Nil(t, rates)
+	mockRepo.AssertExpectations(t)
+}
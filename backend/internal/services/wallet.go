@@ -1,59 +1,359 @@
 package services
 
 import (
+	"backend/internal/metrics"
 	"backend/internal/repos"
+	"backend/internal/tracing"
+	"backend/pkg/auth"
 	"backend/pkg/customerror"
+	"backend/pkg/requests"
+	"backend/pkg/wallet"
 	"context"
+	"errors"
+	"log/slog"
 	"time"
 
 	"github.com/google/uuid"
-	"github.com/jackc/pgx/v5"
+	"go.opentelemetry.io/otel/trace"
 )
 
 type WalletServiceI interface {
-	GetBalance(id uuid.UUID) (int64, error)
-	UpdateBalance(id uuid.UUID, operationType string, amount int64) error
+	CreateWallet(ctx context.Context, id uuid.UUID, amount int64, currency string) (*wallet.Wallet, error)
+	GetBalance(ctx context.Context, id uuid.UUID) (*wallet.Wallet, error)
+	GetCurrency(ctx context.Context, code string) (*wallet.Currency, error)
+	UpdateBalance(ctx context.Context, id uuid.UUID, operationType string, amount int64, currency string, idempotencyKey string) (*wallet.Transaction, error)
+	GetTransactions(ctx context.Context, id uuid.UUID, request requests.GetTransactionsRequest) (*wallet.TransactionPage, error)
+	Transfer(ctx context.Context, fromID uuid.UUID, toID uuid.UUID, amount int64, currency string) (*wallet.Transfer, error)
+	CreateHold(ctx context.Context, walletID uuid.UUID, amount int64, currency string, expiresAt *time.Time) (*wallet.Hold, error)
+	GetHold(ctx context.Context, id uuid.UUID) (*wallet.Hold, error)
+	CaptureHold(ctx context.Context, id uuid.UUID, amount *int64) (*wallet.Hold, error)
+	ReleaseHold(ctx context.Context, id uuid.UUID) (*wallet.Hold, error)
 }
 
+const (
+	defaultTransactionsLimit = 20
+	maxTransactionsLimit     = 100
+)
+
 type WalletService struct {
 	Repo repos.WalletRepositoryI
+	// Timeout bounds every repository call on top of the caller's context.
+	Timeout time.Duration
+	// DefaultCurrency is given to wallets created without a currency.
+	DefaultCurrency string
+	Metrics         metrics.RecorderI
+	Logger          *slog.Logger
+	Tracer          trace.Tracer
 }
 
-func NewWalletService(repo repos.WalletRepositoryI) WalletServiceI {
+func NewWalletService(repo repos.WalletRepositoryI, timeout time.Duration, defaultCurrency string, recorder metrics.RecorderI, logger *slog.Logger, tracerProvider trace.TracerProvider) WalletServiceI {
 	return &WalletService{
-		Repo: repo,
+		Repo:            repo,
+		Timeout:         timeout,
+		DefaultCurrency: defaultCurrency,
+		Metrics:         recorder,
+		Logger:          logger,
+		Tracer:          tracerProvider.Tracer("backend/internal/services"),
+	}
+}
+
+func (WalletService *WalletService) CreateWallet(ctx context.Context, id uuid.UUID, amount int64, currency string) (_ *wallet.Wallet, err error) {
+	ctx, span := WalletService.Tracer.Start(ctx, "WalletService.CreateWallet")
+	defer func() { tracing.End(span, err) }()
+
+	if amount < 0 {
+		return nil, customerror.ErrInvalidAmount
+	}
+	if currency == "" {
+		currency = WalletService.DefaultCurrency
+	}
+	if !wallet.ValidCurrencyCode(currency) {
+		return nil, wallet.ErrUnknownCurrency
+	}
+	if id == uuid.Nil {
+		id = uuid.New()
 	}
+	span.SetAttributes(tracing.WalletIDKey.String(id.String()))
+	ctx, cancel := context.WithTimeout(ctx, WalletService.Timeout)
+	defer cancel()
+
+	// Wallets created by end users belong to them; services and admins create
+	// wallets that no end user owns, rather than wallets only they could use.
+	var ownerID *string
+	if principal := auth.FromContext(ctx); principal != nil && principal.Restricted() {
+		ownerID = &principal.Subject
+	}
+	createdWallet, err := WalletService.Repo.CreateWallet(ctx, id, amount, currency, ownerID)
+	if err == nil {
+		WalletService.Logger.InfoContext(ctx, "wallet created",
+			slog.String("created_wallet_id", createdWallet.ID.String()),
+			slog.Int64("amount", createdWallet.Amount),
+			slog.String("currency", createdWallet.Currency.Code),
+		)
+		return createdWallet, nil
+	}
+	if errors.Is(err, wallet.ErrAlreadyExists) || errors.Is(err, customerror.ErrInvalidAmount) || errors.Is(err, wallet.ErrUnknownCurrency) {
+		return nil, err
+	}
+	return nil, customerror.AppendModule(err, "CreateWallet")
 }
 
-func (WalletService *WalletService) GetBalance(id uuid.UUID) (int64, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+func (WalletService *WalletService) GetBalance(ctx context.Context, id uuid.UUID) (_ *wallet.Wallet, err error) {
+	ctx, span := WalletService.Tracer.Start(ctx, "WalletService.GetBalance", trace.WithAttributes(tracing.WalletIDKey.String(id.String())))
+	defer func() { tracing.End(span, err) }()
+
+	ctx, cancel := context.WithTimeout(ctx, WalletService.Timeout)
 	defer cancel()
-	wallet, err := WalletService.Repo.GetWallet(ctx, id)
+	foundWallet, err := WalletService.Repo.GetWallet(ctx, id)
 	if err == nil {
-		return wallet.Amount, nil
+		if !mayUse(ctx, foundWallet) {
+			return nil, wallet.ErrForbidden
+		}
+		return foundWallet, nil
 	}
-	if err == pgx.ErrNoRows {
-		return 0, err
+	if errors.Is(err, wallet.ErrNotFound) {
+		return nil, err
 	}
-	customError := err.(customerror.CustomError)
-	customError.AppendModule("GetBalance")
-	return 0, customError
+	return nil, customerror.AppendModule(err, "GetBalance")
 }
-func (WalletService *WalletService) UpdateBalance(id uuid.UUID, operationType string, amount int64) error {
-	if operationType != "DEPOSIT" && operationType != "WITHDRAW" {
-		return customerror.ErrWrongOperation
+
+// GetCurrency looks up a supported currency; an empty code is the currency
+// CreateWallet would give the wallet.
+func (WalletService *WalletService) GetCurrency(ctx context.Context, code string) (_ *wallet.Currency, err error) {
+	ctx, span := WalletService.Tracer.Start(ctx, "WalletService.GetCurrency")
+	defer func() { tracing.End(span, err) }()
+
+	if code == "" {
+		code = WalletService.DefaultCurrency
 	}
-	if operationType == "WITHDRAW" {
-		amount = -amount
+	if !wallet.ValidCurrencyCode(code) {
+		return nil, wallet.ErrUnknownCurrency
 	}
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	ctx, cancel := context.WithTimeout(ctx, WalletService.Timeout)
 	defer cancel()
+	currency, err := WalletService.Repo.GetCurrency(ctx, code)
+	if err == nil || errors.Is(err, wallet.ErrUnknownCurrency) {
+		return currency, err
+	}
+	return nil, customerror.AppendModule(err, "GetCurrency")
+}
 
-	err := WalletService.Repo.UpdateWallet(ctx, id, amount)
-	if err == nil || err == customerror.ErrWrongAmount || err == pgx.ErrNoRows {
+// mayUse reports whether the caller in ctx may act on foundWallet. Calls
+// without a caller come from inside the process and are trusted.
+func mayUse(ctx context.Context, foundWallet *wallet.Wallet) bool {
+	principal := auth.FromContext(ctx)
+	return principal == nil || principal.Owns(foundWallet.OwnerID)
+}
+
+// checkWallet makes sure the caller may use the wallet and, when currency is
+// given, that the wallet holds that currency. The wallet is only loaded when
+// there is something to check, so service callers that send no currency pay
+// no extra query. Neither the owner nor the currency of a wallet ever changes,
+// so the check cannot go stale before the update.
+func (WalletService *WalletService) checkWallet(ctx context.Context, id uuid.UUID, currency string) error {
+	principal := auth.FromContext(ctx)
+	restricted := principal != nil && principal.Restricted()
+	if !restricted && currency == "" {
+		return nil
+	}
+	foundWallet, err := WalletService.Repo.GetWallet(ctx, id)
+	if err != nil {
 		return err
 	}
-	customError := err.(customerror.CustomError)
-	customError.AppendModule("UpdateBalance")
-	return customError
+	if restricted && !principal.Owns(foundWallet.OwnerID) {
+		return wallet.ErrForbidden
+	}
+	if currency != "" && currency != foundWallet.Currency.Code {
+		return wallet.ErrCurrencyMismatch
+	}
+	return nil
+}
+
+func (WalletService *WalletService) UpdateBalance(ctx context.Context, id uuid.UUID, operationType string, amount int64, currency string, idempotencyKey string) (_ *wallet.Transaction, err error) {
+	ctx, span := WalletService.Tracer.Start(ctx, "WalletService.UpdateBalance", trace.WithAttributes(
+		tracing.WalletIDKey.String(id.String()),
+		tracing.OperationTypeKey.String(operationType),
+	))
+	defer func() { tracing.End(span, err) }()
+
+	if operationType != wallet.OperationDeposit && operationType != wallet.OperationWithdraw {
+		return nil, customerror.ErrWrongOperation
+	}
+	// The sign comes from operationType; a negative amount would turn a
+	// withdrawal into a credit.
+	if amount <= 0 {
+		return nil, customerror.ErrInvalidAmount
+	}
+	if len(idempotencyKey) > wallet.MaxIdempotencyKeyLength {
+		return nil, customerror.ErrWrongIdempotencyKey
+	}
+	var key *wallet.IdempotencyKey
+	if idempotencyKey != "" {
+		var clientID string
+		if principal := auth.FromContext(ctx); principal != nil {
+			clientID = principal.ID
+		}
+		key = wallet.NewIdempotencyKey(clientID, idempotencyKey, id, operationType, amount)
+	}
+	delta := amount
+	if operationType == wallet.OperationWithdraw {
+		delta = -amount
+	}
+	ctx, cancel := context.WithTimeout(ctx, WalletService.Timeout)
+	defer cancel()
+
+	err = WalletService.checkWallet(ctx, id, currency)
+	if errors.Is(err, wallet.ErrNotFound) || errors.Is(err, wallet.ErrForbidden) || errors.Is(err, wallet.ErrCurrencyMismatch) {
+		return nil, err
+	}
+	if err != nil {
+		return nil, customerror.AppendModule(err, "UpdateBalance")
+	}
+	transaction, err := WalletService.Repo.UpdateWallet(ctx, id, operationType, delta, key)
+	if err == nil {
+		// A replay moved no money, so it is not counted a second time.
+		if !transaction.Replayed {
+			WalletService.Metrics.ObserveOperation(operationType, amount)
+		}
+		WalletService.Logger.InfoContext(ctx, "balance updated",
+			slog.String("operation_type", operationType),
+			slog.Int64("amount", amount),
+			slog.String("transaction_id", transaction.ID.String()),
+			slog.Bool("replayed", transaction.Replayed),
+		)
+		return transaction, nil
+	}
+	if errors.Is(err, customerror.ErrWrongAmount) {
+		WalletService.Metrics.IncInsufficientFunds(operationType)
+		WalletService.Logger.InfoContext(ctx, "insufficient funds",
+			slog.String("operation_type", operationType),
+			slog.Int64("amount", amount),
+		)
+		return nil, err
+	}
+	if errors.Is(err, customerror.ErrIdempotencyKeyReused) || errors.Is(err, wallet.ErrNotFound) || errors.Is(err, customerror.ErrAmountOutOfRange) {
+		return nil, err
+	}
+	return nil, customerror.AppendModule(err, "UpdateBalance")
+}
+
+func (WalletService *WalletService) GetTransactions(ctx context.Context, id uuid.UUID, request requests.GetTransactionsRequest) (_ *wallet.TransactionPage, err error) {
+	ctx, span := WalletService.Tracer.Start(ctx, "WalletService.GetTransactions", trace.WithAttributes(tracing.WalletIDKey.String(id.String())))
+	defer func() { tracing.End(span, err) }()
+
+	switch request.OperationType {
+	case "", wallet.OperationDeposit, wallet.OperationWithdraw, wallet.OperationTransferIn, wallet.OperationTransferOut,
+		wallet.OperationHold, wallet.OperationCapture, wallet.OperationRelease:
+	default:
+		return nil, customerror.ErrWrongOperation
+	}
+	filter := wallet.TransactionFilter{
+		WalletID:      id,
+		OperationType: request.OperationType,
+		From:          request.From,
+		To:            request.To,
+		Ascending:     request.Order == "oldest",
+		Limit:         request.Limit,
+	}
+	if filter.Limit <= 0 {
+		filter.Limit = defaultTransactionsLimit
+	}
+	if filter.Limit > maxTransactionsLimit {
+		filter.Limit = maxTransactionsLimit
+	}
+	if request.Cursor != "" {
+		cursor, err := wallet.DecodeTransactionCursor(request.Cursor)
+		if err != nil {
+			return nil, err
+		}
+		filter.Cursor = cursor
+	}
+	ctx, cancel := context.WithTimeout(ctx, WalletService.Timeout)
+	defer cancel()
+
+	foundWallet, err := WalletService.Repo.GetWallet(ctx, id)
+	if errors.Is(err, wallet.ErrNotFound) {
+		return nil, err
+	}
+	if err != nil {
+		return nil, customerror.AppendModule(err, "GetTransactions")
+	}
+	if !mayUse(ctx, foundWallet) {
+		return nil, wallet.ErrForbidden
+	}
+
+	// One extra row tells whether another page follows without a COUNT query.
+	pageFilter := filter
+	pageFilter.Limit = filter.Limit + 1
+	transactions, err := WalletService.Repo.ListTransactions(ctx, pageFilter)
+	if err != nil {
+		return nil, customerror.AppendModule(err, "GetTransactions")
+	}
+	page := &wallet.TransactionPage{
+		Transactions: transactions,
+		Currency:     foundWallet.Currency,
+	}
+	if len(transactions) > filter.Limit {
+		page.Transactions = transactions[:filter.Limit]
+		page.NextCursor = wallet.NewTransactionCursor(page.Transactions[filter.Limit-1]).Encode()
+	}
+	return page, nil
+}
+
+func (WalletService *WalletService) Transfer(ctx context.Context, fromID uuid.UUID, toID uuid.UUID, amount int64, currency string) (_ *wallet.Transfer, err error) {
+	ctx, span := WalletService.Tracer.Start(ctx, "WalletService.Transfer", trace.WithAttributes(
+		tracing.WalletIDKey.String(fromID.String()),
+		tracing.ToWalletIDKey.String(toID.String()),
+	))
+	defer func() { tracing.End(span, err) }()
+
+	if amount <= 0 {
+		return nil, customerror.ErrInvalidAmount
+	}
+	if fromID == toID {
+		return nil, customerror.ErrSameWallet
+	}
+	ctx, cancel := context.WithTimeout(ctx, WalletService.Timeout)
+	defer cancel()
+
+	// Paying into someone else's wallet is fine; only the source is checked,
+	// and the handler leaves out the balance of a destination the caller does
+	// not own. The amount is in the currency of the source wallet; the repository
+	// converts it when the destination holds another currency.
+	err = WalletService.checkWallet(ctx, fromID, currency)
+	if errors.Is(err, wallet.ErrNotFound) || errors.Is(err, wallet.ErrForbidden) || errors.Is(err, wallet.ErrCurrencyMismatch) {
+		return nil, err
+	}
+	if err != nil {
+		return nil, customerror.AppendModule(err, "Transfer")
+	}
+	transfer, err := WalletService.Repo.Transfer(ctx, fromID, toID, amount)
+	if err == nil {
+		WalletService.Metrics.ObserveOperation(wallet.OperationTransferOut, amount)
+		attrs := []any{
+			slog.String("transfer_id", transfer.ID.String()),
+			slog.Int64("amount", amount),
+		}
+		if transfer.Credit.ExchangeRate != nil {
+			attrs = append(attrs,
+				slog.Int64("credited_amount", transfer.Credit.Amount),
+				slog.String("exchange_rate", *transfer.Credit.ExchangeRate),
+			)
+		}
+		WalletService.Logger.InfoContext(ctx, "transfer completed", attrs...)
+		return transfer, nil
+	}
+	if errors.Is(err, customerror.ErrWrongAmount) {
+		WalletService.Metrics.IncInsufficientFunds(wallet.OperationTransferOut)
+		WalletService.Logger.InfoContext(ctx, "insufficient funds",
+			slog.String("operation_type", wallet.OperationTransferOut),
+			slog.Int64("amount", amount),
+		)
+		return nil, err
+	}
+	if errors.Is(err, wallet.ErrNotFound) || errors.Is(err, wallet.ErrExchangeRateNotFound) ||
+		errors.Is(err, customerror.ErrInvalidAmount) || errors.Is(err, customerror.ErrAmountOutOfRange) {
+		return nil, err
+	}
+	return nil, customerror.AppendModule(err, "Transfer")
 }
@@ -1,41 +1,176 @@
 package services_test
 
 import (
+	"backend/internal/metrics"
 	"backend/internal/services"
+	"backend/internal/tracing"
+	"backend/pkg/auth"
 	"backend/pkg/customerror"
+	"backend/pkg/requests"
 	"backend/pkg/wallet"
 	"context"
+	"log/slog"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/google/uuid"
-	"github.com/jackc/pgx/v5"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
 )
 
 type MockRepository struct {
 	mock.Mock
 }
 
+func (m *MockRepository) CreateWallet(ctx context.Context, id uuid.UUID, amount int64, currency string, ownerID *string) (*wallet.Wallet, error) {
+	args := m.Called(ctx, id, amount, currency, ownerID)
+	return args.Get(0).(*wallet.Wallet), args.Error(1)
+}
+
 func (m *MockRepository) GetWallet(ctx context.Context, id uuid.UUID) (*wallet.Wallet, error) {
 	args := m.Called(ctx, id)
 	return args.Get(0).(*wallet.Wallet), args.Error(1)
 }
 
-func (m *MockRepository) UpdateWallet(ctx context.Context, id uuid.UUID, delta int64) error {
-	args := m.Called(ctx, id, delta)
-	return args.Error(0)
+func (m *MockRepository) GetCurrency(ctx context.Context, code string) (*wallet.Currency, error) {
+	args := m.Called(ctx, code)
+	return args.Get(0).(*wallet.Currency), args.Error(1)
+}
+
+func (m *MockRepository) UpdateWallet(ctx context.Context, id uuid.UUID, operationType string, delta int64, idempotencyKey *wallet.IdempotencyKey) (*wallet.Transaction, error) {
+	args := m.Called(ctx, id, operationType, delta, idempotencyKey)
+	return args.Get(0).(*wallet.Transaction), args.Error(1)
+}
+
+func (m *MockRepository) ListTransactions(ctx context.Context, filter wallet.TransactionFilter) ([]wallet.Transaction, error) {
+	args := m.Called(ctx, filter)
+	return args.Get(0).([]wallet.Transaction), args.Error(1)
+}
+
+func (m *MockRepository) Transfer(ctx context.Context, fromID uuid.UUID, toID uuid.UUID, amount int64) (*wallet.Transfer, error) {
+	args := m.Called(ctx, fromID, toID, amount)
+	return args.Get(0).(*wallet.Transfer), args.Error(1)
+}
+
+func (m *MockRepository) CreateHold(ctx context.Context, id uuid.UUID, walletID uuid.UUID, amount int64, expiresAt time.Time) (*wallet.Hold, error) {
+	args := m.Called(ctx, id, walletID, amount, expiresAt)
+	return args.Get(0).(*wallet.Hold), args.Error(1)
 }
 
-func (m *MockRepository) CreateTables(ctx context.Context) error {
-	args := m.Called(ctx)
-	return args.Error(0)
+func (m *MockRepository) GetHold(ctx context.Context, id uuid.UUID) (*wallet.Hold, error) {
+	args := m.Called(ctx, id)
+	return args.Get(0).(*wallet.Hold), args.Error(1)
+}
+
+func (m *MockRepository) CaptureHold(ctx context.Context, id uuid.UUID, amount *int64) (*wallet.Hold, error) {
+	args := m.Called(ctx, id, amount)
+	return args.Get(0).(*wallet.Hold), args.Error(1)
+}
+
+func (m *MockRepository) ReleaseHold(ctx context.Context, id uuid.UUID) (*wallet.Hold, error) {
+	args := m.Called(ctx, id)
+	return args.Get(0).(*wallet.Hold), args.Error(1)
+}
+
+func (m *MockRepository) ReleaseExpiredHolds(ctx context.Context, limit int) ([]wallet.Hold, error) {
+	args := m.Called(ctx, limit)
+	return args.Get(0).([]wallet.Hold), args.Error(1)
 }
 
 func (m *MockRepository) ClosePull() {
 	m.Called()
 }
 
+type CreateWalletTest struct {
+	Name          string
+	WalletId      uuid.UUID
+	Amount        int64
+	Mock          func(*MockRepository)
+	WaitingWallet *wallet.Wallet
+	WaitingError  error
+}
+
+func TestWalletService_CreateWallet(t *testing.T) {
+	testID := uuid.New()
+	testWallet := &wallet.Wallet{ID: testID, Amount: 100}
+
+	tests := []CreateWalletTest{
+		{
+			Name:     "Success Test",
+			WalletId: testID,
+			Amount:   100,
+			Mock: func(r *MockRepository) {
+				r.On("CreateWallet", mock.Anything, testID, int64(100), "USD", (*string)(nil)).Return(testWallet, nil)
+			},
+			WaitingWallet: testWallet,
+			WaitingError:  nil,
+		},
+		{
+			Name:     "Generated ID Test",
+			WalletId: uuid.Nil,
+			Amount:   0,
+			Mock: func(r *MockRepository) {
+				r.On("CreateWallet", mock.Anything, mock.MatchedBy(func(id uuid.UUID) bool {
+					return id != uuid.Nil
+				}), int64(0), "USD", (*string)(nil)).Return(testWallet, nil)
+			},
+			WaitingWallet: testWallet,
+			WaitingError:  nil,
+		},
+		{
+			Name:          "Negative Amount Test",
+			WalletId:      testID,
+			Amount:        -100,
+			Mock:          func(r *MockRepository) {},
+			WaitingWallet: nil,
+			WaitingError:  customerror.ErrInvalidAmount,
+		},
+		{
+			Name:     "Duplicate Test",
+			WalletId: testID,
+			Amount:   100,
+			Mock: func(r *MockRepository) {
+				r.On("CreateWallet", mock.Anything, testID, int64(100), "USD", (*string)(nil)).Return((*wallet.Wallet)(nil), wallet.ErrAlreadyExists)
+			},
+			WaitingWallet: nil,
+			WaitingError:  wallet.ErrAlreadyExists,
+		},
+		{
+			Name:     "Other Error Test",
+			WalletId: testID,
+			Amount:   100,
+			Mock: func(r *MockRepository) {
+				r.On("CreateWallet", mock.Anything, testID, int64(100), "USD", (*string)(nil)).Return((*wallet.Wallet)(nil), customerror.NewError("", "", "error"))
+			},
+			WaitingWallet: nil,
+			WaitingError:  customerror.NewError("CreateWallet.", "", "error"),
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			mockRepo := new(MockRepository)
+			test.Mock(mockRepo)
+
+			service := services.NewWalletService(mockRepo, 5*time.Second, "USD", metrics.NewNop(), slog.New(slog.DiscardHandler), noop.NewTracerProvider())
+			got, err := service.CreateWallet(context.Background(), test.WalletId, test.Amount, "")
+			if test.WaitingError != nil {
+				assert.EqualError(t, err, test.WaitingError.Error())
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, test.WaitingWallet, got)
+			mockRepo.AssertExpectations(t)
+		})
+	}
+}
+
 type GetBalanceTest struct {
 	Name           string
 	WalletId       uuid.UUID
@@ -62,10 +197,10 @@ func TestWalletService_GetBalance(t *testing.T) {
 			Name:     "Not Found Test",
 			WalletId: testID,
 			Mock: func(r *MockRepository) {
-				r.On("GetWallet", mock.Anything, testID).Return(&wallet.Wallet{}, pgx.ErrNoRows)
+				r.On("GetWallet", mock.Anything, testID).Return(&wallet.Wallet{}, wallet.ErrNotFound)
 			},
 			WaitingBalance: 0,
-			WaitingError:   pgx.ErrNoRows,
+			WaitingError:   wallet.ErrNotFound,
 		},
 		{
 			Name:     "Other Error Test",
@@ -76,6 +211,15 @@ func TestWalletService_GetBalance(t *testing.T) {
 			WaitingBalance: 0,
 			WaitingError:   customerror.NewError("GetBalance.", "", "error"),
 		},
+		{
+			Name:     "Foreign Error Test",
+			WalletId: testID,
+			Mock: func(r *MockRepository) {
+				r.On("GetWallet", mock.Anything, testID).Return(&wallet.Wallet{}, context.DeadlineExceeded)
+			},
+			WaitingBalance: 0,
+			WaitingError:   customerror.NewError("GetBalance", "", "context deadline exceeded"),
+		},
 	}
 
 	for _, test := range tests {
@@ -83,30 +227,36 @@ func TestWalletService_GetBalance(t *testing.T) {
 			mockRepo := new(MockRepository)
 			test.Mock(mockRepo)
 
-			service := services.NewWalletService(mockRepo)
-			got, err := service.GetBalance(test.WalletId)
+			service := services.NewWalletService(mockRepo, 5*time.Second, "USD", metrics.NewNop(), slog.New(slog.DiscardHandler), noop.NewTracerProvider())
+			got, err := service.GetBalance(context.Background(), test.WalletId)
 			if test.WaitingError != nil {
 				assert.EqualError(t, err, test.WaitingError.Error())
 			} else {
 				assert.NoError(t, err)
 			}
-			assert.Equal(t, test.WaitingBalance, got)
+			if got != nil {
+				assert.Equal(t, test.WaitingBalance, got.Amount)
+			}
 			mockRepo.AssertExpectations(t)
 		})
 	}
 }
 
 type UpdateBalanceTest struct {
-	Name          string
-	WalletId      uuid.UUID
-	OperationType string
-	Amount        int64
-	Mock          func(*MockRepository)
-	WaitingError  error
+	Name               string
+	WalletId           uuid.UUID
+	OperationType      string
+	Amount             int64
+	IdempotencyKey     string
+	Mock               func(*MockRepository)
+	WaitingTransaction *wallet.Transaction
+	WaitingError       error
 }
 
 func TestWalletService_UpdateBalance(t *testing.T) {
 	testID := uuid.New()
+	depositTransaction := &wallet.Transaction{ID: uuid.New(), WalletID: testID, OperationType: "DEPOSIT", Amount: 100, Balance: 200}
+	withdrawTransaction := &wallet.Transaction{ID: uuid.New(), WalletID: testID, OperationType: "WITHDRAW", Amount: -100, Balance: 0}
 
 	tests := []UpdateBalanceTest{
 		{
@@ -115,9 +265,10 @@ func TestWalletService_UpdateBalance(t *testing.T) {
 			OperationType: "DEPOSIT",
 			Amount:        100,
 			Mock: func(r *MockRepository) {
-				r.On("UpdateWallet", mock.Anything, testID, int64(100)).Return(nil)
+				r.On("UpdateWallet", mock.Anything, testID, "DEPOSIT", int64(100), (*wallet.IdempotencyKey)(nil)).Return(depositTransaction, nil)
 			},
-			WaitingError: nil,
+			WaitingTransaction: depositTransaction,
+			WaitingError:       nil,
 		},
 		{
 			Name:          "Success Withdraw Test",
@@ -125,9 +276,42 @@ func TestWalletService_UpdateBalance(t *testing.T) {
 			OperationType: "WITHDRAW",
 			Amount:        100,
 			Mock: func(r *MockRepository) {
-				r.On("UpdateWallet", mock.Anything, testID, int64(-100)).Return(nil)
+				r.On("UpdateWallet", mock.Anything, testID, "WITHDRAW", int64(-100), (*wallet.IdempotencyKey)(nil)).Return(withdrawTransaction, nil)
 			},
-			WaitingError: nil,
+			WaitingTransaction: withdrawTransaction,
+			WaitingError:       nil,
+		},
+		{
+			Name:           "Idempotency Key Test",
+			WalletId:       testID,
+			OperationType:  "WITHDRAW",
+			Amount:         100,
+			IdempotencyKey: "key",
+			Mock: func(r *MockRepository) {
+				r.On("UpdateWallet", mock.Anything, testID, "WITHDRAW", int64(-100), wallet.NewIdempotencyKey("", "key", testID, "WITHDRAW", 100)).Return(withdrawTransaction, nil)
+			},
+			WaitingTransaction: withdrawTransaction,
+			WaitingError:       nil,
+		},
+		{
+			Name:           "Idempotency Key Too Long Test",
+			WalletId:       testID,
+			OperationType:  "DEPOSIT",
+			Amount:         100,
+			IdempotencyKey: strings.Repeat("k", 256),
+			Mock:           func(r *MockRepository) {},
+			WaitingError:   customerror.ErrWrongIdempotencyKey,
+		},
+		{
+			Name:           "Idempotency Key Reused Test",
+			WalletId:       testID,
+			OperationType:  "DEPOSIT",
+			Amount:         100,
+			IdempotencyKey: "key",
+			Mock: func(r *MockRepository) {
+				r.On("UpdateWallet", mock.Anything, testID, "DEPOSIT", int64(100), mock.Anything).Return((*wallet.Transaction)(nil), customerror.ErrIdempotencyKeyReused)
+			},
+			WaitingError: customerror.ErrIdempotencyKeyReused,
 		},
 		{
 			Name:          "Wrong Operation Test",
@@ -137,13 +321,29 @@ func TestWalletService_UpdateBalance(t *testing.T) {
 			Mock:          func(r *MockRepository) {},
 			WaitingError:  customerror.ErrWrongOperation,
 		},
+		{
+			Name:          "Negative Amount Test",
+			WalletId:      testID,
+			OperationType: "WITHDRAW",
+			Amount:        -500,
+			Mock:          func(r *MockRepository) {},
+			WaitingError:  customerror.ErrInvalidAmount,
+		},
+		{
+			Name:          "Zero Amount Test",
+			WalletId:      testID,
+			OperationType: "DEPOSIT",
+			Amount:        0,
+			Mock:          func(r *MockRepository) {},
+			WaitingError:  customerror.ErrInvalidAmount,
+		},
 		{
 			Name:          "Wrong Amount Test",
 			WalletId:      testID,
 			OperationType: "WITHDRAW",
 			Amount:        100,
 			Mock: func(r *MockRepository) {
-				r.On("UpdateWallet", mock.Anything, testID, int64(-100)).Return(customerror.ErrWrongAmount)
+				r.On("UpdateWallet", mock.Anything, testID, "WITHDRAW", int64(-100), (*wallet.IdempotencyKey)(nil)).Return((*wallet.Transaction)(nil), customerror.ErrWrongAmount)
 			},
 			WaitingError: customerror.ErrWrongAmount,
 		},
@@ -153,9 +353,19 @@ func TestWalletService_UpdateBalance(t *testing.T) {
 			OperationType: "DEPOSIT",
 			Amount:        100,
 			Mock: func(r *MockRepository) {
-				r.On("UpdateWallet", mock.Anything, testID, int64(100)).Return(pgx.ErrNoRows)
+				r.On("UpdateWallet", mock.Anything, testID, "DEPOSIT", int64(100), (*wallet.IdempotencyKey)(nil)).Return((*wallet.Transaction)(nil), wallet.ErrNotFound)
 			},
-			WaitingError: pgx.ErrNoRows,
+			WaitingError: wallet.ErrNotFound,
+		},
+		{
+			Name:          "Balance Out Of Range Test",
+			WalletId:      testID,
+			OperationType: "DEPOSIT",
+			Amount:        100,
+			Mock: func(r *MockRepository) {
+				r.On("UpdateWallet", mock.Anything, testID, "DEPOSIT", int64(100), (*wallet.IdempotencyKey)(nil)).Return((*wallet.Transaction)(nil), customerror.ErrAmountOutOfRange)
+			},
+			WaitingError: customerror.ErrAmountOutOfRange,
 		},
 		{
 			Name:          "Other Error Test",
@@ -163,7 +373,7 @@ func TestWalletService_UpdateBalance(t *testing.T) {
 			OperationType: "DEPOSIT",
 			Amount:        100,
 			Mock: func(r *MockRepository) {
-				r.On("UpdateWallet", mock.Anything, testID, int64(100)).Return(customerror.NewError("", "", "error"))
+				r.On("UpdateWallet", mock.Anything, testID, "DEPOSIT", int64(100), (*wallet.IdempotencyKey)(nil)).Return((*wallet.Transaction)(nil), customerror.NewError("", "", "error"))
 			},
 			WaitingError: customerror.NewError("UpdateBalance.", "", "error"),
 		},
@@ -174,14 +384,572 @@ func TestWalletService_UpdateBalance(t *testing.T) {
 			mockRepo := new(MockRepository)
 			test.Mock(mockRepo)
 
-			service := services.NewWalletService(mockRepo)
-			err := service.UpdateBalance(test.WalletId, test.OperationType, test.Amount)
+			service := services.NewWalletService(mockRepo, 5*time.Second, "USD", metrics.NewNop(), slog.New(slog.DiscardHandler), noop.NewTracerProvider())
+			transaction, err := service.UpdateBalance(context.Background(), test.WalletId, test.OperationType, test.Amount, "", test.IdempotencyKey)
+			if test.WaitingError != nil {
+				assert.EqualError(t, err, test.WaitingError.Error())
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, test.WaitingTransaction, transaction)
+			mockRepo.AssertExpectations(t)
+		})
+	}
+}
+
+type GetTransactionsTest struct {
+	Name         string
+	WalletId     uuid.UUID
+	Request      requests.GetTransactionsRequest
+	Mock         func(*MockRepository)
+	WaitingPage  *wallet.TransactionPage
+	WaitingError error
+}
+
+func TestWalletService_GetTransactions(t *testing.T) {
+	testID := uuid.New()
+	testWallet := &wallet.Wallet{ID: testID, Amount: 300}
+	testTime := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+	testTransactions := []wallet.Transaction{
+		{ID: uuid.New(), WalletID: testID, OperationType: "DEPOSIT", Amount: 100, Balance: 300, CreatedAt: testTime},
+		{ID: uuid.New(), WalletID: testID, OperationType: "DEPOSIT", Amount: 100, Balance: 200, CreatedAt: testTime.Add(-time.Minute)},
+		{ID: uuid.New(), WalletID: testID, OperationType: "DEPOSIT", Amount: 100, Balance: 100, CreatedAt: testTime.Add(-2 * time.Minute)},
+	}
+	testCursor := wallet.NewTransactionCursor(testTransactions[1])
+
+	tests := []GetTransactionsTest{
+		{
+			Name:     "Default Limit Test",
+			WalletId: testID,
+			Request:  requests.GetTransactionsRequest{},
+			Mock: func(r *MockRepository) {
+				r.On("GetWallet", mock.Anything, testID).Return(testWallet, nil)
+				r.On("ListTransactions", mock.Anything, wallet.TransactionFilter{WalletID: testID, Limit: 21}).Return(testTransactions, nil)
+			},
+			WaitingPage:  &wallet.TransactionPage{Transactions: testTransactions},
+			WaitingError: nil,
+		},
+		{
+			Name:     "Next Page Test",
+			WalletId: testID,
+			Request:  requests.GetTransactionsRequest{Limit: 2, OperationType: "DEPOSIT", Order: "oldest", From: testTime.Add(-time.Hour)},
+			Mock: func(r *MockRepository) {
+				r.On("GetWallet", mock.Anything, testID).Return(testWallet, nil)
+				r.On("ListTransactions", mock.Anything, wallet.TransactionFilter{
+					WalletID:      testID,
+					OperationType: "DEPOSIT",
+					From:          testTime.Add(-time.Hour),
+					Ascending:     true,
+					Limit:         3,
+				}).Return(testTransactions, nil)
+			},
+			WaitingPage:  &wallet.TransactionPage{Transactions: testTransactions[:2], NextCursor: testCursor.Encode()},
+			WaitingError: nil,
+		},
+		{
+			Name:     "Cursor Test",
+			WalletId: testID,
+			Request:  requests.GetTransactionsRequest{Limit: 2, Cursor: testCursor.Encode()},
+			Mock: func(r *MockRepository) {
+				r.On("GetWallet", mock.Anything, testID).Return(testWallet, nil)
+				r.On("ListTransactions", mock.Anything, mock.MatchedBy(func(filter wallet.TransactionFilter) bool {
+					return filter.Cursor != nil && filter.Cursor.ID == testCursor.ID && filter.Cursor.CreatedAt.Equal(testCursor.CreatedAt)
+				})).Return(testTransactions[2:], nil)
+			},
+			WaitingPage:  &wallet.TransactionPage{Transactions: testTransactions[2:]},
+			WaitingError: nil,
+		},
+		{
+			Name:         "Wrong Cursor Test",
+			WalletId:     testID,
+			Request:      requests.GetTransactionsRequest{Cursor: "invalid"},
+			Mock:         func(r *MockRepository) {},
+			WaitingPage:  nil,
+			WaitingError: customerror.ErrWrongCursor,
+		},
+		{
+			Name:         "Wrong Operation Test",
+			WalletId:     testID,
+			Request:      requests.GetTransactionsRequest{OperationType: "INVALID"},
+			Mock:         func(r *MockRepository) {},
+			WaitingPage:  nil,
+			WaitingError: customerror.ErrWrongOperation,
+		},
+		{
+			Name:     "Not Found Test",
+			WalletId: testID,
+			Request:  requests.GetTransactionsRequest{},
+			Mock: func(r *MockRepository) {
+				r.On("GetWallet", mock.Anything, testID).Return(&wallet.Wallet{}, wallet.ErrNotFound)
+			},
+			WaitingPage:  nil,
+			WaitingError: wallet.ErrNotFound,
+		},
+		{
+			Name:     "Other Error Test",
+			WalletId: testID,
+			Request:  requests.GetTransactionsRequest{},
+			Mock: func(r *MockRepository) {
+				r.On("GetWallet", mock.Anything, testID).Return(testWallet, nil)
+				r.On("ListTransactions", mock.Anything, mock.Anything).Return([]wallet.Transaction(nil), customerror.NewError("", "", "error"))
+			},
+			WaitingPage:  nil,
+			WaitingError: customerror.NewError("GetTransactions.", "", "error"),
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			mockRepo := new(MockRepository)
+			test.Mock(mockRepo)
+
+			service := services.NewWalletService(mockRepo, 5*time.Second, "USD", metrics.NewNop(), slog.New(slog.DiscardHandler), noop.NewTracerProvider())
+			page, err := service.GetTransactions(context.Background(), test.WalletId, test.Request)
+			if test.WaitingError != nil {
+				assert.EqualError(t, err, test.WaitingError.Error())
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, test.WaitingPage, page)
+			mockRepo.AssertExpectations(t)
+		})
+	}
+}
+
+type TransferTest struct {
+	Name            string
+	FromId          uuid.UUID
+	ToId            uuid.UUID
+	Amount          int64
+	Mock            func(*MockRepository)
+	WaitingTransfer *wallet.Transfer
+	WaitingError    error
+}
+
+func TestWalletService_Transfer(t *testing.T) {
+	fromID := uuid.New()
+	toID := uuid.New()
+	testTransfer := &wallet.Transfer{
+		ID:     uuid.New(),
+		Debit:  wallet.Transaction{WalletID: fromID, OperationType: "TRANSFER_OUT", Amount: -100, Balance: 0},
+		Credit: wallet.Transaction{WalletID: toID, OperationType: "TRANSFER_IN", Amount: 100, Balance: 100},
+	}
+	rateID := uuid.New()
+	rate := "151.2345"
+	convertedTransfer := &wallet.Transfer{
+		ID:     uuid.New(),
+		Debit:  wallet.Transaction{WalletID: fromID, OperationType: "TRANSFER_OUT", Amount: -100, RateID: &rateID, ExchangeRate: &rate},
+		Credit: wallet.Transaction{WalletID: toID, OperationType: "TRANSFER_IN", Amount: 151, Balance: 151, RateID: &rateID, ExchangeRate: &rate},
+	}
+
+	tests := []TransferTest{
+		{
+			Name:   "Success Test",
+			FromId: fromID,
+			ToId:   toID,
+			Amount: 100,
+			Mock: func(r *MockRepository) {
+				r.On("Transfer", mock.Anything, fromID, toID, int64(100)).Return(testTransfer, nil)
+			},
+			WaitingTransfer: testTransfer,
+			WaitingError:    nil,
+		},
+		{
+			Name:            "Non Positive Amount Test",
+			FromId:          fromID,
+			ToId:            toID,
+			Amount:          0,
+			Mock:            func(r *MockRepository) {},
+			WaitingTransfer: nil,
+			WaitingError:    customerror.ErrInvalidAmount,
+		},
+		{
+			Name:            "Same Wallet Test",
+			FromId:          fromID,
+			ToId:            fromID,
+			Amount:          100,
+			Mock:            func(r *MockRepository) {},
+			WaitingTransfer: nil,
+			WaitingError:    customerror.ErrSameWallet,
+		},
+		{
+			Name:   "Insufficient Funds Test",
+			FromId: fromID,
+			ToId:   toID,
+			Amount: 100,
+			Mock: func(r *MockRepository) {
+				r.On("Transfer", mock.Anything, fromID, toID, int64(100)).Return((*wallet.Transfer)(nil), customerror.ErrWrongAmount)
+			},
+			WaitingTransfer: nil,
+			WaitingError:    customerror.ErrWrongAmount,
+		},
+		{
+			Name:   "Cross Currency Test",
+			FromId: fromID,
+			ToId:   toID,
+			Amount: 100,
+			Mock: func(r *MockRepository) {
+				r.On("Transfer", mock.Anything, fromID, toID, int64(100)).Return(convertedTransfer, nil)
+			},
+			WaitingTransfer: convertedTransfer,
+			WaitingError:    nil,
+		},
+		{
+			Name:   "Exchange Rate Not Found Test",
+			FromId: fromID,
+			ToId:   toID,
+			Amount: 100,
+			Mock: func(r *MockRepository) {
+				r.On("Transfer", mock.Anything, fromID, toID, int64(100)).Return((*wallet.Transfer)(nil), wallet.ErrExchangeRateNotFound)
+			},
+			WaitingTransfer: nil,
+			WaitingError:    wallet.ErrExchangeRateNotFound,
+		},
+		{
+			Name:   "Conversion Too Small Test",
+			FromId: fromID,
+			ToId:   toID,
+			Amount: 1,
+			Mock: func(r *MockRepository) {
+				r.On("Transfer", mock.Anything, fromID, toID, int64(1)).Return((*wallet.Transfer)(nil), customerror.ErrInvalidAmount)
+			},
+			WaitingTransfer: nil,
+			WaitingError:    customerror.ErrInvalidAmount,
+		},
+		{
+			Name:   "Not Found Test",
+			FromId: fromID,
+			ToId:   toID,
+			Amount: 100,
+			Mock: func(r *MockRepository) {
+				r.On("Transfer", mock.Anything, fromID, toID, int64(100)).Return((*wallet.Transfer)(nil), wallet.ErrNotFound)
+			},
+			WaitingTransfer: nil,
+			WaitingError:    wallet.ErrNotFound,
+		},
+		{
+			Name:   "Other Error Test",
+			FromId: fromID,
+			ToId:   toID,
+			Amount: 100,
+			Mock: func(r *MockRepository) {
+				r.On("Transfer", mock.Anything, fromID, toID, int64(100)).Return((*wallet.Transfer)(nil), customerror.NewError("", "", "error"))
+			},
+			WaitingTransfer: nil,
+			WaitingError:    customerror.NewError("Transfer.", "", "error"),
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			mockRepo := new(MockRepository)
+			test.Mock(mockRepo)
+
+			service := services.NewWalletService(mockRepo, 5*time.Second, "USD", metrics.NewNop(), slog.New(slog.DiscardHandler), noop.NewTracerProvider())
+			transfer, err := service.Transfer(context.Background(), test.FromId, test.ToId, test.Amount, "")
 			if test.WaitingError != nil {
 				assert.EqualError(t, err, test.WaitingError.Error())
 			} else {
 				assert.NoError(t, err)
 			}
+			assert.Equal(t, test.WaitingTransfer, transfer)
 			mockRepo.AssertExpectations(t)
 		})
 	}
 }
+
+func TestWalletService_ContextPropagation(t *testing.T) {
+	testID := uuid.New()
+	type ctxKey struct{}
+	parent := context.WithValue(context.Background(), ctxKey{}, "request")
+
+	mockRepo := new(MockRepository)
+	mockRepo.On("GetWallet", mock.MatchedBy(func(ctx context.Context) bool {
+		deadline, ok := ctx.Deadline()
+		return ok && time.Until(deadline) <= time.Second && ctx.Value(ctxKey{}) == "request"
+	}), testID).Return(&wallet.Wallet{ID: testID, Amount: 100}, nil)
+
+	service := services.NewWalletService(mockRepo, time.Second, "USD", metrics.NewNop(), slog.New(slog.DiscardHandler), noop.NewTracerProvider())
+	balance, err := service.GetBalance(parent, testID)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(100), balance.Amount)
+	mockRepo.AssertExpectations(t)
+
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+	mockRepo = new(MockRepository)
+	mockRepo.On("GetWallet", mock.MatchedBy(func(ctx context.Context) bool {
+		return ctx.Err() == context.Canceled
+	}), testID).Return(&wallet.Wallet{}, context.Canceled)
+
+	service = services.NewWalletService(mockRepo, time.Second, "USD", metrics.NewNop(), slog.New(slog.DiscardHandler), noop.NewTracerProvider())
+	_, err = service.GetBalance(cancelled, testID)
+	assert.ErrorIs(t, err, context.Canceled)
+	mockRepo.AssertExpectations(t)
+}
+
+type MockRecorder struct {
+	mock.Mock
+}
+
+func (m *MockRecorder) ObserveRequest(method string, route string, status int, duration time.Duration) {
+	m.Called(method, route, status, duration)
+}
+
+func (m *MockRecorder) ObserveOperation(operationType string, amount int64) {
+	m.Called(operationType, amount)
+}
+
+func (m *MockRecorder) IncInsufficientFunds(operationType string) {
+	m.Called(operationType)
+}
+
+type MetricsTest struct {
+	Name          string
+	OperationType string
+	Amount        int64
+	Mock          func(*MockRepository, *MockRecorder)
+}
+
+func TestWalletService_Metrics(t *testing.T) {
+	testID := uuid.New()
+
+	tests := []MetricsTest{
+		{
+			Name:          "Withdraw counted with positive amount",
+			OperationType: wallet.OperationWithdraw,
+			Amount:        30,
+			Mock: func(r *MockRepository, m *MockRecorder) {
+				r.On("UpdateWallet", mock.Anything, testID, wallet.OperationWithdraw, int64(-30), (*wallet.IdempotencyKey)(nil)).Return(&wallet.Transaction{Balance: 70}, nil)
+				m.On("ObserveOperation", wallet.OperationWithdraw, int64(30)).Once()
+			},
+		},
+		{
+			Name:          "Replay not counted",
+			OperationType: wallet.OperationDeposit,
+			Amount:        30,
+			Mock: func(r *MockRepository, m *MockRecorder) {
+				r.On("UpdateWallet", mock.Anything, testID, wallet.OperationDeposit, int64(30), (*wallet.IdempotencyKey)(nil)).Return(&wallet.Transaction{Balance: 130, Replayed: true}, nil)
+			},
+		},
+		{
+			Name:          "Insufficient funds counted",
+			OperationType: wallet.OperationWithdraw,
+			Amount:        300,
+			Mock: func(r *MockRepository, m *MockRecorder) {
+				r.On("UpdateWallet", mock.Anything, testID, wallet.OperationWithdraw, int64(-300), (*wallet.IdempotencyKey)(nil)).Return((*wallet.Transaction)(nil), customerror.ErrWrongAmount)
+				m.On("IncInsufficientFunds", wallet.OperationWithdraw).Once()
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			mockRepo := new(MockRepository)
+			mockRecorder := new(MockRecorder)
+			test.Mock(mockRepo, mockRecorder)
+
+			service := services.NewWalletService(mockRepo, 5*time.Second, "USD", mockRecorder, slog.New(slog.DiscardHandler), noop.NewTracerProvider())
+			_, _ = service.UpdateBalance(context.Background(), testID, test.OperationType, test.Amount, "", "")
+
+			mockRepo.AssertExpectations(t)
+			mockRecorder.AssertExpectations(t)
+		})
+	}
+}
+
+func TestWalletService_Tracing(t *testing.T) {
+	testID := uuid.New()
+	spanRecorder := tracetest.NewSpanRecorder()
+	tracerProvider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(spanRecorder))
+
+	mockRepo := new(MockRepository)
+	mockRepo.On("UpdateWallet", mock.MatchedBy(func(ctx context.Context) bool {
+		return trace.SpanContextFromContext(ctx).IsValid()
+	}), testID, wallet.OperationWithdraw, int64(-300), (*wallet.IdempotencyKey)(nil)).Return((*wallet.Transaction)(nil), customerror.ErrWrongAmount)
+
+	service := services.NewWalletService(mockRepo, 5*time.Second, "USD", metrics.NewNop(), slog.New(slog.DiscardHandler), tracerProvider)
+	_, err := service.UpdateBalance(context.Background(), testID, wallet.OperationWithdraw, 300, "", "")
+	assert.ErrorIs(t, err, customerror.ErrWrongAmount)
+
+	spans := spanRecorder.Ended()
+	assert.Len(t, spans, 1)
+	assert.Equal(t, "WalletService.UpdateBalance", spans[0].Name())
+	assert.Contains(t, spans[0].Attributes(), tracing.WalletIDKey.String(testID.String()))
+	assert.Contains(t, spans[0].Attributes(), tracing.OperationTypeKey.String(wallet.OperationWithdraw))
+	// Insufficient funds is an expected answer, not a failed span.
+	assert.Equal(t, codes.Unset, spans[0].Status().Code)
+	mockRepo.AssertExpectations(t)
+}
+
+type OwnershipTest struct {
+	Name         string
+	Principal    *auth.Principal
+	OwnerID      *string
+	WaitingError error
+}
+
+func TestWalletService_Ownership(t *testing.T) {
+	testID := uuid.New()
+	owner := "user-1"
+	stranger := "user-2"
+
+	tests := []OwnershipTest{
+		{
+			Name:      "Owner Test",
+			Principal: &auth.Principal{Subject: owner},
+			OwnerID:   &owner,
+		},
+		{
+			Name:         "Other Owner Test",
+			Principal:    &auth.Principal{Subject: owner},
+			OwnerID:      &stranger,
+			WaitingError: wallet.ErrForbidden,
+		},
+		{
+			Name:         "Unowned Wallet Test",
+			Principal:    &auth.Principal{Subject: owner},
+			WaitingError: wallet.ErrForbidden,
+		},
+		{
+			Name:      "Admin Test",
+			Principal: &auth.Principal{Subject: owner, Admin: true},
+			OwnerID:   &stranger,
+		},
+		{
+			Name:      "API Key Test",
+			Principal: &auth.Principal{ID: uuid.NewString()},
+			OwnerID:   &stranger,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			ctx := auth.NewContext(context.Background(), test.Principal)
+			foundWallet := &wallet.Wallet{ID: testID, Amount: 100, OwnerID: test.OwnerID}
+
+			repo := new(MockRepository)
+			repo.On("GetWallet", mock.Anything, testID).Return(foundWallet, nil)
+			if test.WaitingError == nil {
+				repo.On("UpdateWallet", mock.Anything, testID, wallet.OperationDeposit, int64(10), (*wallet.IdempotencyKey)(nil)).
+					Return(&wallet.Transaction{ID: uuid.New(), WalletID: testID}, nil)
+			}
+			service := services.NewWalletService(repo, time.Second, "USD", metrics.NewNop(), slog.New(slog.DiscardHandler), noop.NewTracerProvider())
+
+			_, err := service.GetBalance(ctx, testID)
+			assert.ErrorIs(t, err, test.WaitingError)
+			_, err = service.UpdateBalance(ctx, testID, wallet.OperationDeposit, 10, "", "")
+			assert.ErrorIs(t, err, test.WaitingError)
+			repo.AssertExpectations(t)
+		})
+	}
+}
+
+type CreateWalletOwnerTest struct {
+	Name          string
+	Principal     *auth.Principal
+	ExpectedOwner *string
+}
+
+func TestWalletService_CreateWalletOwner(t *testing.T) {
+	testID := uuid.New()
+	owner := "user-1"
+
+	tests := []CreateWalletOwnerTest{
+		{
+			Name:          "End User Test",
+			Principal:     &auth.Principal{Subject: owner},
+			ExpectedOwner: &owner,
+		},
+		{
+			Name:      "Admin Test",
+			Principal: &auth.Principal{Subject: "admin-1", Admin: true},
+		},
+		{
+			Name:      "API Key Test",
+			Principal: &auth.Principal{ID: "key-1", Name: "billing"},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			repo := new(MockRepository)
+			repo.On("CreateWallet", mock.Anything, testID, int64(0), "USD", test.ExpectedOwner).Return(&wallet.Wallet{ID: testID, OwnerID: test.ExpectedOwner}, nil)
+			service := services.NewWalletService(repo, time.Second, "USD", metrics.NewNop(), slog.New(slog.DiscardHandler), noop.NewTracerProvider())
+
+			ctx := auth.NewContext(context.Background(), test.Principal)
+			_, err := service.CreateWallet(ctx, testID, 0, "")
+			assert.NoError(t, err)
+			repo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestWalletService_Currency(t *testing.T) {
+	testID := uuid.New()
+	otherID := uuid.New()
+	usdWallet := &wallet.Wallet{ID: testID, Amount: 100, Currency: wallet.Currency{Code: "USD", MinorUnits: 2}}
+
+	repo := new(MockRepository)
+	repo.On("GetWallet", mock.Anything, testID).Return(usdWallet, nil)
+	repo.On("UpdateWallet", mock.Anything, testID, wallet.OperationDeposit, int64(10), (*wallet.IdempotencyKey)(nil)).
+		Return(&wallet.Transaction{ID: uuid.New(), WalletID: testID}, nil).Once()
+	service := services.NewWalletService(repo, time.Second, "USD", metrics.NewNop(), slog.New(slog.DiscardHandler), noop.NewTracerProvider())
+
+	_, err := service.CreateWallet(context.Background(), testID, 0, "usd")
+	assert.ErrorIs(t, err, wallet.ErrUnknownCurrency)
+
+	_, err = service.UpdateBalance(context.Background(), testID, wallet.OperationDeposit, 10, "JPY", "")
+	assert.ErrorIs(t, err, wallet.ErrCurrencyMismatch)
+
+	_, err = service.UpdateBalance(context.Background(), testID, wallet.OperationDeposit, 10, "USD", "")
+	assert.NoError(t, err)
+
+	_, err = service.Transfer(context.Background(), testID, otherID, 10, "EUR")
+	assert.ErrorIs(t, err, wallet.ErrCurrencyMismatch)
+	repo.AssertExpectations(t)
+}
+
+func TestWalletService_GetCurrency(t *testing.T) {
+	jpy := &wallet.Currency{Code: "JPY", MinorUnits: 0}
+	usd := &wallet.Currency{Code: "USD", MinorUnits: 2}
+
+	repo := new(MockRepository)
+	repo.On("GetCurrency", mock.Anything, "JPY").Return(jpy, nil).Once()
+	repo.On("GetCurrency", mock.Anything, "USD").Return(usd, nil).Once()
+	repo.On("GetCurrency", mock.Anything, "XXX").Return((*wallet.Currency)(nil), wallet.ErrUnknownCurrency).Once()
+	service := services.NewWalletService(repo, time.Second, "USD", metrics.NewNop(), slog.New(slog.DiscardHandler), noop.NewTracerProvider())
+
+	currency, err := service.GetCurrency(context.Background(), "JPY")
+	assert.NoError(t, err)
+	assert.Equal(t, jpy, currency)
+
+	currency, err = service.GetCurrency(context.Background(), "")
+	assert.NoError(t, err)
+	assert.Equal(t, usd, currency)
+
+	_, err = service.GetCurrency(context.Background(), "XXX")
+	assert.ErrorIs(t, err, wallet.ErrUnknownCurrency)
+
+	_, err = service.GetCurrency(context.Background(), "usd")
+	assert.ErrorIs(t, err, wallet.ErrUnknownCurrency)
+	repo.AssertExpectations(t)
+}
+
+func TestWalletService_IdempotencyKeyScope(t *testing.T) {
+	testID := uuid.New()
+	transaction := &wallet.Transaction{ID: uuid.New(), WalletID: testID}
+
+	repo := new(MockRepository)
+	// Two callers picking the same key claim it each in their own key space.
+	for _, clientID := range []string{"client-1", "client-2"} {
+		repo.On("UpdateWallet", mock.Anything, testID, wallet.OperationDeposit, int64(10), wallet.NewIdempotencyKey(clientID, "key", testID, wallet.OperationDeposit, 10)).
+			Return(transaction, nil).Once()
+	}
+	service := services.NewWalletService(repo, time.Second, "USD", metrics.NewNop(), slog.New(slog.DiscardHandler), noop.NewTracerProvider())
+
+	for _, clientID := range []string{"client-1", "client-2"} {
+		ctx := auth.NewContext(context.Background(), &auth.Principal{ID: clientID})
+		_, err := service.UpdateBalance(ctx, testID, wallet.OperationDeposit, 10, "", "key")
+		assert.NoError(t, err)
+	}
+	repo.AssertExpectations(t)
+}
@@ -0,0 +1,66 @@
+package tracing
+
+import (
+	"backend/pkg/config"
+	"backend/pkg/customerror"
+	"context"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+const (
+	ExporterNone = "none"
+	ExporterOTLP = "otlp"
+)
+
+// Attribute keys shared by every layer.
+const (
+	WalletIDKey       = attribute.Key("wallet.id")
+	ToWalletIDKey     = attribute.Key("wallet.to_id")
+	OperationTypeKey  = attribute.Key("operation.type")
+	ExchangeRateIDKey = attribute.Key("exchange_rate.id")
+	HoldIDKey         = attribute.Key("hold.id")
+	APIKeyIDKey       = attribute.Key("api_key.id")
+	RowCountKey       = attribute.Key("db.rows_affected")
+)
+
+// NewTracerProvider builds the provider selected by appConfig. The returned
+// function flushes pending spans and must be called on shutdown.
+func NewTracerProvider(ctx context.Context, appConfig *config.Config) (trace.TracerProvider, func(context.Context) error, error) {
+	if appConfig.TracingExporter != ExporterOTLP {
+		return noop.NewTracerProvider(), func(context.Context) error { return nil }, nil
+	}
+	options := []otlptracehttp.Option{otlptracehttp.WithEndpoint(appConfig.TracingEndpoint)}
+	if appConfig.TracingInsecure {
+		options = append(options, otlptracehttp.WithInsecure())
+	}
+	exporter, err := otlptracehttp.New(ctx, options...)
+	if err != nil {
+		return nil, nil, customerror.Wrap(err, "tracing.NewTracerProvider", appConfig.TracingEndpoint)
+	}
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(resource.NewSchemaless(semconv.ServiceName(appConfig.ServiceName))),
+	)
+	return provider, provider.Shutdown, nil
+}
+
+// End finishes span with the outcome of err. Only internal errors mark the
+// span as failed; not found, conflict and validation errors are expected
+// answers and are recorded as events instead.
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		if customerror.KindOf(err) == customerror.ErrInternal {
+			span.SetStatus(codes.Error, err.Error())
+		}
+	}
+	span.End()
+}
@@ -0,0 +1,120 @@
+package tracing_test
+
+import (
+	"backend/internal/tracing"
+	"backend/pkg/customerror"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func newRecorder() (*tracetest.SpanRecorder, *sdktrace.TracerProvider) {
+	recorder := tracetest.NewSpanRecorder()
+	return recorder, sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+}
+
+func attributes(span sdktrace.ReadOnlySpan) map[attribute.Key]attribute.Value {
+	values := make(map[attribute.Key]attribute.Value)
+	for _, kv := range span.Attributes() {
+		values[kv.Key] = kv.Value
+	}
+	return values
+}
+
+func TestMiddleware(t *testing.T) {
+	recorder, provider := newRecorder()
+
+	router := gin.New()
+	router.Use(tracing.Middleware(provider))
+	router.GET("/wallets/:id", func(ctx *gin.Context) {
+		ctx.Status(http.StatusInternalServerError)
+	})
+
+	req, _ := http.NewRequest(http.MethodGet, "/wallets/abc", nil)
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	router.ServeHTTP(httptest.NewRecorder(), req)
+
+	spans := recorder.Ended()
+	assert.Len(t, spans, 1)
+	assert.Equal(t, "GET /wallets/:id", spans[0].Name())
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", spans[0].SpanContext().TraceID().String())
+	assert.Equal(t, "00f067aa0ba902b7", spans[0].Parent().SpanID().String())
+	assert.Equal(t, int64(http.StatusInternalServerError), attributes(spans[0])["http.response.status_code"].AsInt64())
+	assert.Equal(t, codes.Error, spans[0].Status().Code)
+}
+
+type QueryTracerTest struct {
+	Name         string
+	SQL          string
+	CommandTag   pgconn.CommandTag
+	Err          error
+	WaitingName  string
+	WaitingRows  int64
+	WaitingError bool
+}
+
+func TestQueryTracer(t *testing.T) {
+	tests := []QueryTracerTest{
+		{
+			Name:        "Update Test",
+			SQL:         "UPDATE wallet SET amount = amount + $1 WHERE id = $2 RETURNING amount",
+			CommandTag:  pgconn.NewCommandTag("UPDATE 1"),
+			WaitingName: "pgx UPDATE",
+			WaitingRows: 1,
+		},
+		{
+			Name:         "Error Test",
+			SQL:          "\n\tselect id from wallet",
+			Err:          errors.New("connection reset"),
+			WaitingName:  "pgx SELECT",
+			WaitingError: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			recorder, provider := newRecorder()
+			queryTracer := tracing.NewQueryTracer(provider)
+
+			ctx := queryTracer.TraceQueryStart(context.Background(), nil, pgx.TraceQueryStartData{SQL: test.SQL})
+			queryTracer.TraceQueryEnd(ctx, nil, pgx.TraceQueryEndData{CommandTag: test.CommandTag, Err: test.Err})
+
+			spans := recorder.Ended()
+			assert.Len(t, spans, 1)
+			assert.Equal(t, test.WaitingName, spans[0].Name())
+			assert.Equal(t, test.WaitingRows, attributes(spans[0])[tracing.RowCountKey].AsInt64())
+			if test.WaitingError {
+				assert.Equal(t, codes.Error, spans[0].Status().Code)
+			} else {
+				assert.Equal(t, codes.Unset, spans[0].Status().Code)
+			}
+		})
+	}
+}
+
+func TestEnd(t *testing.T) {
+	recorder, provider := newRecorder()
+	tracer := provider.Tracer("test")
+
+	_, span := tracer.Start(context.Background(), "validation")
+	tracing.End(span, customerror.ErrInvalidAmount)
+	_, span = tracer.Start(context.Background(), "internal")
+	tracing.End(span, customerror.NewError("walletRepo.GetWallet", "host:80", "connection reset"))
+
+	spans := recorder.Ended()
+	assert.Len(t, spans, 2)
+	assert.Equal(t, codes.Unset, spans[0].Status().Code)
+	assert.Len(t, spans[0].Events(), 1)
+	assert.Equal(t, codes.Error, spans[1].Status().Code)
+}
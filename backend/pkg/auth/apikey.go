@@ -0,0 +1,57 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// APIKeyPrefix marks API keys so they are easy to spot in leaked config.
+const APIKeyPrefix = "wk_"
+
+// APIKey is a stored key. Only the SHA-256 hash of the secret is kept, so a
+// database dump does not leak usable keys.
+type APIKey struct {
+	ID        uuid.UUID
+	Name      string
+	Hash      string
+	Scopes    []string
+	CreatedAt time.Time
+	ExpiresAt *time.Time
+	RevokedAt *time.Time
+}
+
+// Active reports whether the key may still authenticate at now.
+func (key *APIKey) Active(now time.Time) bool {
+	if key.RevokedAt != nil {
+		return false
+	}
+	return key.ExpiresAt == nil || now.Before(*key.ExpiresAt)
+}
+
+func (key *APIKey) Principal() *Principal {
+	return &Principal{
+		ID:     key.ID.String(),
+		Name:   key.Name,
+		Scopes: key.Scopes,
+	}
+}
+
+// GenerateAPIKey returns a new random secret. It is shown to the operator once
+// and never stored.
+func GenerateAPIKey() (string, error) {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return "", err
+	}
+	return APIKeyPrefix + base64.RawURLEncoding.EncodeToString(secret), nil
+}
+
+func HashAPIKey(secret string) string {
+	hash := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(hash[:])
+}
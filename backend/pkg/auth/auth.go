@@ -0,0 +1,65 @@
+package auth
+
+import (
+	"context"
+	"slices"
+)
+
+// Scopes granted to API keys.
+const (
+	ScopeWalletRead  = "wallet:read"
+	ScopeWalletWrite = "wallet:write"
+	// ScopeRatesWrite allows loading exchange rates, which affects every
+	// cross-currency transfer, so end users need the admin role on top.
+	ScopeRatesWrite = "rates:write"
+)
+
+var Scopes = []string{ScopeWalletRead, ScopeWalletWrite, ScopeRatesWrite}
+
+// WalletScopes are the scopes of the wallet routes.
+var WalletScopes = []string{ScopeWalletRead, ScopeWalletWrite}
+
+func ValidScope(scope string) bool {
+	return slices.Contains(Scopes, scope)
+}
+
+// Principal is the authenticated caller of a request. API keys belong to
+// services and may act on any wallet; bearer tokens belong to end users, named
+// by Subject, who may only act on the wallets they own unless Admin is set.
+type Principal struct {
+	ID      string
+	Name    string
+	Scopes  []string
+	Subject string
+	Admin   bool
+}
+
+func (principal *Principal) HasScope(scope string) bool {
+	return slices.Contains(principal.Scopes, scope)
+}
+
+// Restricted reports whether the caller is confined to its own wallets.
+func (principal *Principal) Restricted() bool {
+	return principal.Subject != "" && !principal.Admin
+}
+
+// Owns reports whether a wallet with ownerID may be used by the caller.
+// Wallets without an owner belong to no end user.
+func (principal *Principal) Owns(ownerID *string) bool {
+	if !principal.Restricted() {
+		return true
+	}
+	return ownerID != nil && *ownerID == principal.Subject
+}
+
+type contextKey struct{}
+
+func NewContext(ctx context.Context, principal *Principal) context.Context {
+	return context.WithValue(ctx, contextKey{}, principal)
+}
+
+// FromContext returns the caller stored in ctx, or nil for anonymous calls.
+func FromContext(ctx context.Context) *Principal {
+	principal, _ := ctx.Value(contextKey{}).(*Principal)
+	return principal
+}
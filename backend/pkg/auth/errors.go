@@ -0,0 +1,11 @@
+package auth
+
+import "backend/pkg/customerror"
+
+var ErrUnauthenticated = customerror.NewKindError(customerror.ErrUnauthorized, "missing or invalid credentials")
+
+var ErrInsufficientScope = customerror.NewKindError(customerror.ErrForbidden, "credentials lack the required scope")
+
+var ErrAPIKeyNotFound = customerror.NewKindError(customerror.ErrNotFound, "api key not found")
+
+var ErrInvalidScope = customerror.NewKindError(customerror.ErrValidation, "unknown scope")
@@ -0,0 +1,187 @@
+package auth
+
+import (
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Signing algorithms accepted in the "alg" header. Anything else, "none" in
+// particular, is rejected.
+const (
+	AlgorithmHS256 = "HS256"
+	AlgorithmRS256 = "RS256"
+)
+
+// minRSAKeyBits rejects JWKS keys too short to resist factoring.
+const minRSAKeyBits = 2048
+
+// clockSkew tolerates clocks of the token issuer and this service drifting.
+const clockSkew = 30 * time.Second
+
+type TokenVerifierI interface {
+	Verify(token string) (*Principal, error)
+}
+
+// JWTVerifier validates bearer tokens signed with a shared HS256 secret or
+// with one of the RS256 keys of a JWKS file. Issuer and Audience are only
+// checked when set.
+type JWTVerifier struct {
+	Secret    []byte
+	Keys      map[string]*rsa.PublicKey
+	Issuer    string
+	Audience  string
+	AdminRole string
+	Now       func() time.Time
+}
+
+func NewJWTVerifier(secret string, jwksPath string, issuer string, audience string, adminRole string) (*JWTVerifier, error) {
+	verifier := &JWTVerifier{
+		Issuer:    issuer,
+		Audience:  audience,
+		AdminRole: adminRole,
+		Now:       time.Now,
+	}
+	if secret != "" {
+		verifier.Secret = []byte(secret)
+	}
+	if jwksPath != "" {
+		keys, err := LoadJWKS(jwksPath)
+		if err != nil {
+			return nil, err
+		}
+		verifier.Keys = keys
+	}
+	return verifier, nil
+}
+
+type jwks struct {
+	Keys []struct {
+		Kty string `json:"kty"`
+		Kid string `json:"kid"`
+		Use string `json:"use"`
+		N   string `json:"n"`
+		E   string `json:"e"`
+	} `json:"keys"`
+}
+
+// LoadJWKS reads the RSA public keys of a JSON Web Key Set file, indexed by
+// key id. Keys of other types or meant for encryption are skipped; signing
+// keys shorter than 2048 bits fail the whole set.
+func LoadJWKS(path string) (map[string]*rsa.PublicKey, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var set jwks
+	if err := json.Unmarshal(raw, &set); err != nil {
+		return nil, err
+	}
+	keys := make(map[string]*rsa.PublicKey)
+	for _, key := range set.Keys {
+		if key.Kty != "RSA" || (key.Use != "" && key.Use != "sig") {
+			continue
+		}
+		n, err := base64.RawURLEncoding.DecodeString(key.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := base64.RawURLEncoding.DecodeString(key.E)
+		if err != nil {
+			return nil, err
+		}
+		exponent := new(big.Int).SetBytes(e)
+		if !exponent.IsInt64() || exponent.Int64() < 3 || exponent.Int64() > 1<<31-1 {
+			return nil, errors.New("jwks: key " + key.Kid + " has an invalid exponent")
+		}
+		modulus := new(big.Int).SetBytes(n)
+		if modulus.BitLen() < minRSAKeyBits {
+			return nil, errors.New("jwks: key " + key.Kid + " is shorter than 2048 bits")
+		}
+		keys[key.Kid] = &rsa.PublicKey{N: modulus, E: int(exponent.Int64())}
+	}
+	if len(keys) == 0 {
+		return nil, errors.New("jwks: no RSA signing keys in " + path)
+	}
+	return keys, nil
+}
+
+type tokenClaims struct {
+	jwt.RegisteredClaims
+	Scope string   `json:"scope"`
+	Role  string   `json:"role"`
+	Roles []string `json:"roles"`
+}
+
+// Verify checks the signature and the registered claims of token and returns
+// the end user it was issued to. Tokens without a "scope" claim get no scope
+// at all. Every failure is reported as ErrUnauthenticated.
+func (verifier *JWTVerifier) Verify(token string) (*Principal, error) {
+	options := []jwt.ParserOption{
+		jwt.WithValidMethods([]string{AlgorithmHS256, AlgorithmRS256}),
+		jwt.WithExpirationRequired(),
+		jwt.WithLeeway(clockSkew),
+		jwt.WithTimeFunc(verifier.Now),
+	}
+	if verifier.Issuer != "" {
+		options = append(options, jwt.WithIssuer(verifier.Issuer))
+	}
+	if verifier.Audience != "" {
+		options = append(options, jwt.WithAudience(verifier.Audience))
+	}
+	var claims tokenClaims
+	_, err := jwt.NewParser(options...).ParseWithClaims(token, &claims, verifier.key)
+	if err != nil || claims.Subject == "" {
+		return nil, ErrUnauthenticated
+	}
+
+	principal := &Principal{
+		ID:      claims.Subject,
+		Name:    claims.Subject,
+		Subject: claims.Subject,
+		Scopes:  strings.Fields(claims.Scope),
+	}
+	if verifier.AdminRole != "" {
+		principal.Admin = claims.Role == verifier.AdminRole || slices.Contains(claims.Roles, verifier.AdminRole)
+	}
+	return principal, nil
+}
+
+// key returns the key that verifies token, which depends on its algorithm.
+func (verifier *JWTVerifier) key(token *jwt.Token) (any, error) {
+	switch token.Method.Alg() {
+	case AlgorithmHS256:
+		if len(verifier.Secret) == 0 {
+			return nil, ErrUnauthenticated
+		}
+		return verifier.Secret, nil
+	case AlgorithmRS256:
+		kid, _ := token.Header["kid"].(string)
+		key := verifier.rsaKey(kid)
+		if key == nil {
+			return nil, ErrUnauthenticated
+		}
+		return key, nil
+	default:
+		return nil, ErrUnauthenticated
+	}
+}
+
+// rsaKey picks the key named by kid. A token without kid is accepted only
+// when the key set holds a single key.
+func (verifier *JWTVerifier) rsaKey(kid string) *rsa.PublicKey {
+	if kid == "" && len(verifier.Keys) == 1 {
+		for _, key := range verifier.Keys {
+			return key
+		}
+	}
+	return verifier.Keys[kid]
+}
@@ -0,0 +1,218 @@
+package auth_test
+
+import (
+	"backend/pkg/auth"
+	"crypto"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const testSecret = "0123456789abcdef0123456789abcdef"
+
+func encodeSegment(t *testing.T, value any) string {
+	raw, err := json.Marshal(value)
+	assert.NoError(t, err)
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
+
+func signHS256(t *testing.T, header map[string]any, claims map[string]any) string {
+	signed := encodeSegment(t, header) + "." + encodeSegment(t, claims)
+	mac := hmac.New(sha256.New, []byte(testSecret))
+	mac.Write([]byte(signed))
+	return signed + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func signRS256(t *testing.T, key *rsa.PrivateKey, kid string, claims map[string]any) string {
+	signed := encodeSegment(t, map[string]any{"alg": "RS256", "kid": kid}) + "." + encodeSegment(t, claims)
+	digest := sha256.Sum256([]byte(signed))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+	assert.NoError(t, err)
+	return signed + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+type VerifyTest struct {
+	Name             string
+	Token            func(t *testing.T) string
+	WaitingPrincipal *auth.Principal
+}
+
+func TestJWTVerifier_Verify(t *testing.T) {
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	hs256 := map[string]any{"alg": "HS256", "typ": "JWT"}
+	claims := func(extra map[string]any) map[string]any {
+		base := map[string]any{"sub": "user-1", "exp": now.Add(time.Hour).Unix(), "iss": "issuer", "aud": "wallet-api"}
+		for name, value := range extra {
+			base[name] = value
+		}
+		return base
+	}
+
+	tests := []VerifyTest{
+		{
+			Name: "Success Test",
+			Token: func(t *testing.T) string {
+				return signHS256(t, hs256, claims(map[string]any{"scope": "wallet:read wallet:write"}))
+			},
+			WaitingPrincipal: &auth.Principal{
+				ID: "user-1", Name: "user-1", Subject: "user-1", Scopes: auth.WalletScopes,
+			},
+		},
+		{
+			Name:  "No Scope Test",
+			Token: func(t *testing.T) string { return signHS256(t, hs256, claims(nil)) },
+			WaitingPrincipal: &auth.Principal{
+				ID: "user-1", Name: "user-1", Subject: "user-1", Scopes: []string{},
+			},
+		},
+		{
+			Name: "Scope And Admin Role Test",
+			Token: func(t *testing.T) string {
+				return signHS256(t, hs256, claims(map[string]any{"scope": "wallet:read", "roles": []string{"admin"}, "aud": []string{"other", "wallet-api"}}))
+			},
+			WaitingPrincipal: &auth.Principal{
+				ID: "user-1", Name: "user-1", Subject: "user-1", Scopes: []string{auth.ScopeWalletRead}, Admin: true,
+			},
+		},
+		{
+			Name: "Expired Test",
+			Token: func(t *testing.T) string {
+				return signHS256(t, hs256, claims(map[string]any{"exp": now.Add(-time.Minute).Unix()}))
+			},
+		},
+		{
+			Name: "Not Yet Valid Test",
+			Token: func(t *testing.T) string {
+				return signHS256(t, hs256, claims(map[string]any{"nbf": now.Add(time.Minute).Unix()}))
+			},
+		},
+		{
+			Name:  "Wrong Audience Test",
+			Token: func(t *testing.T) string { return signHS256(t, hs256, claims(map[string]any{"aud": "other"})) },
+		},
+		{
+			Name:  "Wrong Issuer Test",
+			Token: func(t *testing.T) string { return signHS256(t, hs256, claims(map[string]any{"iss": "other"})) },
+		},
+		{
+			Name:  "Missing Subject Test",
+			Token: func(t *testing.T) string { return signHS256(t, hs256, claims(map[string]any{"sub": ""})) },
+		},
+		{
+			Name: "Alg None Test",
+			Token: func(t *testing.T) string {
+				return encodeSegment(t, map[string]any{"alg": "none"}) + "." + encodeSegment(t, claims(nil)) + "."
+			},
+		},
+		{
+			Name: "Tampered Claims Test",
+			Token: func(t *testing.T) string {
+				token := signHS256(t, hs256, claims(nil))
+				forged := signHS256(t, hs256, claims(map[string]any{"role": "admin"}))
+				return forged[:len(forged)-43] + token[len(token)-43:]
+			},
+		},
+		{
+			Name: "Missing Expiry Test",
+			Token: func(t *testing.T) string {
+				noExpiry := claims(nil)
+				delete(noExpiry, "exp")
+				return signHS256(t, hs256, noExpiry)
+			},
+		},
+		{
+			Name:  "Malformed Test",
+			Token: func(t *testing.T) string { return "not-a-token" },
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			verifier := &auth.JWTVerifier{
+				Secret:    []byte(testSecret),
+				Issuer:    "issuer",
+				Audience:  "wallet-api",
+				AdminRole: "admin",
+				Now:       func() time.Time { return now },
+			}
+			principal, err := verifier.Verify(test.Token(t))
+			if test.WaitingPrincipal == nil {
+				assert.ErrorIs(t, err, auth.ErrUnauthenticated)
+				assert.Nil(t, principal)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, test.WaitingPrincipal, principal)
+		})
+	}
+}
+
+func writeJWKS(t *testing.T, kid string, key *rsa.PublicKey) string {
+	jwks := map[string]any{"keys": []map[string]string{{
+		"kty": "RSA",
+		"kid": kid,
+		"use": "sig",
+		"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+		"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+	}}}
+	raw, err := json.Marshal(jwks)
+	assert.NoError(t, err)
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	assert.NoError(t, os.WriteFile(path, raw, 0o600))
+	return path
+}
+
+func TestLoadJWKS_ShortKey(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 1024)
+	assert.NoError(t, err)
+
+	_, err = auth.LoadJWKS(writeJWKS(t, "key-1", &key.PublicKey))
+	assert.Error(t, err)
+}
+
+func TestJWTVerifier_RS256(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, err)
+	other, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, err)
+	path := writeJWKS(t, "key-1", &key.PublicKey)
+
+	verifier, err := auth.NewJWTVerifier("", path, "", "", "admin")
+	assert.NoError(t, err)
+	claims := map[string]any{"sub": "user-1", "exp": time.Now().Add(time.Hour).Unix()}
+
+	principal, err := verifier.Verify(signRS256(t, key, "key-1", claims))
+	assert.NoError(t, err)
+	assert.Equal(t, "user-1", principal.Subject)
+
+	_, err = verifier.Verify(signRS256(t, other, "key-1", claims))
+	assert.ErrorIs(t, err, auth.ErrUnauthenticated)
+
+	_, err = verifier.Verify(signHS256(t, map[string]any{"alg": "HS256"}, claims))
+	assert.ErrorIs(t, err, auth.ErrUnauthenticated)
+}
+
+func TestPrincipal_Owns(t *testing.T) {
+	owner := "user-1"
+	stranger := "user-2"
+
+	user := &auth.Principal{Subject: owner}
+	assert.True(t, user.Owns(&owner))
+	assert.False(t, user.Owns(&stranger))
+	assert.False(t, user.Owns(nil))
+
+	admin := &auth.Principal{Subject: owner, Admin: true}
+	assert.True(t, admin.Owns(&stranger))
+
+	service := &auth.Principal{ID: "key"}
+	assert.True(t, service.Owns(nil))
+}
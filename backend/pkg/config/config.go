@@ -2,11 +2,30 @@ package config
 
 import (
 	"backend/pkg/customerror"
+	"backend/pkg/wallet"
+	"log/slog"
+	"math"
 	"os"
+	"strconv"
+	"time"
 
 	"github.com/joho/godotenv"
 )
 
+const (
+	defaultOperationTimeout = 5 * time.Second
+	defaultShutdownTimeout  = 15 * time.Second
+	defaultReadinessTimeout = time.Second
+	// minJWTSecretLength is the HS256 key size RFC 7518 requires.
+	minJWTSecretLength         = 32
+	defaultClientRate          = 50
+	defaultClientBurst         = 100
+	defaultWalletRate          = 10
+	defaultWalletBurst         = 20
+	defaultHoldExpiryInterval  = time.Minute
+	defaultHoldExpiryBatchSize = 100
+)
+
 type Config struct {
 	DbHost     string
 	DbPort     string
@@ -15,6 +34,49 @@ type Config struct {
 	DbName     string
 	WebHost    string
 	WebPort    string
+	// OperationTimeout bounds each service call, on top of the request context.
+	OperationTimeout time.Duration
+	// LegacyStatusEnvelope makes handlers answer with HTTP 200 and carry the
+	// real status in the response body, as the API did originally.
+	LegacyStatusEnvelope bool
+	// ShutdownTimeout is how long in-flight requests may drain after SIGTERM
+	// before the server is closed. It should exceed OperationTimeout.
+	ShutdownTimeout time.Duration
+	// ReadinessTimeout bounds the database ping done by the readiness probe.
+	ReadinessTimeout time.Duration
+	LogLevel         slog.Level
+	// LogFormat is either "json", the default, or "text".
+	LogFormat string
+	// TracingExporter is "none", the default, or "otlp" to send spans over
+	// OTLP/HTTP to TracingEndpoint.
+	TracingExporter string
+	TracingEndpoint string
+	TracingInsecure bool
+	ServiceName     string
+	// JWTSecret and JWTJWKSFile enable bearer tokens signed with HS256 and
+	// RS256 respectively. Without either only API keys are accepted.
+	JWTSecret   string
+	JWTJWKSFile string
+	// JWTIssuer and JWTAudience, when set, must match the iss and aud claims.
+	JWTIssuer   string
+	JWTAudience string
+	// JWTAdminRole is the role claim that lifts the wallet ownership check.
+	JWTAdminRole string
+	// RateLimitStore is "memory", the default, which limits every replica on
+	// its own, or "postgres", which shares the buckets between replicas.
+	RateLimitStore string
+	// Rates are in requests per second. A zero rate disables that limit.
+	ClientRate  float64
+	ClientBurst int
+	WalletRate  float64
+	WalletBurst int
+	// DefaultCurrency is the ISO 4217 code of wallets created without one.
+	DefaultCurrency string
+	// HoldExpiryInterval is how often expired holds are released; zero turns
+	// the worker off in this replica. HoldExpiryBatchSize is the number of
+	// holds released per database transaction.
+	HoldExpiryInterval  time.Duration
+	HoldExpiryBatchSize int
 }
 
 func NewConfig(dotenvPath string) (*Config, error) {
@@ -51,5 +113,138 @@ func NewConfig(dotenvPath string) (*Config, error) {
 	if config.WebPort == "" {
 		return &Config{}, customerror.NewError("config.NewConfig", "", "WEB_PORT incorrect")
 	}
+	config.OperationTimeout = defaultOperationTimeout
+	if operationTimeout := os.Getenv("OPERATION_TIMEOUT"); operationTimeout != "" {
+		config.OperationTimeout, err = time.ParseDuration(operationTimeout)
+		if err != nil || config.OperationTimeout <= 0 {
+			return &Config{}, customerror.NewError("config.NewConfig", "", "OPERATION_TIMEOUT incorrect")
+		}
+	}
+	config.ShutdownTimeout = defaultShutdownTimeout
+	if shutdownTimeout := os.Getenv("SHUTDOWN_TIMEOUT"); shutdownTimeout != "" {
+		config.ShutdownTimeout, err = time.ParseDuration(shutdownTimeout)
+		if err != nil || config.ShutdownTimeout <= 0 {
+			return &Config{}, customerror.NewError("config.NewConfig", "", "SHUTDOWN_TIMEOUT incorrect")
+		}
+	}
+	config.ReadinessTimeout = defaultReadinessTimeout
+	if readinessTimeout := os.Getenv("READINESS_TIMEOUT"); readinessTimeout != "" {
+		config.ReadinessTimeout, err = time.ParseDuration(readinessTimeout)
+		if err != nil || config.ReadinessTimeout <= 0 {
+			return &Config{}, customerror.NewError("config.NewConfig", "", "READINESS_TIMEOUT incorrect")
+		}
+	}
+	if logLevel := os.Getenv("LOG_LEVEL"); logLevel != "" {
+		if err := config.LogLevel.UnmarshalText([]byte(logLevel)); err != nil {
+			return &Config{}, customerror.NewError("config.NewConfig", "", "LOG_LEVEL incorrect")
+		}
+	}
+	config.LogFormat = os.Getenv("LOG_FORMAT")
+	switch config.LogFormat {
+	case "":
+		config.LogFormat = "json"
+	case "json", "text":
+	default:
+		return &Config{}, customerror.NewError("config.NewConfig", "", "LOG_FORMAT incorrect")
+	}
+	config.TracingExporter = os.Getenv("TRACING_EXPORTER")
+	switch config.TracingExporter {
+	case "":
+		config.TracingExporter = "none"
+	case "none":
+	case "otlp":
+		config.TracingEndpoint = os.Getenv("TRACING_ENDPOINT")
+		if config.TracingEndpoint == "" {
+			return &Config{}, customerror.NewError("config.NewConfig", "", "TRACING_ENDPOINT incorrect")
+		}
+	default:
+		return &Config{}, customerror.NewError("config.NewConfig", "", "TRACING_EXPORTER incorrect")
+	}
+	if tracingInsecure := os.Getenv("TRACING_INSECURE"); tracingInsecure != "" {
+		config.TracingInsecure, err = strconv.ParseBool(tracingInsecure)
+		if err != nil {
+			return &Config{}, customerror.NewError("config.NewConfig", "", "TRACING_INSECURE incorrect")
+		}
+	}
+	config.ServiceName = os.Getenv("SERVICE_NAME")
+	if config.ServiceName == "" {
+		config.ServiceName = "wallet-api"
+	}
+	config.JWTSecret = os.Getenv("JWT_HS256_SECRET")
+	if config.JWTSecret != "" && len(config.JWTSecret) < minJWTSecretLength {
+		return &Config{}, customerror.NewError("config.NewConfig", "", "JWT_HS256_SECRET incorrect")
+	}
+	config.JWTJWKSFile = os.Getenv("JWT_JWKS_FILE")
+	config.JWTIssuer = os.Getenv("JWT_ISSUER")
+	config.JWTAudience = os.Getenv("JWT_AUDIENCE")
+	config.JWTAdminRole = os.Getenv("JWT_ADMIN_ROLE")
+	if config.JWTAdminRole == "" {
+		config.JWTAdminRole = "admin"
+	}
+	config.RateLimitStore = os.Getenv("RATE_LIMIT_STORE")
+	switch config.RateLimitStore {
+	case "":
+		config.RateLimitStore = "memory"
+	case "memory", "postgres":
+	default:
+		return &Config{}, customerror.NewError("config.NewConfig", "", "RATE_LIMIT_STORE incorrect")
+	}
+	config.ClientRate, config.ClientBurst, err = parseLimit("RATE_LIMIT_CLIENT", defaultClientRate, defaultClientBurst)
+	if err != nil {
+		return &Config{}, err
+	}
+	config.WalletRate, config.WalletBurst, err = parseLimit("RATE_LIMIT_WALLET", defaultWalletRate, defaultWalletBurst)
+	if err != nil {
+		return &Config{}, err
+	}
+	config.DefaultCurrency = os.Getenv("DEFAULT_CURRENCY")
+	if config.DefaultCurrency == "" {
+		config.DefaultCurrency = "USD"
+	}
+	if !wallet.ValidCurrencyCode(config.DefaultCurrency) {
+		return &Config{}, customerror.NewError("config.NewConfig", "", "DEFAULT_CURRENCY incorrect")
+	}
+	config.HoldExpiryInterval = defaultHoldExpiryInterval
+	if holdExpiryInterval := os.Getenv("HOLD_EXPIRY_INTERVAL"); holdExpiryInterval != "" {
+		config.HoldExpiryInterval, err = time.ParseDuration(holdExpiryInterval)
+		if err != nil || config.HoldExpiryInterval < 0 {
+			return &Config{}, customerror.NewError("config.NewConfig", "", "HOLD_EXPIRY_INTERVAL incorrect")
+		}
+	}
+	config.HoldExpiryBatchSize = defaultHoldExpiryBatchSize
+	if holdExpiryBatchSize := os.Getenv("HOLD_EXPIRY_BATCH_SIZE"); holdExpiryBatchSize != "" {
+		config.HoldExpiryBatchSize, err = strconv.Atoi(holdExpiryBatchSize)
+		if err != nil || config.HoldExpiryBatchSize < 1 {
+			return &Config{}, customerror.NewError("config.NewConfig", "", "HOLD_EXPIRY_BATCH_SIZE incorrect")
+		}
+	}
+	if legacyStatusEnvelope := os.Getenv("LEGACY_STATUS_ENVELOPE"); legacyStatusEnvelope != "" {
+		config.LegacyStatusEnvelope, err = strconv.ParseBool(legacyStatusEnvelope)
+		if err != nil {
+			return &Config{}, customerror.NewError("config.NewConfig", "", "LEGACY_STATUS_ENVELOPE incorrect")
+		}
+	}
 	return &config, nil
 }
+
+// parseLimit reads the <prefix>_RATE and <prefix>_BURST pair of a rate limit.
+func parseLimit(prefix string, defaultRate float64, defaultBurst int) (float64, int, error) {
+	rate, burst := defaultRate, defaultBurst
+	if rateStr := os.Getenv(prefix + "_RATE"); rateStr != "" {
+		parsed, err := strconv.ParseFloat(rateStr, 64)
+		// ParseFloat accepts NaN and Inf, which would silently turn the limit
+		// off or make it unbounded.
+		if err != nil || parsed < 0 || math.IsNaN(parsed) || math.IsInf(parsed, 0) {
+			return 0, 0, customerror.NewError("config.NewConfig", "", prefix+"_RATE incorrect")
+		}
+		rate = parsed
+	}
+	if burstStr := os.Getenv(prefix + "_BURST"); burstStr != "" {
+		parsed, err := strconv.Atoi(burstStr)
+		if err != nil || parsed < 1 {
+			return 0, 0, customerror.NewError("config.NewConfig", "", prefix+"_BURST incorrect")
+		}
+		burst = parsed
+	}
+	return rate, burst, nil
+}
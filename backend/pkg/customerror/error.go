@@ -1,23 +1,151 @@
 package customerror
 
-import "fmt"
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+)
+
+// Kinds classify an error independently of the layer that produced it.
+// Handlers map them to responses with errors.Is, so every error created by
+// this package wraps exactly one kind.
+var (
+	ErrNotFound     = errors.New("not found")
+	ErrConflict     = errors.New("conflict")
+	ErrValidation   = errors.New("validation failed")
+	ErrUnauthorized = errors.New("unauthorized")
+	ErrForbidden    = errors.New("forbidden")
+	ErrInternal     = errors.New("internal error")
+)
+
+var ErrWrongAmount = NewKindError(ErrValidation, "wrong amount")
+
+var ErrInvalidAmount = NewKindError(ErrValidation, "invalid amount")
+
+// ErrAmountOutOfRange is returned for amounts or balances that do not fit the
+// BIGINT columns they are stored in.
+var ErrAmountOutOfRange = NewKindError(ErrValidation, "amount out of range")
+
+var ErrWrongOperation = NewKindError(ErrValidation, "wrong operation")
+
+var ErrWrongCursor = NewKindError(ErrValidation, "wrong cursor")
+
+var ErrSameWallet = NewKindError(ErrValidation, "source and destination wallets are the same")
+
+var ErrWrongIdempotencyKey = NewKindError(ErrValidation, "wrong idempotency key")
+
+var ErrIdempotencyKeyReused = NewKindError(ErrConflict, "idempotency key reused with a different request")
+
+type kindError struct {
+	message string
+	kind    error
+}
+
+func (kindError *kindError) Error() string {
+	return kindError.message
+}
+
+func (kindError *kindError) Unwrap() error {
+	return kindError.kind
+}
+
+// NewKindError returns a sentinel error that reports message and matches kind
+// under errors.Is.
+func NewKindError(kind error, message string) error {
+	return &kindError{
+		message: message,
+		kind:    kind,
+	}
+}
+
+// KindOf returns the kind err belongs to, defaulting to ErrInternal.
+func KindOf(err error) error {
+	for _, kind := range []error{ErrNotFound, ErrConflict, ErrValidation, ErrUnauthorized, ErrForbidden} {
+		if errors.Is(err, kind) {
+			return kind
+		}
+	}
+	return ErrInternal
+}
 
 type CustomError struct {
 	Module   string
 	Endpoint string
 	Message  string
+	// RequestID ties the error to the client request that caused it.
+	RequestID string
+	Kind      error
+	Err       error
 }
 
-var ErrWrongAmount = fmt.Errorf("wrong amount")
-
-var ErrWrongOperation = fmt.Errorf("wrong operation")
-
 func (customError CustomError) Error() string {
+	if customError.RequestID != "" {
+		return fmt.Sprintf("ERROR|%s|%s:%s|%s", customError.Endpoint, customError.Module, customError.Message, customError.RequestID)
+	}
 	return fmt.Sprintf("ERROR|%s|%s:%s", customError.Endpoint, customError.Module, customError.Message)
 }
 
-func (customError *CustomError) AppendModule(module string) {
+func (customError CustomError) Unwrap() []error {
+	wrapped := make([]error, 0, 2)
+	if customError.Kind != nil {
+		wrapped = append(wrapped, customError.Kind)
+	}
+	if customError.Err != nil {
+		wrapped = append(wrapped, customError.Err)
+	}
+	return wrapped
+}
+
+// Is reports whether target describes the same failure, so an error rebuilt
+// with NewError matches one that also carries its cause.
+func (customError CustomError) Is(target error) bool {
+	other, ok := target.(CustomError)
+	if !ok {
+		return false
+	}
+	return customError.Module == other.Module &&
+		customError.Endpoint == other.Endpoint &&
+		customError.Message == other.Message
+}
+
+// WithModule returns a copy of the error with module prepended to its call
+// stack. Unlike mutating a type-asserted value it cannot lose the update.
+func (customError CustomError) WithModule(module string) CustomError {
 	customError.Module = module + "." + customError.Module
+	return customError
+}
+
+// LogValue logs the error as a group, so log pipelines can index it by module
+// and endpoint instead of parsing the Error string.
+func (customError CustomError) LogValue() slog.Value {
+	kind := ErrInternal
+	if customError.Kind != nil {
+		kind = customError.Kind
+	}
+	attrs := []slog.Attr{
+		slog.String("module", customError.Module),
+		slog.String("endpoint", customError.Endpoint),
+		slog.String("message", customError.Message),
+		slog.String("kind", kind.Error()),
+	}
+	if customError.RequestID != "" {
+		attrs = append(attrs, slog.String("request_id", customError.RequestID))
+	}
+	return slog.GroupValue(attrs...)
+}
+
+// WithRequestID records the id of the request that failed, wrapping err into a
+// CustomError first when needed.
+func WithRequestID(err error, requestID string) error {
+	if err == nil || requestID == "" {
+		return err
+	}
+	customError, ok := err.(CustomError)
+	if !ok {
+		customError = Wrap(err, "", "").(CustomError)
+	}
+	customError.RequestID = requestID
+	return customError
 }
 
 func NewError(module, endpoint, message string) error {
@@ -25,5 +153,39 @@ func NewError(module, endpoint, message string) error {
 		Module:   module,
 		Endpoint: endpoint,
 		Message:  message,
+		Kind:     ErrInternal,
+	}
+}
+
+// Wrap turns any error into a CustomError raised in module, keeping err
+// reachable through errors.Is and errors.As.
+func Wrap(err error, module, endpoint string) error {
+	if err == nil {
+		return nil
+	}
+	return CustomError{
+		Module:   module,
+		Endpoint: endpoint,
+		Message:  err.Error(),
+		Kind:     KindOf(err),
+		Err:      err,
+	}
+}
+
+// AppendModule records module in the call stack of err. A CustomError gets the
+// module prepended; any other error, such as a context deadline or a driver
+// error wrapped elsewhere, is wrapped into a new CustomError instead.
+func AppendModule(err error, module string) error {
+	if err == nil {
+		return nil
+	}
+	if customError, ok := err.(CustomError); ok {
+		return customError.WithModule(module)
+	}
+	endpoint := ""
+	var inner CustomError
+	if errors.As(err, &inner) {
+		endpoint = inner.Endpoint
 	}
+	return Wrap(err, module, endpoint)
 }
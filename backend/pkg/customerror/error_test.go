@@ -0,0 +1,146 @@
+package customerror_test
+
+import (
+	"backend/pkg/customerror"
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type KindOfTest struct {
+	Name        string
+	Err         error
+	WaitingKind error
+}
+
+func TestKindOf(t *testing.T) {
+	tests := []KindOfTest{
+		{
+			Name:        "Validation Sentinel Test",
+			Err:         customerror.ErrWrongAmount,
+			WaitingKind: customerror.ErrValidation,
+		},
+		{
+			Name:        "Wrapped Conflict Test",
+			Err:         fmt.Errorf("reuse: %w", customerror.ErrIdempotencyKeyReused),
+			WaitingKind: customerror.ErrConflict,
+		},
+		{
+			Name:        "Unauthorized Test",
+			Err:         customerror.NewKindError(customerror.ErrUnauthorized, "invalid api key"),
+			WaitingKind: customerror.ErrUnauthorized,
+		},
+		{
+			Name:        "Custom Error Test",
+			Err:         customerror.NewError("module", "endpoint", "message"),
+			WaitingKind: customerror.ErrInternal,
+		},
+		{
+			Name:        "Foreign Error Test",
+			Err:         context.DeadlineExceeded,
+			WaitingKind: customerror.ErrInternal,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			assert.Equal(t, test.WaitingKind, customerror.KindOf(test.Err))
+		})
+	}
+}
+
+type AppendModuleTest struct {
+	Name          string
+	Err           error
+	Module        string
+	WaitingString string
+	WaitingIs     []error
+}
+
+func TestAppendModule(t *testing.T) {
+	cause := errors.New("connection reset")
+	tests := []AppendModuleTest{
+		{
+			Name:          "Custom Error Test",
+			Err:           customerror.NewError("walletRepo.GetWallet", "host:80", "error"),
+			Module:        "GetBalance",
+			WaitingString: "ERROR|host:80|GetBalance.walletRepo.GetWallet:error",
+			WaitingIs:     []error{customerror.ErrInternal},
+		},
+		{
+			Name:          "Wrapped Cause Test",
+			Err:           customerror.Wrap(cause, "walletRepo.GetWallet", "host:80"),
+			Module:        "GetBalance",
+			WaitingString: "ERROR|host:80|GetBalance.walletRepo.GetWallet:connection reset",
+			WaitingIs:     []error{customerror.ErrInternal, cause},
+		},
+		{
+			Name:          "Foreign Error Test",
+			Err:           context.DeadlineExceeded,
+			Module:        "GetBalance",
+			WaitingString: "ERROR||GetBalance:context deadline exceeded",
+			WaitingIs:     []error{customerror.ErrInternal, context.DeadlineExceeded},
+		},
+		{
+			Name:          "Foreign Wrapper Test",
+			Err:           fmt.Errorf("retry: %w", customerror.Wrap(cause, "walletRepo.GetWallet", "host:80")),
+			Module:        "GetBalance",
+			WaitingString: "ERROR|host:80|GetBalance:retry: ERROR|host:80|walletRepo.GetWallet:connection reset",
+			WaitingIs:     []error{customerror.ErrInternal, cause},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			err := customerror.AppendModule(test.Err, test.Module)
+			assert.EqualError(t, err, test.WaitingString)
+			for _, target := range test.WaitingIs {
+				assert.ErrorIs(t, err, target)
+			}
+			var customError customerror.CustomError
+			assert.ErrorAs(t, err, &customError)
+		})
+	}
+	assert.NoError(t, customerror.AppendModule(nil, "GetBalance"))
+}
+
+type WithRequestIDTest struct {
+	Name          string
+	Err           error
+	RequestID     string
+	WaitingString string
+}
+
+func TestWithRequestID(t *testing.T) {
+	tests := []WithRequestIDTest{
+		{
+			Name:          "Custom Error Test",
+			Err:           customerror.NewError("GetBalance.walletRepo.GetWallet", "host:80", "error"),
+			RequestID:     "req-1",
+			WaitingString: "ERROR|host:80|GetBalance.walletRepo.GetWallet:error|req-1",
+		},
+		{
+			Name:          "Foreign Error Test",
+			Err:           context.DeadlineExceeded,
+			RequestID:     "req-1",
+			WaitingString: "ERROR||:context deadline exceeded|req-1",
+		},
+		{
+			Name:          "Empty Request ID Test",
+			Err:           customerror.NewError("GetBalance", "host:80", "error"),
+			WaitingString: "ERROR|host:80|GetBalance:error",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			err := customerror.WithRequestID(test.Err, test.RequestID)
+			assert.EqualError(t, err, test.WaitingString)
+			assert.ErrorIs(t, err, test.Err)
+		})
+	}
+	assert.NoError(t, customerror.WithRequestID(nil, "req-1"))
+}
@@ -1,9 +1,74 @@
 package requests
 
-import "github.com/google/uuid"
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+)
 
 type UpdateBalanceRequest struct {
 	WalletId      uuid.UUID `json:"valletId"`
 	OperationType string    `json:"operationType"`
-	Amount        int64     `json:"amount"`
+	// Amount is an integer in minor units, or a decimal string in major units
+	// when the request asks for decimal amounts.
+	Amount json.Number `json:"amount"`
+	// Currency, when given, must be the currency of the wallet.
+	Currency       string `json:"currency,omitempty"`
+	IdempotencyKey string `json:"idempotencyKey,omitempty"`
+}
+
+type CreateWalletRequest struct {
+	WalletId uuid.UUID   `json:"walletId"`
+	Amount   json.Number `json:"amount"`
+	// Currency is an ISO 4217 code; the configured default when omitted.
+	Currency string `json:"currency,omitempty"`
+}
+
+type GetTransactionsRequest struct {
+	Limit         int       `form:"limit" binding:"omitempty,min=1,max=100"`
+	Cursor        string    `form:"cursor"`
+	OperationType string    `form:"operationType" binding:"omitempty,oneof=DEPOSIT WITHDRAW TRANSFER_IN TRANSFER_OUT HOLD CAPTURE RELEASE"`
+	From          time.Time `form:"from" time_format:"2006-01-02T15:04:05Z07:00"`
+	To            time.Time `form:"to" time_format:"2006-01-02T15:04:05Z07:00"`
+	Order         string    `form:"order" binding:"omitempty,oneof=newest oldest"`
+}
+
+type TransferRequest struct {
+	FromWalletId uuid.UUID   `json:"fromWalletId" binding:"required"`
+	ToWalletId   uuid.UUID   `json:"toWalletId" binding:"required"`
+	Amount       json.Number `json:"amount"`
+	// Currency, when given, must be the currency of the source wallet; Amount
+	// is in that currency and converted when the destination holds another.
+	Currency string `json:"currency,omitempty"`
+}
+
+type CreateHoldRequest struct {
+	Amount json.Number `json:"amount"`
+	// Currency, when given, must be the currency of the wallet.
+	Currency string `json:"currency,omitempty"`
+	// ExpiresAt defaults to wallet.DefaultHoldDuration from now.
+	ExpiresAt *time.Time `json:"expiresAt,omitempty"`
+}
+
+// CaptureHoldRequest captures Amount of a hold, or all of it when Amount is
+// omitted or null. The body itself may be omitted for a full capture; an
+// explicit zero is rejected.
+type CaptureHoldRequest struct {
+	Amount json.Number `json:"amount"`
+}
+
+// ImportRatesRequest loads exchange rates. Rate is a decimal string so no
+// digit is lost to a float; ValidFrom defaults to the time of the import.
+type ImportRatesRequest struct {
+	Rates []ExchangeRateRequest `json:"rates" binding:"required"`
+}
+
+type ExchangeRateRequest struct {
+	Base      string     `json:"base"`
+	Quote     string     `json:"quote"`
+	Rate      string     `json:"rate"`
+	ValidFrom *time.Time `json:"validFrom"`
+	ValidTo   *time.Time `json:"validTo"`
+	Source    string     `json:"source"`
 }
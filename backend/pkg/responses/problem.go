@@ -0,0 +1,103 @@
+package responses
+
+import "net/http"
+
+const ProblemContentType = "application/problem+json"
+
+// Error codes are part of the public API: clients switch on them, so they must
+// never change once released. Titles are for humans and may be reworded.
+const (
+	CodeInvalidRequest         = "INVALID_REQUEST"
+	CodeInvalidWalletID        = "INVALID_WALLET_ID"
+	CodeInvalidAmount          = "INVALID_AMOUNT"
+	CodeAmountOutOfRange       = "AMOUNT_OUT_OF_RANGE"
+	CodeInvalidAmountFormat    = "INVALID_AMOUNT_FORMAT"
+	CodeInvalidOperation       = "INVALID_OPERATION"
+	CodeInvalidCursor          = "INVALID_CURSOR"
+	CodeInvalidIdempotencyKey  = "INVALID_IDEMPOTENCY_KEY"
+	CodeInsufficientFunds      = "INSUFFICIENT_FUNDS"
+	CodeUnknownCurrency        = "UNKNOWN_CURRENCY"
+	CodeCurrencyMismatch       = "CURRENCY_MISMATCH"
+	CodeInvalidExchangeRate    = "INVALID_EXCHANGE_RATE"
+	CodeExchangeRateNotFound   = "EXCHANGE_RATE_NOT_FOUND"
+	CodeSameWallet             = "SAME_WALLET"
+	CodeWalletNotFound         = "WALLET_NOT_FOUND"
+	CodeWalletAlreadyExists    = "WALLET_ALREADY_EXISTS"
+	CodeHoldNotFound           = "HOLD_NOT_FOUND"
+	CodeHoldNotActive          = "HOLD_NOT_ACTIVE"
+	CodeHoldExpired            = "HOLD_EXPIRED"
+	CodeInvalidHoldExpiry      = "INVALID_HOLD_EXPIRY"
+	CodeIdempotencyKeyConflict = "IDEMPOTENCY_KEY_CONFLICT"
+	CodeUnauthorized           = "UNAUTHORIZED"
+	CodeInsufficientScope      = "INSUFFICIENT_SCOPE"
+	CodeForbidden              = "FORBIDDEN"
+	CodeRateLimited            = "RATE_LIMITED"
+	CodeNotFound               = "NOT_FOUND"
+	CodeConflict               = "CONFLICT"
+	CodeUnavailable            = "UNAVAILABLE"
+	CodeInternal               = "INTERNAL_ERROR"
+)
+
+// Problem is an RFC 7807 problem details object extended with a stable
+// machine-readable code.
+type Problem struct {
+	Type     string `json:"type"`
+	Title    string `json:"title"`
+	Status   int    `json:"status"`
+	Detail   string `json:"detail,omitempty"`
+	Instance string `json:"instance,omitempty"`
+	Code     string `json:"code"`
+	// RequestID is the X-Request-ID of the failed request, for support.
+	RequestID string `json:"requestId,omitempty"`
+	// LegacyMessage is the "error" of the legacy envelope when it differs
+	// from Title, so legacy clients keep reading the strings they match on.
+	LegacyMessage string `json:"-"`
+}
+
+func NewProblem(status int, code string, title string) Problem {
+	return Problem{
+		Type:   "urn:wallet-api:problem:" + code,
+		Title:  title,
+		Status: status,
+		Code:   code,
+	}
+}
+
+// WithLegacyMessage keeps message as the "error" legacy clients see.
+func (problem Problem) WithLegacyMessage(message string) Problem {
+	problem.LegacyMessage = message
+	return problem
+}
+
+var (
+	ProblemInvalidRequest         = NewProblem(http.StatusBadRequest, CodeInvalidRequest, "Wrong input")
+	ProblemInvalidWalletID        = NewProblem(http.StatusBadRequest, CodeInvalidWalletID, "Wrong uuid")
+	ProblemInvalidAmount          = NewProblem(http.StatusBadRequest, CodeInvalidAmount, "Wrong amount").WithLegacyMessage("Amount cant be less than zero")
+	ProblemAmountOutOfRange       = NewProblem(http.StatusBadRequest, CodeAmountOutOfRange, "Amount or resulting balance is too large")
+	ProblemInvalidAmountFormat    = NewProblem(http.StatusBadRequest, CodeInvalidAmountFormat, "Amount-Format must be minor or decimal")
+	ProblemInvalidOperation       = NewProblem(http.StatusBadRequest, CodeInvalidOperation, "Operation must be DEPOSIT or WITHDRAW")
+	ProblemInvalidFilterOperation = NewProblem(http.StatusBadRequest, CodeInvalidOperation, "Operation must be DEPOSIT, WITHDRAW, TRANSFER_IN, TRANSFER_OUT, HOLD, CAPTURE or RELEASE").WithLegacyMessage("Operation must be DEPOSIT, WITHDRAW, TRANSFER_IN or TRANSFER_OUT")
+	ProblemInvalidCursor          = NewProblem(http.StatusBadRequest, CodeInvalidCursor, "Wrong cursor")
+	ProblemInvalidIdempotencyKey  = NewProblem(http.StatusBadRequest, CodeInvalidIdempotencyKey, "Idempotency key is too long")
+	ProblemInsufficientFunds      = NewProblem(http.StatusBadRequest, CodeInsufficientFunds, "Insufficient funds").WithLegacyMessage("Amount cant be less than zero")
+	ProblemUnknownCurrency        = NewProblem(http.StatusBadRequest, CodeUnknownCurrency, "Currency is not supported")
+	ProblemCurrencyMismatch       = NewProblem(http.StatusBadRequest, CodeCurrencyMismatch, "Currency does not match the wallet")
+	ProblemInvalidExchangeRate    = NewProblem(http.StatusBadRequest, CodeInvalidExchangeRate, "Wrong exchange rate")
+	ProblemExchangeRateNotFound   = NewProblem(http.StatusBadRequest, CodeExchangeRateNotFound, "No exchange rate for the currency pair")
+	ProblemSameWallet             = NewProblem(http.StatusBadRequest, CodeSameWallet, "Source and destination wallets must differ")
+	ProblemWalletNotFound         = NewProblem(http.StatusNotFound, CodeWalletNotFound, "Wallet not found")
+	ProblemWalletAlreadyExists    = NewProblem(http.StatusConflict, CodeWalletAlreadyExists, "Wallet already exists")
+	ProblemHoldNotFound           = NewProblem(http.StatusNotFound, CodeHoldNotFound, "Hold not found")
+	ProblemHoldNotActive          = NewProblem(http.StatusConflict, CodeHoldNotActive, "Hold was already captured or released")
+	ProblemHoldExpired            = NewProblem(http.StatusConflict, CodeHoldExpired, "Hold has expired and can only be released")
+	ProblemInvalidHoldExpiry      = NewProblem(http.StatusBadRequest, CodeInvalidHoldExpiry, "Hold expiry must be in the future and at most 30 days away")
+	ProblemIdempotencyKeyConflict = NewProblem(http.StatusConflict, CodeIdempotencyKeyConflict, "Idempotency key was already used with a different request")
+	ProblemUnauthorized           = NewProblem(http.StatusUnauthorized, CodeUnauthorized, "Missing or invalid credentials")
+	ProblemInsufficientScope      = NewProblem(http.StatusForbidden, CodeInsufficientScope, "API key lacks the required scope")
+	ProblemForbidden              = NewProblem(http.StatusForbidden, CodeForbidden, "Forbidden")
+	ProblemRateLimited            = NewProblem(http.StatusTooManyRequests, CodeRateLimited, "Too many requests")
+	ProblemNotFound               = NewProblem(http.StatusNotFound, CodeNotFound, "Not found")
+	ProblemConflict               = NewProblem(http.StatusConflict, CodeConflict, "Conflict")
+	ProblemUnavailable            = NewProblem(http.StatusServiceUnavailable, CodeUnavailable, "Service is starting")
+	ProblemInternal               = NewProblem(http.StatusInternalServerError, CodeInternal, "Internal Server Error")
+)
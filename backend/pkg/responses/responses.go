@@ -0,0 +1,204 @@
+package responses
+
+import (
+	"backend/pkg/wallet"
+	"encoding/json"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Envelope wraps every successful response. Error is always null on success;
+// it is only filled for clients that still run in the legacy status mode.
+type Envelope struct {
+	Status int         `json:"status"`
+	Data   interface{} `json:"data"`
+	Error  *string     `json:"error"`
+}
+
+func NewEnvelope(status int, data interface{}) Envelope {
+	return Envelope{
+		Status: status,
+		Data:   data,
+	}
+}
+
+// NewLegacyError renders a problem in the original always-200 error shape.
+func NewLegacyError(problem Problem) Envelope {
+	title := problem.Title
+	if problem.LegacyMessage != "" {
+		title = problem.LegacyMessage
+	}
+	return Envelope{
+		Status: problem.Status,
+		Data:   struct{}{},
+		Error:  &title,
+	}
+}
+
+// LegacyBodyEnvelope is the legacy shape of POST /wallet, which carried its
+// payload under "body" rather than "data".
+type LegacyBodyEnvelope struct {
+	Status int         `json:"status"`
+	Body   interface{} `json:"body"`
+	Error  *string     `json:"error"`
+}
+
+func (envelope Envelope) LegacyBody() LegacyBodyEnvelope {
+	return LegacyBodyEnvelope{
+		Status: envelope.Status,
+		Body:   envelope.Data,
+		Error:  envelope.Error,
+	}
+}
+
+// Amount is an amount in minor units. It is written as a JSON integer or, when
+// In is set, as a JSON string in major units of In, such as "12.34", for
+// clients that cannot hold every int64 in a number.
+type Amount struct {
+	Value int64
+	In    *wallet.Currency
+}
+
+func (amount Amount) MarshalJSON() ([]byte, error) {
+	if amount.In == nil {
+		return strconv.AppendInt(nil, amount.Value, 10), nil
+	}
+	return json.Marshal(amount.In.FormatAmount(amount.Value))
+}
+
+// Balances are in minor units of Currency; MinorUnits is the number of
+// decimals to show them in major units.
+type WalletData struct {
+	ID         uuid.UUID `json:"id"`
+	Balance    Amount    `json:"balance"`
+	Currency   string    `json:"currency"`
+	MinorUnits int       `json:"minorUnits"`
+}
+
+// Balance is the total of the wallet and Available what is left of it after
+// the funds reserved by active holds.
+type BalanceData struct {
+	Balance    Amount `json:"balance"`
+	Available  Amount `json:"available"`
+	Currency   string `json:"currency"`
+	MinorUnits int    `json:"minorUnits"`
+}
+
+type TransactionData struct {
+	TransactionID uuid.UUID `json:"transactionId"`
+	Balance       Amount    `json:"balance"`
+}
+
+type TransactionsData struct {
+	Transactions []Transaction `json:"transactions"`
+	NextCursor   *string       `json:"nextCursor"`
+}
+
+// Transaction is a wallet.Transaction with its amounts written in the format
+// the client asked for.
+type Transaction struct {
+	ID            uuid.UUID  `json:"id"`
+	WalletID      uuid.UUID  `json:"walletId"`
+	OperationType string     `json:"operationType"`
+	Amount        Amount     `json:"amount"`
+	Balance       Amount     `json:"balance"`
+	TransferID    *uuid.UUID `json:"transferId,omitempty"`
+	RateID        *uuid.UUID `json:"rateId,omitempty"`
+	ExchangeRate  *string    `json:"exchangeRate,omitempty"`
+	HoldID        *uuid.UUID `json:"holdId,omitempty"`
+	// HeldAmount is the change in held funds of the wallet; only entries of
+	// holds move them.
+	HeldAmount *Amount   `json:"heldAmount,omitempty"`
+	CreatedAt  time.Time `json:"createdAt"`
+}
+
+// NewTransactions converts transactions of one wallet; in is passed on to
+// every Amount.
+func NewTransactions(transactions []wallet.Transaction, in *wallet.Currency) []Transaction {
+	converted := make([]Transaction, 0, len(transactions))
+	for _, transaction := range transactions {
+		var heldAmount *Amount
+		if transaction.HeldAmount != 0 {
+			heldAmount = &Amount{Value: transaction.HeldAmount, In: in}
+		}
+		converted = append(converted, Transaction{
+			ID:            transaction.ID,
+			WalletID:      transaction.WalletID,
+			OperationType: transaction.OperationType,
+			Amount:        Amount{Value: transaction.Amount, In: in},
+			Balance:       Amount{Value: transaction.Balance, In: in},
+			TransferID:    transaction.TransferID,
+			RateID:        transaction.RateID,
+			ExchangeRate:  transaction.ExchangeRate,
+			HoldID:        transaction.HoldID,
+			HeldAmount:    heldAmount,
+			CreatedAt:     transaction.CreatedAt,
+		})
+	}
+	return converted
+}
+
+// Amount is debited in the currency of the source wallet and CreditedAmount
+// credited in the currency of the destination; they differ only when
+// ExchangeRate is set. ToBalance is left out for end users paying into a
+// wallet they do not own.
+type TransferData struct {
+	TransferID     uuid.UUID `json:"transferId"`
+	FromWalletID   uuid.UUID `json:"fromWalletId"`
+	ToWalletID     uuid.UUID `json:"toWalletId"`
+	Amount         Amount    `json:"amount"`
+	CreditedAmount Amount    `json:"creditedAmount"`
+	ExchangeRate   *string   `json:"exchangeRate,omitempty"`
+	FromBalance    Amount    `json:"fromBalance"`
+	ToBalance      *Amount   `json:"toBalance,omitempty"`
+}
+
+type HoldData struct {
+	ID         uuid.UUID `json:"id"`
+	WalletID   uuid.UUID `json:"walletId"`
+	Amount     Amount    `json:"amount"`
+	Captured   Amount    `json:"captured"`
+	Status     string    `json:"status"`
+	ExpiresAt  time.Time `json:"expiresAt"`
+	CreatedAt  time.Time `json:"createdAt"`
+	Currency   string    `json:"currency"`
+	MinorUnits int       `json:"minorUnits"`
+}
+
+// NewHoldData converts hold; in is passed on to every Amount.
+func NewHoldData(hold *wallet.Hold, in *wallet.Currency) HoldData {
+	return HoldData{
+		ID:         hold.ID,
+		WalletID:   hold.WalletID,
+		Amount:     Amount{Value: hold.Amount, In: in},
+		Captured:   Amount{Value: hold.Captured, In: in},
+		Status:     hold.Status,
+		ExpiresAt:  hold.ExpiresAt,
+		CreatedAt:  hold.CreatedAt,
+		Currency:   hold.Currency.Code,
+		MinorUnits: hold.Currency.MinorUnits,
+	}
+}
+
+type RatesData struct {
+	Rates []wallet.ExchangeRate `json:"rates"`
+}
+
+// HealthData is the body of the liveness and readiness probes. Probes read the
+// HTTP status, so these are never wrapped in an Envelope.
+type HealthData struct {
+	Status string    `json:"status"`
+	Reason string    `json:"reason,omitempty"`
+	Pool   *PoolData `json:"pool,omitempty"`
+}
+
+type PoolData struct {
+	AcquiredConns     int32 `json:"acquiredConns"`
+	IdleConns         int32 `json:"idleConns"`
+	TotalConns        int32 `json:"totalConns"`
+	MaxConns          int32 `json:"maxConns"`
+	AcquireCount      int64 `json:"acquireCount"`
+	EmptyAcquireCount int64 `json:"emptyAcquireCount"`
+}
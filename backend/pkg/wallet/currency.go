@@ -0,0 +1,77 @@
+package wallet
+
+import (
+	"backend/pkg/customerror"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Currency is an ISO 4217 currency. Amounts are always stored in minor units;
+// MinorUnits is the number of decimals needed to show them in major units,
+// 2 for USD and 0 for JPY.
+type Currency struct {
+	Code       string
+	MinorUnits int
+}
+
+// ValidCurrencyCode reports whether code has the shape of an ISO 4217 code.
+// Whether the currency is supported is up to the currencies table.
+func ValidCurrencyCode(code string) bool {
+	if len(code) != 3 {
+		return false
+	}
+	for _, letter := range code {
+		if letter < 'A' || letter > 'Z' {
+			return false
+		}
+	}
+	return true
+}
+
+var decimalAmountPattern = regexp.MustCompile(`^-?[0-9]+(\.[0-9]+)?$`)
+
+// ParseAmount reads value, a decimal in major units such as "12.34", as minor
+// units of currency. Decimals beyond MinorUnits are refused unless they are
+// zeros, so nothing is ever rounded away; amounts that do not fit in an int64
+// are ErrAmountOutOfRange.
+func (currency Currency) ParseAmount(value string) (int64, error) {
+	if !decimalAmountPattern.MatchString(value) {
+		return 0, fmt.Errorf("%w: %q is not a decimal", customerror.ErrInvalidAmount, value)
+	}
+	whole, fraction, _ := strings.Cut(value, ".")
+	fraction = strings.TrimRight(fraction, "0")
+	if len(fraction) > currency.MinorUnits {
+		return 0, fmt.Errorf("%w: %s has %d decimals", customerror.ErrInvalidAmount, currency.Code, currency.MinorUnits)
+	}
+	amount, err := strconv.ParseInt(whole+fraction+strings.Repeat("0", currency.MinorUnits-len(fraction)), 10, 64)
+	if errors.Is(err, strconv.ErrRange) {
+		return 0, customerror.ErrAmountOutOfRange
+	}
+	if err != nil {
+		return 0, fmt.Errorf("%w: %q is not a decimal", customerror.ErrInvalidAmount, value)
+	}
+	return amount, nil
+}
+
+// FormatAmount writes amount, in minor units of currency, as a decimal in
+// major units with exactly MinorUnits decimals, such as "12.30".
+func (currency Currency) FormatAmount(amount int64) string {
+	if currency.MinorUnits <= 0 {
+		return strconv.FormatInt(amount, 10)
+	}
+	sign := ""
+	magnitude := uint64(amount)
+	if amount < 0 {
+		sign = "-"
+		magnitude = -magnitude
+	}
+	digits := strconv.FormatUint(magnitude, 10)
+	if len(digits) <= currency.MinorUnits {
+		digits = strings.Repeat("0", currency.MinorUnits-len(digits)+1) + digits
+	}
+	point := len(digits) - currency.MinorUnits
+	return sign + digits[:point] + "." + digits[point:]
+}
@@ -0,0 +1,63 @@
+package wallet_test
+
+import (
+	"backend/pkg/customerror"
+	"backend/pkg/wallet"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type ParseAmountTest struct {
+	Name          string
+	Value         string
+	Currency      wallet.Currency
+	WaitingAmount int64
+	WaitingError  error
+}
+
+func TestCurrency_ParseAmount(t *testing.T) {
+	usd := wallet.Currency{Code: "USD", MinorUnits: 2}
+	jpy := wallet.Currency{Code: "JPY", MinorUnits: 0}
+	bhd := wallet.Currency{Code: "BHD", MinorUnits: 3}
+
+	tests := []ParseAmountTest{
+		{Name: "Decimal Test", Value: "12.34", Currency: usd, WaitingAmount: 1234},
+		{Name: "Short Fraction Test", Value: "12.3", Currency: usd, WaitingAmount: 1230},
+		{Name: "Whole Test", Value: "12", Currency: bhd, WaitingAmount: 12000},
+		{Name: "Trailing Zeros Test", Value: "500.00", Currency: jpy, WaitingAmount: 500},
+		{Name: "Negative Test", Value: "-0.01", Currency: usd, WaitingAmount: -1},
+		{Name: "Largest Test", Value: "92233720368547758.07", Currency: usd, WaitingAmount: math.MaxInt64},
+		{Name: "Too Many Decimals Test", Value: "12.345", Currency: usd, WaitingError: customerror.ErrInvalidAmount},
+		{Name: "Fraction Of Yen Test", Value: "500.5", Currency: jpy, WaitingError: customerror.ErrInvalidAmount},
+		{Name: "Not A Decimal Test", Value: "1e3", Currency: usd, WaitingError: customerror.ErrInvalidAmount},
+		{Name: "Empty Test", Value: "", Currency: usd, WaitingError: customerror.ErrInvalidAmount},
+		{Name: "Overflow Test", Value: "92233720368547758.08", Currency: usd, WaitingError: customerror.ErrAmountOutOfRange},
+	}
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			amount, err := test.Currency.ParseAmount(test.Value)
+			if test.WaitingError != nil {
+				assert.ErrorIs(t, err, test.WaitingError)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, test.WaitingAmount, amount)
+			}
+		})
+	}
+}
+
+func TestCurrency_FormatAmount(t *testing.T) {
+	usd := wallet.Currency{Code: "USD", MinorUnits: 2}
+	jpy := wallet.Currency{Code: "JPY", MinorUnits: 0}
+	bhd := wallet.Currency{Code: "BHD", MinorUnits: 3}
+
+	assert.Equal(t, "12.34", usd.FormatAmount(1234))
+	assert.Equal(t, "0.05", usd.FormatAmount(5))
+	assert.Equal(t, "-0.05", usd.FormatAmount(-5))
+	assert.Equal(t, "0.00", usd.FormatAmount(0))
+	assert.Equal(t, "500", jpy.FormatAmount(500))
+	assert.Equal(t, "1.000", bhd.FormatAmount(1000))
+	assert.Equal(t, "-92233720368547758.08", usd.FormatAmount(math.MinInt64))
+}
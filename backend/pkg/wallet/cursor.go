@@ -0,0 +1,52 @@
+package wallet
+
+import (
+	"backend/pkg/customerror"
+	"encoding/base64"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// TransactionCursor points at the last transaction of a page; the next page
+// starts strictly after it in the requested sort order.
+type TransactionCursor struct {
+	CreatedAt time.Time
+	ID        uuid.UUID
+}
+
+func NewTransactionCursor(transaction Transaction) *TransactionCursor {
+	return &TransactionCursor{
+		CreatedAt: transaction.CreatedAt,
+		ID:        transaction.ID,
+	}
+}
+
+func (cursor *TransactionCursor) Encode() string {
+	raw := cursor.CreatedAt.UTC().Format(time.RFC3339Nano) + "|" + cursor.ID.String()
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func DecodeTransactionCursor(encoded string) (*TransactionCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, customerror.ErrWrongCursor
+	}
+	createdAtStr, idStr, found := strings.Cut(string(raw), "|")
+	if !found {
+		return nil, customerror.ErrWrongCursor
+	}
+	createdAt, err := time.Parse(time.RFC3339Nano, createdAtStr)
+	if err != nil {
+		return nil, customerror.ErrWrongCursor
+	}
+	id, err := uuid.Parse(idStr)
+	if err != nil {
+		return nil, customerror.ErrWrongCursor
+	}
+	return &TransactionCursor{
+		CreatedAt: createdAt,
+		ID:        id,
+	}, nil
+}
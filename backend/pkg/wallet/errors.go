@@ -0,0 +1,27 @@
+package wallet
+
+import "backend/pkg/customerror"
+
+// Repositories translate storage specific failures into these errors, so
+// callers never depend on the database driver.
+var ErrNotFound = customerror.NewKindError(customerror.ErrNotFound, "wallet not found")
+
+var ErrAlreadyExists = customerror.NewKindError(customerror.ErrConflict, "wallet already exists")
+
+var ErrUnknownCurrency = customerror.NewKindError(customerror.ErrValidation, "unknown currency")
+
+var ErrCurrencyMismatch = customerror.NewKindError(customerror.ErrValidation, "currency does not match the wallet")
+
+var ErrInvalidExchangeRate = customerror.NewKindError(customerror.ErrValidation, "invalid exchange rate")
+
+var ErrExchangeRateNotFound = customerror.NewKindError(customerror.ErrValidation, "no exchange rate for the currency pair")
+
+var ErrHoldNotFound = customerror.NewKindError(customerror.ErrNotFound, "hold not found")
+
+var ErrHoldNotActive = customerror.NewKindError(customerror.ErrConflict, "hold is no longer active")
+
+var ErrHoldExpired = customerror.NewKindError(customerror.ErrConflict, "hold has expired")
+
+var ErrInvalidHoldExpiry = customerror.NewKindError(customerror.ErrValidation, "invalid hold expiry")
+
+var ErrForbidden = customerror.NewKindError(customerror.ErrForbidden, "wallet belongs to another owner")
@@ -0,0 +1,36 @@
+package wallet
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	HoldStatusActive   = "ACTIVE"
+	HoldStatusCaptured = "CAPTURED"
+	HoldStatusReleased = "RELEASED"
+)
+
+const (
+	// DefaultHoldDuration is how long a hold created without an expiry lasts.
+	DefaultHoldDuration = 7 * 24 * time.Hour
+	// MaxHoldDuration caps how long funds can be reserved in one hold.
+	MaxHoldDuration = 30 * 24 * time.Hour
+)
+
+// Hold reserves Amount of a wallet until it is captured, released or expires.
+// While it is active the amount counts towards the held funds of the wallet
+// and cannot be spent by anything else. A capture spends Captured, at most
+// Amount, and releases the rest.
+type Hold struct {
+	ID        uuid.UUID
+	WalletID  uuid.UUID
+	Amount    int64
+	Captured  int64
+	Status    string
+	ExpiresAt time.Time
+	CreatedAt time.Time
+	// Currency is the currency of the wallet the hold is on.
+	Currency Currency
+}
@@ -0,0 +1,29 @@
+package wallet
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+
+	"github.com/google/uuid"
+)
+
+const MaxIdempotencyKeyLength = 255
+
+// IdempotencyKey binds a client supplied key to a fingerprint of the request
+// it was first used with, so a retry can be told apart from a reused key.
+// Keys are scoped to ClientID, the caller that picked them.
+type IdempotencyKey struct {
+	ClientID    string
+	Key         string
+	RequestHash string
+}
+
+func NewIdempotencyKey(clientID string, key string, walletID uuid.UUID, operationType string, amount int64) *IdempotencyKey {
+	hash := sha256.Sum256([]byte(fmt.Sprintf("%s|%s|%d", walletID, operationType, amount)))
+	return &IdempotencyKey{
+		ClientID:    clientID,
+		Key:         key,
+		RequestHash: hex.EncodeToString(hash[:]),
+	}
+}
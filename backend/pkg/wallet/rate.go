@@ -0,0 +1,174 @@
+package wallet
+
+import (
+	"backend/pkg/customerror"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// MaxRateDecimals keeps rates exact: anything finer than this is noise
+	// for amounts stored in whole minor units.
+	MaxRateDecimals = 12
+	// MaxRateSourceLength matches the source column of the rates table.
+	MaxRateSourceLength = 64
+)
+
+var rateFormat = regexp.MustCompile(`^[0-9]{1,12}(\.[0-9]{1,12})?$`)
+
+// ExchangeRate is the price of one major unit of Base in major units of
+// Quote, in effect from ValidFrom until ValidTo, or until it is superseded by
+// a rate with a later ValidFrom. Rate is kept as the decimal string it was
+// loaded with, so it is never rounded through a float.
+type ExchangeRate struct {
+	ID        uuid.UUID  `json:"id"`
+	Base      string     `json:"base"`
+	Quote     string     `json:"quote"`
+	Rate      string     `json:"rate"`
+	ValidFrom time.Time  `json:"validFrom"`
+	ValidTo   *time.Time `json:"validTo,omitempty"`
+	Source    string     `json:"source"`
+	CreatedAt time.Time  `json:"createdAt"`
+}
+
+// ParseRate parses a positive decimal with at most MaxRateDecimals decimals.
+func ParseRate(value string) (*big.Rat, error) {
+	if !rateFormat.MatchString(value) {
+		return nil, ErrInvalidExchangeRate
+	}
+	rate, ok := new(big.Rat).SetString(value)
+	if !ok || rate.Sign() <= 0 {
+		return nil, ErrInvalidExchangeRate
+	}
+	return rate, nil
+}
+
+// Validate checks everything about the rate that does not need the database;
+// whether both currencies are supported is up to the currencies table.
+func (rate *ExchangeRate) Validate() error {
+	if !ValidCurrencyCode(rate.Base) || !ValidCurrencyCode(rate.Quote) {
+		return ErrUnknownCurrency
+	}
+	if rate.Base == rate.Quote {
+		return fmt.Errorf("%w: base and quote currencies are the same", ErrInvalidExchangeRate)
+	}
+	if _, err := ParseRate(rate.Rate); err != nil {
+		return fmt.Errorf("%w: rate must be a positive decimal with at most %d decimals", ErrInvalidExchangeRate, MaxRateDecimals)
+	}
+	if rate.ValidTo != nil && !rate.ValidTo.After(rate.ValidFrom) {
+		return fmt.Errorf("%w: validTo must be after validFrom", ErrInvalidExchangeRate)
+	}
+	if rate.Source == "" || len(rate.Source) > MaxRateSourceLength {
+		return fmt.Errorf("%w: source must be 1 to %d characters", ErrInvalidExchangeRate, MaxRateSourceLength)
+	}
+	return nil
+}
+
+// Convert turns amount, in minor units of from, into minor units of to at
+// rate. The result is rounded down to a whole minor unit, so a conversion
+// never credits more than the debited amount is worth and the fraction stays
+// with the service. Amounts that round to nothing are ErrInvalidAmount and
+// amounts that do not fit in an int64 ErrAmountOutOfRange.
+func Convert(amount int64, rate *big.Rat, from Currency, to Currency) (int64, error) {
+	numerator := new(big.Int).Mul(big.NewInt(amount), rate.Num())
+	numerator.Mul(numerator, pow10(to.MinorUnits))
+	denominator := new(big.Int).Mul(rate.Denom(), pow10(from.MinorUnits))
+	converted := numerator.Quo(numerator, denominator)
+	if !converted.IsInt64() {
+		return 0, customerror.ErrAmountOutOfRange
+	}
+	if converted.Sign() <= 0 {
+		return 0, customerror.ErrInvalidAmount
+	}
+	return converted.Int64(), nil
+}
+
+func pow10(exponent int) *big.Int {
+	return new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(exponent)), nil)
+}
+
+// rateColumns are the CSV header names ReadExchangeRatesCSV understands.
+// valid_from, valid_to and source may be left out.
+var rateColumns = []string{"base", "quote", "rate", "valid_from", "valid_to", "source"}
+
+// ReadExchangeRatesCSV reads rates from CSV with a header row naming its
+// columns. Times are RFC 3339; an empty valid_from is left zero for the
+// caller to fill in and an empty source falls back to defaultSource. Every
+// row is validated and errors name the offending line.
+func ReadExchangeRatesCSV(reader io.Reader, defaultSource string) ([]ExchangeRate, error) {
+	csvReader := csv.NewReader(reader)
+	csvReader.TrimLeadingSpace = true
+	header, err := csvReader.Read()
+	if errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("%w: missing header", ErrInvalidExchangeRate)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidExchangeRate, err.Error())
+	}
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if !slices.Contains(rateColumns, name) {
+			return nil, fmt.Errorf("%w: unknown column %q", ErrInvalidExchangeRate, name)
+		}
+		columns[name] = i
+	}
+	for _, name := range rateColumns[:3] {
+		if _, ok := columns[name]; !ok {
+			return nil, fmt.Errorf("%w: missing column %q", ErrInvalidExchangeRate, name)
+		}
+	}
+	field := func(record []string, name string) string {
+		if i, ok := columns[name]; ok {
+			return strings.TrimSpace(record[i])
+		}
+		return ""
+	}
+
+	rates := []ExchangeRate{}
+	for {
+		record, err := csvReader.Read()
+		if errors.Is(err, io.EOF) {
+			return rates, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s", ErrInvalidExchangeRate, err.Error())
+		}
+		line, _ := csvReader.FieldPos(0)
+		rate := ExchangeRate{
+			Base:   strings.ToUpper(field(record, "base")),
+			Quote:  strings.ToUpper(field(record, "quote")),
+			Rate:   field(record, "rate"),
+			Source: field(record, "source"),
+		}
+		if rate.Source == "" {
+			rate.Source = defaultSource
+		}
+		if validFrom := field(record, "valid_from"); validFrom != "" {
+			rate.ValidFrom, err = time.Parse(time.RFC3339, validFrom)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w: valid_from must be an RFC 3339 time", line, ErrInvalidExchangeRate)
+			}
+		}
+		if validTo := field(record, "valid_to"); validTo != "" {
+			parsed, err := time.Parse(time.RFC3339, validTo)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w: valid_to must be an RFC 3339 time", line, ErrInvalidExchangeRate)
+			}
+			rate.ValidTo = &parsed
+		}
+		if err := rate.Validate(); err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		rates = append(rates, rate)
+	}
+}
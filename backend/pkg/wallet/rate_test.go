@@ -0,0 +1,130 @@
+package wallet_test
+
+import (
+	"backend/pkg/customerror"
+	"backend/pkg/wallet"
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type ConvertTest struct {
+	Name          string
+	Amount        int64
+	Rate          string
+	From          wallet.Currency
+	To            wallet.Currency
+	WaitingAmount int64
+	WaitingError  error
+}
+
+func TestConvert(t *testing.T) {
+	usd := wallet.Currency{Code: "USD", MinorUnits: 2}
+	eur := wallet.Currency{Code: "EUR", MinorUnits: 2}
+	jpy := wallet.Currency{Code: "JPY", MinorUnits: 0}
+	bhd := wallet.Currency{Code: "BHD", MinorUnits: 3}
+
+	tests := []ConvertTest{
+		{Name: "Same Minor Units Test", Amount: 10000, Rate: "0.92", From: usd, To: eur, WaitingAmount: 9200},
+		{Name: "Rounds Down Test", Amount: 1, Rate: "0.92", From: usd, To: eur, WaitingAmount: 0, WaitingError: customerror.ErrInvalidAmount},
+		{Name: "Fraction Dropped Test", Amount: 1999, Rate: "0.925", From: usd, To: eur, WaitingAmount: 1849},
+		{Name: "Fewer Minor Units Test", Amount: 1000, Rate: "151.2345", From: usd, To: jpy, WaitingAmount: 1512},
+		{Name: "More Minor Units Test", Amount: 1000, Rate: "0.0025", From: jpy, To: bhd, WaitingAmount: 2500},
+		{Name: "Overflow Test", Amount: math.MaxInt64, Rate: "2", From: usd, To: eur, WaitingError: customerror.ErrAmountOutOfRange},
+	}
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			rate, err := wallet.ParseRate(test.Rate)
+			assert.NoError(t, err)
+			amount, err := wallet.Convert(test.Amount, rate, test.From, test.To)
+			if test.WaitingError != nil {
+				assert.ErrorIs(t, err, test.WaitingError)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, test.WaitingAmount, amount)
+			}
+		})
+	}
+}
+
+func TestParseRate(t *testing.T) {
+	for _, value := range []string{"1", "0.92", "151.2345", "0.000000000001"} {
+		_, err := wallet.ParseRate(value)
+		assert.NoError(t, err, value)
+	}
+	for _, value := range []string{"", "0", "0.000", "-1", "1e3", "1/3", "1.", ".5", "0.0000000000001"} {
+		_, err := wallet.ParseRate(value)
+		assert.ErrorIs(t, err, wallet.ErrInvalidExchangeRate, value)
+	}
+}
+
+type ReadExchangeRatesCSVTest struct {
+	Name         string
+	CSV          string
+	WaitingRates []wallet.ExchangeRate
+	WaitingError string
+}
+
+func TestReadExchangeRatesCSV(t *testing.T) {
+	validFrom := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	validTo := time.Date(2026, 2, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []ReadExchangeRatesCSVTest{
+		{
+			Name: "Success Test",
+			CSV: "base,quote,rate,valid_from,valid_to,source\n" +
+				"usd,EUR,0.92,2026-01-01T00:00:00Z,2026-02-01T00:00:00Z,ecb\n" +
+				"EUR,USD,1.087,,,\n",
+			WaitingRates: []wallet.ExchangeRate{
+				{Base: "USD", Quote: "EUR", Rate: "0.92", ValidFrom: validFrom, ValidTo: &validTo, Source: "ecb"},
+				{Base: "EUR", Quote: "USD", Rate: "1.087", Source: "import"},
+			},
+		},
+		{
+			Name:         "Columns In Any Order Test",
+			CSV:          "rate,quote,base\n151.2345,JPY,USD\n",
+			WaitingRates: []wallet.ExchangeRate{{Base: "USD", Quote: "JPY", Rate: "151.2345", Source: "import"}},
+		},
+		{
+			Name:         "Missing Column Test",
+			CSV:          "base,quote\nUSD,EUR\n",
+			WaitingError: `invalid exchange rate: missing column "rate"`,
+		},
+		{
+			Name:         "Unknown Column Test",
+			CSV:          "base,quote,rate,comment\nUSD,EUR,0.92,hi\n",
+			WaitingError: `invalid exchange rate: unknown column "comment"`,
+		},
+		{
+			Name:         "Invalid Row Test",
+			CSV:          "base,quote,rate\nUSD,EUR,0.92\nUSD,EUR,abc\n",
+			WaitingError: "line 3: invalid exchange rate: rate must be a positive decimal with at most 12 decimals",
+		},
+		{
+			Name:         "Invalid Time Test",
+			CSV:          "base,quote,rate,valid_from\nUSD,EUR,0.92,yesterday\n",
+			WaitingError: "line 2: invalid exchange rate: valid_from must be an RFC 3339 time",
+		},
+		{
+			Name:         "Empty Test",
+			CSV:          "",
+			WaitingError: "invalid exchange rate: missing header",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			rates, err := wallet.ReadExchangeRatesCSV(strings.NewReader(test.CSV), "import")
+			if test.WaitingError != "" {
+				assert.EqualError(t, err, test.WaitingError)
+				assert.ErrorIs(t, err, wallet.ErrInvalidExchangeRate)
+				assert.Nil(t, rates)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, test.WaitingRates, rates)
+			}
+		})
+	}
+}
@@ -1,8 +1,87 @@
 package wallet
 
-import "github.com/google/uuid"
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	OperationDeposit     = "DEPOSIT"
+	OperationWithdraw    = "WITHDRAW"
+	OperationTransferIn  = "TRANSFER_IN"
+	OperationTransferOut = "TRANSFER_OUT"
+	// A hold journals a change of held funds only; its capture journals the
+	// spent amount and the release of the rest.
+	OperationHold    = "HOLD"
+	OperationCapture = "CAPTURE"
+	OperationRelease = "RELEASE"
+)
 
 type Wallet struct {
 	ID     uuid.UUID
 	Amount int64
+	// Held is the part of Amount reserved by active holds.
+	Held int64
+	// OwnerID is the subject of the end user the wallet belongs to, or nil
+	// for wallets managed by services only.
+	OwnerID  *string
+	Currency Currency
+}
+
+// Available is the part of the balance that is not held and can be spent.
+func (wallet Wallet) Available() int64 {
+	return wallet.Amount - wallet.Held
+}
+
+// Amount and Balance are the change and the resulting total balance; holds
+// do not change the total but the funds held, which HeldAmount records.
+type Transaction struct {
+	ID            uuid.UUID  `json:"id"`
+	WalletID      uuid.UUID  `json:"walletId"`
+	OperationType string     `json:"operationType"`
+	Amount        int64      `json:"amount"`
+	Balance       int64      `json:"balance"`
+	TransferID    *uuid.UUID `json:"transferId,omitempty"`
+	// RateID and ExchangeRate are set on both legs of a transfer between
+	// wallets of different currencies.
+	RateID       *uuid.UUID `json:"rateId,omitempty"`
+	ExchangeRate *string    `json:"exchangeRate,omitempty"`
+	// HoldID and HeldAmount are set on entries of a hold.
+	HoldID     *uuid.UUID `json:"holdId,omitempty"`
+	HeldAmount int64      `json:"heldAmount,omitempty"`
+	CreatedAt  time.Time  `json:"createdAt"`
+	// Replayed is set when the transaction was returned for a repeated
+	// idempotency key instead of being applied again.
+	Replayed bool `json:"-"`
+}
+
+type Transfer struct {
+	ID     uuid.UUID
+	Debit  Transaction
+	Credit Transaction
+	// FromCurrency and ToCurrency are the currencies of the debited and the
+	// credited wallet.
+	FromCurrency Currency
+	ToCurrency   Currency
+	// ToOwnerID is the owner of the credited wallet, which decides whether
+	// the caller may see its balance.
+	ToOwnerID *string
+}
+
+type TransactionFilter struct {
+	WalletID      uuid.UUID
+	OperationType string
+	From          time.Time
+	To            time.Time
+	Cursor        *TransactionCursor
+	Ascending     bool
+	Limit         int
+}
+
+type TransactionPage struct {
+	Transactions []Transaction
+	NextCursor   string
+	// Currency is the currency of the wallet all the transactions belong to.
+	Currency Currency
 }